@@ -0,0 +1,345 @@
+package go_contentline
+
+import (
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//ErrNotInline is returned by Property.Binary if the value of the property is not inline binary data, but e.g. a
+// reference to an external resource.
+var ErrNotInline = errors.New("value is not inline binary data")
+
+//BinaryHandler receives the inline binary data of a property while it is parsed, see ParserOptions.Binary.
+// data returns the decoded bytes and is only valid until the handler returns.
+type BinaryHandler func(p *Property, mediaType string, data io.Reader) error
+
+//maxDataURIHeader is the maximal length of the part before the ',' of a streamed data: URI.
+const maxDataURIHeader = 1024
+
+//Binary returns the decoded inline binary data of the property and its media type, regardless of the form it is
+// written in: ENCODING=BASE64 with FMTTYPE (iCalendar), ENCODING=b or ENCODING=BASE64 with TYPE (vCard 3.0 and 2.1)
+// or a data: URI (vCard 4.0). If there is no media type, application/octet-stream is returned.
+// If the value is not inline binary data, ErrNotInline is returned.
+func (p *Property) Binary() (data []byte, mediaType string, err error) {
+	if enc := strings.ToUpper(p.Parameters.Get("ENCODING")); enc == "B" || enc == "BASE64" {
+		data, err = base64.StdEncoding.DecodeString(removeWhitespace(p.Value))
+		return data, binaryMediaType(p), errors.Wrapf(err, "%s: invalid base64", p.Name)
+	}
+	if hasPrefixFold(p.Value, "data:") {
+		return parseDataURI(p.Value)
+	}
+	return nil, "", ErrNotInline
+}
+
+//SetBinary sets the value of the property to the inline binary data, in the form used by the given version of
+// iCalendar or vCard: ENCODING=BASE64, VALUE=BINARY and FMTTYPE for iCalendar ("2.0", the default for any other
+// version), ENCODING=b (vCard 3.0) or ENCODING=BASE64 (vCard 2.1) with the media type as TYPE (e.g. JPEG for
+// image/jpeg) or a data: URI for vCard 4.0. Parameters of the other forms are removed.
+func (p *Property) SetBinary(data []byte, mediaType, version string) {
+	if p.Parameters == nil {
+		p.Parameters = make(Parameters)
+	}
+	for _, name := range []string{"ENCODING", "VALUE", "FMTTYPE", "MEDIATYPE"} {
+		p.Parameters.remove(name)
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	switch version {
+	case VCard40:
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+		p.Value = "data:" + mediaType + ";base64," + encoded
+	case VCard30, VCard21:
+		p.Value = encoded
+		if version == VCard30 {
+			p.SetParameter("ENCODING", "b")
+		} else {
+			p.SetParameter("ENCODING", "BASE64")
+		}
+		p.Parameters.remove("TYPE")
+		if typ := legacyMediaType(mediaType); typ != "" {
+			p.SetParameter("TYPE", typ)
+		}
+	default:
+		p.Value = encoded
+		p.SetParameter("ENCODING", "BASE64")
+		p.SetParameter("VALUE", "BINARY")
+		if mediaType != "" {
+			p.SetParameter("FMTTYPE", mediaType)
+		}
+	}
+}
+
+//remove deletes the parameter with the given name, which is compared case-insensitively.
+func (ps Parameters) remove(name string) {
+	for k := range ps {
+		if strings.EqualFold(k, name) {
+			delete(ps, k)
+		}
+	}
+}
+
+//binaryMediaType returns the media type of base64 encoded data, given by FMTTYPE (iCalendar) or TYPE (vCard).
+func binaryMediaType(p *Property) string {
+	if typ := p.Parameters.Get("FMTTYPE"); typ != "" {
+		return typ
+	}
+	typ := strings.ToLower(p.Parameters.Get("TYPE"))
+	switch {
+	case strings.Contains(typ, "/"):
+		return typ
+	case typ == "":
+		return "application/octet-stream"
+	case typ == "pgp":
+		return "application/pgp-keys"
+	case typ == "x509":
+		return "application/pkix-cert"
+	case typ == "wave":
+		return "audio/wav"
+	case p.Name == "SOUND":
+		return "audio/" + typ
+	case p.Name == "KEY":
+		return "application/" + typ
+	}
+	return "image/" + typ
+}
+
+//legacyMediaType returns the TYPE value used by vCard 2.1 and 3.0 for a media type, e.g. JPEG for image/jpeg.
+func legacyMediaType(mediaType string) string {
+	switch mediaType = strings.ToLower(mediaType); mediaType {
+	case "application/pgp-keys":
+		return "PGP"
+	case "application/pkix-cert", "application/x-x509-ca-cert", "application/x-x509-user-cert":
+		return "X509"
+	case "audio/wav", "audio/x-wav":
+		return "WAVE"
+	case "", "application/octet-stream":
+		return ""
+	}
+	if i := strings.IndexByte(mediaType, '/'); i >= 0 {
+		mediaType = mediaType[i+1:]
+	}
+	return strings.ToUpper(strings.TrimPrefix(mediaType, "x-"))
+}
+
+//parseDataURI decodes a data: URI (RFC2397) and returns the data and its media type.
+func parseDataURI(uri string) ([]byte, string, error) {
+	comma := strings.IndexByte(uri, ',')
+	if comma < 0 {
+		return nil, "", errors.New("invalid data: URI, missing ','")
+	}
+	mediaType, isBase64 := parseDataURIHeader(uri[len("data:"):comma])
+	data := uri[comma+1:]
+	if isBase64 {
+		b, err := base64.StdEncoding.DecodeString(removeWhitespace(data))
+		return b, mediaType, errors.Wrap(err, "invalid base64 in data: URI")
+	}
+	s, err := url.PathUnescape(data)
+	return []byte(s), mediaType, errors.Wrap(err, "invalid data: URI")
+}
+
+//parseDataURIHeader parses the part of a data: URI between "data:" and ','.
+func parseDataURIHeader(header string) (mediaType string, isBase64 bool) {
+	if strings.HasSuffix(strings.ToLower(header), ";base64") {
+		header, isBase64 = header[:len(header)-len(";base64")], true
+	}
+	if i := strings.IndexByte(header, ';'); i >= 0 {
+		header = header[:i]
+	}
+	if header == "" {
+		//the default of RFC2397
+		header = "text/plain"
+	}
+	return header, isBase64
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func removeWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+//readHead reads the next unfolded line like readLine, but stops after the ':' which separates the value of a
+// property. The value stays in the reader and is read by readValue. Lines of components (BEGIN/END) are read
+// completely.
+func (p *Parser) readHead() (string, error) {
+	for {
+		head, err := p.readUntilValue()
+		if head == "" && err == nil && p.opts.Legacy {
+			p.start, p.startOffset = p.line+1, p.r.offset
+			continue
+		}
+		return head, err
+	}
+}
+
+//readUntilValue reads an unfolded line up to the first ':' outside of a quoted string.
+func (p *Parser) readUntilValue() (string, error) {
+	var buf []byte
+	quoted := false
+	for {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case c == '\r' || c == '\n':
+			more, err := p.lineBreak(c)
+			if err != nil || !more {
+				return string(buf), err
+			}
+			continue
+		case c == '"':
+			quoted = !quoted
+		case c == ':' && !quoted:
+			buf = append(buf, c)
+			name := strings.ToUpper(string(buf[:len(buf)-1]))
+			if name == sBEGIN || name == sEND {
+				rest, err := p.readUnfoldedLine()
+				return string(buf) + rest, err
+			}
+			p.pending = true
+			return string(buf), nil
+		}
+		buf = append(buf, c)
+		if err := p.checkLength(len(buf)); err != nil {
+			return "", err
+		}
+	}
+}
+
+//lineBreak consumes a line break after c (the first byte of it was already read) and returns whether the line
+// continues on the next line (is folded).
+func (p *Parser) lineBreak(c byte) (bool, error) {
+	if c != '\r' {
+		return false, errors.New("Expected CRLF")
+	}
+	if c, err := p.r.ReadByte(); err != nil || c != '\n' {
+		return false, errors.New("Expected CRLF")
+	}
+	p.line++
+	next, err := p.r.Peek(1)
+	if err != nil {
+		return false, err
+	}
+	if next[0] == ' ' || next[0] == '\t' {
+		p.r.ReadByte()
+		return true, nil
+	}
+	return false, nil
+}
+
+//readValue reads the value of a property whose line was read by readHead. Inline binary data of PHOTO, LOGO,
+// SOUND, KEY and ATTACH is decoded while it is read and passed to the BinaryHandler, its value stays empty.
+// All other values are stored in the property.
+func (p *Parser) readValue(prop *Property) error {
+	if binaryProperties[prop.Name] {
+		if enc := strings.ToUpper(prop.Parameters.Get("ENCODING")); enc == "B" || enc == "BASE64" {
+			return p.stream(prop, binaryMediaType(prop), base64.NewDecoder(base64.StdEncoding, &valueReader{p: p, skipSpace: true}))
+		}
+		if start, _ := p.r.Peek(len("data:")); hasPrefixFold(string(start), "data:") {
+			return p.streamDataURI(prop)
+		}
+	}
+	value, err := p.readUnfoldedLine()
+	for p.opts.Legacy && err == nil && strings.HasSuffix(value, "=") && isQuotedPrintable(prop.olds) {
+		var next string
+		next, err = p.readUnfoldedLine()
+		value = value[:len(value)-1] + next
+	}
+	if err != nil && err != io.EOF {
+		return err
+	}
+	prop.Value = value
+	prop.olds += value
+	return nil
+}
+
+//streamDataURI passes the data of a data: URI to the BinaryHandler.
+func (p *Parser) streamDataURI(prop *Property) error {
+	vr := &valueReader{p: p}
+	//read byte by byte, so that the data stays in the reader
+	var header []byte
+	c := make([]byte, 1)
+	for len(header) == 0 || header[len(header)-1] != ',' {
+		if _, err := vr.Read(c); err != nil || len(header) == maxDataURIHeader {
+			return errors.Errorf("%s: invalid data: URI, missing ','", prop.Name)
+		}
+		header = append(header, c[0])
+	}
+	mediaType, isBase64 := parseDataURIHeader(string(header[len("data:") : len(header)-1]))
+	if isBase64 {
+		vr.skipSpace = true
+		return p.stream(prop, mediaType, base64.NewDecoder(base64.StdEncoding, vr))
+	}
+	//percent-encoded data is only used for small values
+	data, err := ioutil.ReadAll(vr)
+	if err != nil {
+		return err
+	}
+	s, err := url.PathUnescape(string(data))
+	if err != nil {
+		return errors.Wrapf(err, "%s: invalid data: URI", prop.Name)
+	}
+	return p.stream(prop, mediaType, strings.NewReader(s))
+}
+
+//stream calls the BinaryHandler and reads the rest of the value afterwards.
+func (p *Parser) stream(prop *Property, mediaType string, data io.Reader) error {
+	if err := p.opts.Binary(prop, mediaType, data); err != nil {
+		return errors.Wrapf(err, "%s", prop.Name)
+	}
+	_, err := io.Copy(ioutil.Discard, data)
+	return errors.Wrapf(err, "%s", prop.Name)
+}
+
+//valueReader reads the rest of the current (folded) line from the parser, without the line breaks of the folding.
+type valueReader struct {
+	p    *Parser
+	done bool
+	//skipSpace removes all spaces and tabs, e.g. from base64 data indented by more than one space (vCard 2.1).
+	skipSpace bool
+}
+
+func (v *valueReader) Read(b []byte) (int, error) {
+	n := 0
+	for n < len(b) && !v.done {
+		c, err := v.p.r.ReadByte()
+		if err == io.EOF {
+			v.done = true
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		if c == '\r' || c == '\n' {
+			more, err := v.p.lineBreak(c)
+			if err != nil && err != io.EOF {
+				return n, err
+			}
+			v.done = !more
+			continue
+		}
+		if v.skipSpace && (c == ' ' || c == '\t') {
+			continue
+		}
+		b[n] = c
+		n++
+	}
+	if n == 0 && v.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,128 @@
+package go_contentline
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProperty_Binary(t *testing.T) {
+	data := []byte("GIF89a\x00\x01")
+	checks := []struct {
+		version, mediaType string
+		want               string
+		params             Parameters
+	}{
+		{ICalendar20, "image/gif", "R0lGODlhAAE=", Parameters{"ENCODING": {"BASE64"}, "VALUE": {"BINARY"}, "FMTTYPE": {"image/gif"}}},
+		{VCard21, "image/gif", "R0lGODlhAAE=", Parameters{"ENCODING": {"BASE64"}, "TYPE": {"GIF"}}},
+		{VCard30, "image/gif", "R0lGODlhAAE=", Parameters{"ENCODING": {"b"}, "TYPE": {"GIF"}}},
+		{VCard40, "image/gif", "data:image/gif;base64,R0lGODlhAAE=", Parameters{}},
+		{VCard40, "", "data:application/octet-stream;base64,R0lGODlhAAE=", Parameters{}},
+	}
+	for _, check := range checks {
+		p := NewPropertyUnchecked("PHOTO", "http://example.com/old.gif", Parameters{"VALUE": {"uri"}})
+		p.SetBinary(data, check.mediaType, check.version)
+		if p.Value != check.want || !reflect.DeepEqual(p.Parameters, check.params) {
+			t.Errorf("%s: unexpected property %v", check.version, p)
+		}
+		got, typ, err := p.Binary()
+		wantType := check.mediaType
+		if wantType == "" {
+			wantType = "application/octet-stream"
+		}
+		if err != nil || !bytes.Equal(got, data) || typ != wantType {
+			t.Errorf("%s: got %q (%s), %v", check.version, got, typ, err)
+		}
+	}
+
+	p := NewPropertyUnchecked("PHOTO", "data:,hello%20world", Parameters{})
+	if got, typ, err := p.Binary(); err != nil || string(got) != "hello world" || typ != "text/plain" {
+		t.Errorf("got %q (%s), %v", got, typ, err)
+	}
+	p = NewPropertyUnchecked("PHOTO", "http://example.com/photo.jpg", Parameters{})
+	if _, _, err := p.Binary(); err != ErrNotInline {
+		t.Errorf("expected ErrNotInline, got %v", err)
+	}
+}
+
+func TestParserOptions_Binary(t *testing.T) {
+	in := "BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"FN;X-NOTE=\"a:b\":Jane\r\n" +
+		"PHOTO;ENCODING=b;TYPE=JPEG:/9j/4AAQSkZJRgABAQEASABIAAD/2wBDAAMCAgMCAgMDAwMEAw\r\n" +
+		" MDBQgFBQQEBQoHBwYIDAoMDAsKCwsNDhIQDQ4RDgsLEBYQERMUFRUVDA8XGBYUGBIUFRT/\r\n" +
+		" 2Q==\r\n" +
+		"LOGO:data:image/png;base64,iVBO\r\n" +
+		" Rw0KGgo=\r\n" +
+		"KEY;VALUE=uri:http://example.com/key.asc\r\n" +
+		"NOTE:a long\r\n" +
+		"  note\r\n" +
+		"END:VCARD\r\n"
+
+	var got []string
+	handler := func(p *Property, mediaType string, data io.Reader) error {
+		//read only a part, the rest is skipped by the parser
+		b := make([]byte, 4)
+		n, err := io.ReadFull(data, b)
+		got = append(got, p.Name+" "+mediaType+" "+string(b[:n]))
+		return err
+	}
+	c, err := InitParserWithOptions(strings.NewReader(in), ParserOptions{Binary: handler}).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"PHOTO image/jpeg \xff\xd8\xff\xe0", "LOGO image/png \x89PNG"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted %q, got %q", want, got)
+	}
+
+	//the other properties are the same as without handler
+	c2, err := InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Properties) != len(c2.Properties) {
+		t.Fatalf("expected %d properties, got %d", len(c2.Properties), len(c.Properties))
+	}
+	for i, p := range c.Properties {
+		if p.Name == "PHOTO" || p.Name == "LOGO" {
+			if p.Value != "" {
+				t.Errorf("expected an empty value for %s, got %q", p.Name, p.Value)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(p, c2.Properties[i]) {
+			t.Errorf("Wanted %#v\nGot    %#v", c2.Properties[i], p)
+		}
+	}
+}
+
+func TestParserOptions_BinaryLegacy(t *testing.T) {
+	in := "BEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"PHOTO;BASE64;GIF:\r\n" +
+		"    R0lGODlh\r\n" +
+		"    AAE=\r\n" +
+		"\r\n" +
+		"NOTE;ENCODING=QUOTED-PRINTABLE:a=\r\n" +
+		"b\r\n" +
+		"END:VCARD\r\n"
+	var photo []byte
+	handler := func(p *Property, mediaType string, data io.Reader) (err error) {
+		photo, err = ioutil.ReadAll(data)
+		return err
+	}
+	c, err := InitParserWithOptions(strings.NewReader(in), ParserOptions{Legacy: true, Binary: handler}).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(photo) != "GIF89a\x00\x01" {
+		t.Errorf("unexpected photo: %q", photo)
+	}
+	if note := c.GetProperty("NOTE"); note == nil || note.Value != "ab" {
+		t.Errorf("unexpected NOTE: %v", note)
+	}
+}
@@ -0,0 +1,116 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+//Trigger is the TRIGGER of an alarm, either relative to the start (or end) of the component or an absolute time.
+type Trigger struct {
+	//Duration is the offset to the start of the component, negative offsets are before the start.
+	Duration time.Duration
+	//RelatedEnd is true if Duration is relative to the end of the component (RELATED=END).
+	RelatedEnd bool
+	//Time is the absolute time of the trigger (VALUE=DATE-TIME). If it is set, Duration and RelatedEnd are ignored.
+	Time time.Time
+}
+
+//Alarm is a VALARM component.
+type Alarm struct {
+	Action      string        //ACTION, e.g. DISPLAY
+	Trigger     *Trigger      //TRIGGER
+	Description string        //DESCRIPTION
+	Summary     string        //SUMMARY
+	Duration    time.Duration //DURATION between repetitions
+	Repeat      int           //REPEAT
+	Attendees   []*Attendee   //ATTENDEE, for EMAIL alarms
+
+	//Extra contains all properties which can't be represented by the typed fields.
+	Extra []*go_contentline.Property
+	//ExtraComponents contains all subcomponents.
+	ExtraComponents []*go_contentline.Component
+}
+
+func (a *Alarm) fields(resolve go_contentline.TZResolver) []field {
+	return []field{
+		textField("ACTION", &a.Action),
+		triggerField(&a.Trigger, resolve),
+		textField("DESCRIPTION", &a.Description),
+		textField("SUMMARY", &a.Summary),
+		durationField("DURATION", &a.Duration),
+		intField("REPEAT", &a.Repeat),
+		attendeesField(&a.Attendees),
+	}
+}
+
+func triggerField(v **Trigger, resolve go_contentline.TZResolver) field {
+	return field{
+		name: "TRIGGER",
+		decode: func(p *go_contentline.Property) error {
+			tr := &Trigger{}
+			if strings.EqualFold(p.Parameters.Get("VALUE"), "DATE-TIME") {
+				t, isDate, err := p.DateTime(resolve)
+				if err != nil {
+					return err
+				}
+				if isDate {
+					return errors.New("TRIGGER: expected DATE-TIME")
+				}
+				tr.Time = t
+			} else {
+				d, err := go_contentline.ParseDuration(p.Value)
+				if err != nil {
+					return err
+				}
+				tr.Duration = d
+				tr.RelatedEnd = strings.EqualFold(p.Parameters.Get("RELATED"), "END")
+			}
+			*v = tr
+			return nil
+		},
+		encode: func() []*go_contentline.Property {
+			tr := *v
+			if tr == nil {
+				return nil
+			}
+			p := newProperty("TRIGGER", "")
+			if !tr.Time.IsZero() {
+				p.SetDateTime(tr.Time, false)
+				p.SetParameter("VALUE", "DATE-TIME")
+				return single(p)
+			}
+			p.Value = go_contentline.FormatDuration(tr.Duration)
+			if tr.RelatedEnd {
+				p.SetParameter("RELATED", "END")
+			}
+			return single(p)
+		},
+		reset: func() { *v = nil },
+	}
+}
+
+//AlarmFromComponent converts a VALARM component, see EventFromComponent.
+func AlarmFromComponent(c *go_contentline.Component, resolve go_contentline.TZResolver) (*Alarm, error) {
+	if c.Name != "VALARM" {
+		return nil, errors.Errorf("expected VALARM, got %s", c.Name)
+	}
+	a := &Alarm{}
+	a.Extra = decodeFields(c.Properties, a.fields(floating(resolve)))
+	for _, sub := range c.Comps {
+		a.ExtraComponents = append(a.ExtraComponents, sub.Clone())
+	}
+	return a, nil
+}
+
+//ToComponent converts the alarm into a VALARM component.
+func (a *Alarm) ToComponent() *go_contentline.Component {
+	c := &go_contentline.Component{Name: "VALARM"}
+	c.Properties = encodeFields(a.fields(nil), a.Extra)
+	for _, sub := range a.ExtraComponents {
+		c.AddComponent(sub.Clone())
+	}
+	return c
+}
@@ -0,0 +1,129 @@
+//Package calendar maps the components of iCalendar (RFC5545) onto typed models: Event, Todo, Journal and Alarm.
+//
+// The models convert to and from *go_contentline.Component without loss: properties which are unknown, repeated
+// where only one is expected, or whose parameters or values can't be represented by the typed fields are kept in the
+// Extra field of each model and written again by ToComponent. Unknown subcomponents are kept in ExtraComponents.
+// The order of the properties is not kept.
+package calendar
+
+import (
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/timezone"
+	"github.com/pkg/errors"
+)
+
+//DateTime is a DATE or DATE-TIME value. Floating times (without time zone) are in time.Local.
+type DateTime struct {
+	time.Time
+	//IsDate is true for DATE values (VALUE=DATE), which have no time of day.
+	IsDate bool
+}
+
+//property returns a property with the value of the DateTime.
+func (dt DateTime) property(name string) *go_contentline.Property {
+	p := newProperty(name, "")
+	p.SetDateTime(dt.Time, dt.IsDate)
+	return p
+}
+
+//floating wraps resolve, so that floating times are resolved to time.Local and written without time zone again.
+// If resolve is nil, go_contentline.DefaultTZResolver is used.
+func floating(resolve go_contentline.TZResolver) go_contentline.TZResolver {
+	if resolve == nil {
+		resolve = go_contentline.DefaultTZResolver
+	}
+	return func(tzid string) (*time.Location, error) {
+		if tzid == "" {
+			return time.Local, nil
+		}
+		return resolve(tzid)
+	}
+}
+
+//Calendar is a VCALENDAR object.
+type Calendar struct {
+	ProdID  string //PRODID
+	Version string //VERSION
+	Method  string //METHOD
+	Scale   string //CALSCALE
+
+	Events   []*Event
+	Todos    []*Todo
+	Journals []*Journal
+	//TimeZones contains the VTIMEZONE components, which are needed to write the times of the other components.
+	TimeZones []*go_contentline.Component
+
+	Extra           []*go_contentline.Property
+	ExtraComponents []*go_contentline.Component
+}
+
+func (cal *Calendar) fields() []field {
+	return []field{
+		textField("PRODID", &cal.ProdID),
+		textField("VERSION", &cal.Version),
+		textField("CALSCALE", &cal.Scale),
+		textField("METHOD", &cal.Method),
+	}
+}
+
+//FromComponent converts a VCALENDAR component. TZIDs are resolved with the VTIMEZONE components of the calendar
+// and, if they are not found there, with go_contentline.DefaultTZResolver.
+func FromComponent(c *go_contentline.Component) (*Calendar, error) {
+	if c.Name != "VCALENDAR" {
+		return nil, errors.Errorf("expected VCALENDAR, got %s", c.Name)
+	}
+	resolve := timezone.Resolver(c, nil)
+	cal := &Calendar{}
+	cal.Extra = decodeFields(c.Properties, cal.fields())
+	for _, sub := range c.Comps {
+		switch sub.Name {
+		case "VEVENT":
+			e, err := EventFromComponent(sub, resolve)
+			if err != nil {
+				return nil, err
+			}
+			cal.Events = append(cal.Events, e)
+		case "VTODO":
+			t, err := TodoFromComponent(sub, resolve)
+			if err != nil {
+				return nil, err
+			}
+			cal.Todos = append(cal.Todos, t)
+		case "VJOURNAL":
+			j, err := JournalFromComponent(sub, resolve)
+			if err != nil {
+				return nil, err
+			}
+			cal.Journals = append(cal.Journals, j)
+		case "VTIMEZONE":
+			cal.TimeZones = append(cal.TimeZones, sub.Clone())
+		default:
+			cal.ExtraComponents = append(cal.ExtraComponents, sub.Clone())
+		}
+	}
+	return cal, nil
+}
+
+//ToComponent converts the calendar into a VCALENDAR component, with the time zones first.
+func (cal *Calendar) ToComponent() *go_contentline.Component {
+	c := &go_contentline.Component{Name: "VCALENDAR"}
+	c.Properties = encodeFields(cal.fields(), cal.Extra)
+	for _, tz := range cal.TimeZones {
+		c.AddComponent(tz.Clone())
+	}
+	for _, e := range cal.Events {
+		c.AddComponent(e.ToComponent())
+	}
+	for _, t := range cal.Todos {
+		c.AddComponent(t.ToComponent())
+	}
+	for _, j := range cal.Journals {
+		c.AddComponent(j.ToComponent())
+	}
+	for _, sub := range cal.ExtraComponents {
+		c.AddComponent(sub.Clone())
+	}
+	return c
+}
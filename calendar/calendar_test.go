@@ -0,0 +1,237 @@
+package calendar
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mqus/go-contentline"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Test//EN\r\n" +
+	"X-WR-CALNAME:Work\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Berlin\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19701025T030000\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU\r\n" +
+	"TZOFFSETFROM:+0200\r\n" +
+	"TZOFFSETTO:+0100\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:19700329T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU\r\n" +
+	"TZOFFSETFROM:+0100\r\n" +
+	"TZOFFSETTO:+0200\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:event-1@example.com\r\n" +
+	"DTSTAMP:20200101T120000Z\r\n" +
+	"SEQUENCE:2\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20200115T100000\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"SUMMARY:Planning\\, part 1\r\n" +
+	"LOCATION:Room 1\r\n" +
+	"CATEGORIES:WORK,MEETING\r\n" +
+	"ORGANIZER;CN=Boss:mailto:boss@example.com\r\n" +
+	"ATTENDEE;CN=A;PARTSTAT=ACCEPTED;RSVP=TRUE;X-FOO=bar:mailto:a@example.com\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=4\r\n" +
+	"EXDATE;TZID=Europe/Berlin:20200122T100000\r\n" +
+	"X-CUSTOM;X-PARAM=1:custom value\r\n" +
+	"DESCRIPTION;ALTREP=\"http://example.com/desc\":Long text\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"TRIGGER;RELATED=END:-PT15M\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:todo-1@example.com\r\n" +
+	"DTSTAMP:20200101T120000Z\r\n" +
+	"DUE;VALUE=DATE:20200201\r\n" +
+	"PERCENT-COMPLETE:50\r\n" +
+	"SUMMARY:Write report\r\n" +
+	"SUMMARY:Second summary\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VJOURNAL\r\n" +
+	"UID:journal-1@example.com\r\n" +
+	"DTSTAMP:20200101T120000Z\r\n" +
+	"DTSTART:20200110T090000\r\n" +
+	"SUMMARY:Notes\r\n" +
+	"END:VJOURNAL\r\n" +
+	"END:VCALENDAR\r\n"
+
+func parseString(t *testing.T, in string) *go_contentline.Component {
+	t.Helper()
+	c, err := go_contentline.InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+//describe lists the properties of a component and its subcomponents in a stable form for comparisons.
+func describe(c *go_contentline.Component) []string {
+	var out []string
+	for _, p := range c.Properties {
+		s := p.Name
+		var keys []string
+		for k := range p.Parameters {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s += ";" + k + "=" + strings.Join(p.Parameters[k], ",")
+		}
+		out = append(out, s+":"+p.Value)
+	}
+	sort.Strings(out)
+	for _, sub := range c.Comps {
+		out = append(out, "BEGIN:"+sub.Name)
+		out = append(out, describe(sub)...)
+		out = append(out, "END:"+sub.Name)
+	}
+	return out
+}
+
+func TestFromComponent(t *testing.T) {
+	cal, err := FromComponent(parseString(t, testCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Version != "2.0" || cal.ProdID != "-//Example//Test//EN" || len(cal.TimeZones) != 1 {
+		t.Errorf("unexpected calendar: %+v", cal)
+	}
+	if len(cal.Extra) != 1 || cal.Extra[0].Name != "X-WR-CALNAME" {
+		t.Errorf("unexpected extra properties of the calendar: %v", cal.Extra)
+	}
+	if len(cal.Events) != 1 || len(cal.Todos) != 1 || len(cal.Journals) != 1 {
+		t.Fatalf("unexpected number of components: %d, %d, %d", len(cal.Events), len(cal.Todos), len(cal.Journals))
+	}
+
+	e := cal.Events[0]
+	if e.UID != "event-1@example.com" || e.Sequence != 2 || e.Summary != "Planning, part 1" || e.Location != "Room 1" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if !e.Stamp.Equal(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected DTSTAMP: %v", e.Stamp)
+	}
+	if !e.Start.Equal(time.Date(2020, 1, 15, 9, 0, 0, 0, time.UTC)) || e.Start.IsDate || e.Start.Location().String() != "Europe/Berlin" {
+		t.Errorf("unexpected DTSTART: %v", e.Start)
+	}
+	if e.Duration != 90*time.Minute {
+		t.Errorf("unexpected DURATION: %v", e.Duration)
+	}
+	if !reflect.DeepEqual(e.Categories, []string{"WORK", "MEETING"}) {
+		t.Errorf("unexpected CATEGORIES: %q", e.Categories)
+	}
+	if e.Organizer == nil || e.Organizer.Address != "mailto:boss@example.com" || e.Organizer.CommonName != "Boss" {
+		t.Errorf("unexpected ORGANIZER: %+v", e.Organizer)
+	}
+	if len(e.Attendees) != 1 {
+		t.Fatalf("unexpected ATTENDEEs: %v", e.Attendees)
+	}
+	a := e.Attendees[0]
+	if a.CommonName != "A" || a.PartStat != "ACCEPTED" || !a.RSVP || a.Params.Get("X-FOO") != "bar" {
+		t.Errorf("unexpected ATTENDEE: %+v", a)
+	}
+	if e.RecurrenceRule == nil || e.RecurrenceRule.Count != 4 || len(e.ExceptionDates) != 1 {
+		t.Errorf("unexpected recurrence: %v, %v", e.RecurrenceRule, e.ExceptionDates)
+	}
+	//the ALTREP parameter of DESCRIPTION can't be represented by the typed field
+	if e.Description != "" || len(e.Extra) != 2 {
+		t.Errorf("unexpected extra properties of the event: %v", e.Extra)
+	}
+	if len(e.Alarms) != 1 || e.Alarms[0].Action != "DISPLAY" || e.Alarms[0].Trigger == nil ||
+		e.Alarms[0].Trigger.Duration != -15*time.Minute || !e.Alarms[0].Trigger.RelatedEnd {
+		t.Errorf("unexpected alarms: %+v", e.Alarms)
+	}
+
+	todo := cal.Todos[0]
+	if todo.Summary != "Write report" || todo.PercentComplete != 50 || !todo.Due.IsDate || len(todo.Extra) != 1 {
+		t.Errorf("unexpected todo: %+v", todo)
+	}
+
+	j := cal.Journals[0]
+	if j.Start.Location() != time.Local || j.Summary != "Notes" {
+		t.Errorf("unexpected journal: %+v", j)
+	}
+}
+
+func TestCalendar_ToComponent(t *testing.T) {
+	in := parseString(t, testCalendar)
+	cal, err := FromComponent(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := cal.ToComponent()
+	if got, want := describe(out), describe(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the calendar:\nwant %q\ngot  %q", want, got)
+	}
+
+	var buf bytes.Buffer
+	out.Encode(&buf)
+	if _, err := FromComponent(parseString(t, buf.String())); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEvent_RoundTripValues(t *testing.T) {
+	in := parseString(t, "BEGIN:VEVENT\r\n"+
+		"UID:1@example.com\r\n"+
+		"URL:http://x.example/a,b;c=1\r\n"+
+		"SUMMARY:needlessly \\:escaped\r\n"+
+		"PRIORITY:05\r\n"+
+		"END:VEVENT\r\n")
+	e, err := EventFromComponent(in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.URL != "http://x.example/a,b;c=1" {
+		t.Errorf("unexpected URL %q", e.URL)
+	}
+	//values which would change when encoded again are kept as they are
+	if e.Summary != "" || e.Priority != 0 || len(e.Extra) != 2 {
+		t.Errorf("unexpected extra properties: %v", e.Extra)
+	}
+	if got, want := describe(e.ToComponent()), describe(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the event:\nwant %q\ngot  %q", want, got)
+	}
+}
+
+func TestEvent_ToComponent(t *testing.T) {
+	e := &Event{}
+	e.UID = "new@example.com"
+	e.Stamp = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	e.Start = DateTime{time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local), true}
+	e.Summary = "Holiday; all day"
+	e.Attendees = []*Attendee{{Address: "mailto:a@example.com", Role: "CHAIR", RSVP: true}}
+	e.Alarms = []*Alarm{{Action: "DISPLAY", Trigger: &Trigger{Time: time.Date(2020, 1, 31, 8, 0, 0, 0, time.UTC)}}}
+
+	want := []string{
+		"ATTENDEE;ROLE=CHAIR;RSVP=TRUE:mailto:a@example.com",
+		"DTSTAMP:20200101T120000Z",
+		"DTSTART;VALUE=DATE:20200201",
+		"SUMMARY:Holiday\\; all day",
+		"UID:new@example.com",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER;VALUE=DATE-TIME:20200131T080000Z",
+		"END:VALARM",
+	}
+	if got := describe(e.ToComponent()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted %q, got %q", want, got)
+	}
+}
+
+func TestEventFromComponent_WrongName(t *testing.T) {
+	if _, err := EventFromComponent(&go_contentline.Component{Name: "VTODO"}, nil); err == nil {
+		t.Error("expected an error")
+	}
+}
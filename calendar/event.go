@@ -0,0 +1,234 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/recurrence"
+	"github.com/pkg/errors"
+)
+
+//Common contains the properties shared by events, to-dos and journal entries.
+type Common struct {
+	UID          string
+	Stamp        time.Time //DTSTAMP
+	Created      time.Time //CREATED
+	LastModified time.Time //LAST-MODIFIED
+	Sequence     int       //SEQUENCE
+
+	Start       DateTime //DTSTART
+	Summary     string   //SUMMARY
+	Description string   //DESCRIPTION
+	Status      string   //STATUS, e.g. CONFIRMED
+	Class       string   //CLASS, e.g. PUBLIC
+	URL         string   //URL
+	Categories  []string //CATEGORIES
+
+	Organizer *Organizer
+	Attendees []*Attendee
+
+	//RecurrenceID and ThisAndFuture (RANGE=THISANDFUTURE) identify the instance replaced by an override.
+	RecurrenceID  DateTime
+	ThisAndFuture bool
+
+	RecurrenceRule  *recurrence.Rule //RRULE
+	RecurrenceDates []DateTime       //RDATE
+	ExceptionDates  []DateTime       //EXDATE
+
+	//Extra contains all properties which can't be represented by the typed fields.
+	Extra []*go_contentline.Property
+}
+
+func (c *Common) fields(resolve go_contentline.TZResolver) []field {
+	return []field{
+		textField("UID", &c.UID),
+		timeField("DTSTAMP", &c.Stamp, resolve),
+		timeField("CREATED", &c.Created, resolve),
+		timeField("LAST-MODIFIED", &c.LastModified, resolve),
+		intField("SEQUENCE", &c.Sequence),
+		dateTimeField("DTSTART", &c.Start, resolve),
+		textField("SUMMARY", &c.Summary),
+		textField("DESCRIPTION", &c.Description),
+		textField("STATUS", &c.Status),
+		textField("CLASS", &c.Class),
+		rawField("URL", &c.URL),
+		listField("CATEGORIES", &c.Categories),
+		organizerField(&c.Organizer),
+		attendeesField(&c.Attendees),
+		recurrenceIDField(&c.RecurrenceID, &c.ThisAndFuture, resolve),
+		ruleField("RRULE", &c.RecurrenceRule),
+		dateListField("RDATE", &c.RecurrenceDates, resolve),
+		dateListField("EXDATE", &c.ExceptionDates, resolve),
+	}
+}
+
+//recurrenceIDField decodes RECURRENCE-ID with its RANGE parameter.
+func recurrenceIDField(v *DateTime, thisAndFuture *bool, resolve go_contentline.TZResolver) field {
+	f := dateTimeField("RECURRENCE-ID", v, resolve)
+	decode, encode := f.decode, f.encode
+	f.decode = func(p *go_contentline.Property) error {
+		*thisAndFuture = strings.EqualFold(p.Parameters.Get("RANGE"), "THISANDFUTURE")
+		return decode(p)
+	}
+	f.encode = func() []*go_contentline.Property {
+		out := encode()
+		if len(out) > 0 && *thisAndFuture {
+			out[0].SetParameter("RANGE", "THISANDFUTURE")
+		}
+		return out
+	}
+	f.reset = func() {
+		*v = DateTime{}
+		*thisAndFuture = false
+	}
+	return f
+}
+
+//Event is a VEVENT component.
+type Event struct {
+	Common
+	End          DateTime      //DTEND
+	Duration     time.Duration //DURATION
+	Location     string        //LOCATION
+	Transparency string        //TRANSP, e.g. OPAQUE
+	Priority     int           //PRIORITY
+
+	Alarms []*Alarm
+	//ExtraComponents contains all subcomponents except VALARM.
+	ExtraComponents []*go_contentline.Component
+}
+
+func (e *Event) fields(resolve go_contentline.TZResolver) []field {
+	return append(e.Common.fields(resolve),
+		dateTimeField("DTEND", &e.End, resolve),
+		durationField("DURATION", &e.Duration),
+		textField("LOCATION", &e.Location),
+		textField("TRANSP", &e.Transparency),
+		intField("PRIORITY", &e.Priority),
+	)
+}
+
+//EventFromComponent converts a VEVENT component. TZIDs are resolved with resolve, which defaults to
+// go_contentline.DefaultTZResolver if nil. Use timezone.Resolver for the VTIMEZONEs of a calendar.
+func EventFromComponent(c *go_contentline.Component, resolve go_contentline.TZResolver) (*Event, error) {
+	if c.Name != "VEVENT" {
+		return nil, errors.Errorf("expected VEVENT, got %s", c.Name)
+	}
+	e := &Event{}
+	e.Extra = decodeFields(c.Properties, e.fields(floating(resolve)))
+	var err error
+	e.Alarms, e.ExtraComponents, err = decodeAlarms(c, resolve)
+	return e, err
+}
+
+//ToComponent converts the event into a VEVENT component.
+func (e *Event) ToComponent() *go_contentline.Component {
+	c := &go_contentline.Component{Name: "VEVENT"}
+	c.Properties = encodeFields(e.fields(nil), e.Extra)
+	encodeAlarms(c, e.Alarms, e.ExtraComponents)
+	return c
+}
+
+//Todo is a VTODO component.
+type Todo struct {
+	Common
+	Due             DateTime      //DUE
+	Duration        time.Duration //DURATION
+	Completed       time.Time     //COMPLETED
+	PercentComplete int           //PERCENT-COMPLETE
+	Location        string        //LOCATION
+	Priority        int           //PRIORITY
+
+	Alarms []*Alarm
+	//ExtraComponents contains all subcomponents except VALARM.
+	ExtraComponents []*go_contentline.Component
+}
+
+func (t *Todo) fields(resolve go_contentline.TZResolver) []field {
+	return append(t.Common.fields(resolve),
+		dateTimeField("DUE", &t.Due, resolve),
+		durationField("DURATION", &t.Duration),
+		timeField("COMPLETED", &t.Completed, resolve),
+		intField("PERCENT-COMPLETE", &t.PercentComplete),
+		textField("LOCATION", &t.Location),
+		intField("PRIORITY", &t.Priority),
+	)
+}
+
+//TodoFromComponent converts a VTODO component, see EventFromComponent.
+func TodoFromComponent(c *go_contentline.Component, resolve go_contentline.TZResolver) (*Todo, error) {
+	if c.Name != "VTODO" {
+		return nil, errors.Errorf("expected VTODO, got %s", c.Name)
+	}
+	t := &Todo{}
+	t.Extra = decodeFields(c.Properties, t.fields(floating(resolve)))
+	var err error
+	t.Alarms, t.ExtraComponents, err = decodeAlarms(c, resolve)
+	return t, err
+}
+
+//ToComponent converts the to-do into a VTODO component.
+func (t *Todo) ToComponent() *go_contentline.Component {
+	c := &go_contentline.Component{Name: "VTODO"}
+	c.Properties = encodeFields(t.fields(nil), t.Extra)
+	encodeAlarms(c, t.Alarms, t.ExtraComponents)
+	return c
+}
+
+//Journal is a VJOURNAL component.
+type Journal struct {
+	Common
+	//ExtraComponents contains all subcomponents.
+	ExtraComponents []*go_contentline.Component
+}
+
+//JournalFromComponent converts a VJOURNAL component, see EventFromComponent.
+func JournalFromComponent(c *go_contentline.Component, resolve go_contentline.TZResolver) (*Journal, error) {
+	if c.Name != "VJOURNAL" {
+		return nil, errors.Errorf("expected VJOURNAL, got %s", c.Name)
+	}
+	j := &Journal{}
+	j.Extra = decodeFields(c.Properties, j.fields(floating(resolve)))
+	for _, sub := range c.Comps {
+		j.ExtraComponents = append(j.ExtraComponents, sub.Clone())
+	}
+	return j, nil
+}
+
+//ToComponent converts the journal entry into a VJOURNAL component.
+func (j *Journal) ToComponent() *go_contentline.Component {
+	c := &go_contentline.Component{Name: "VJOURNAL"}
+	c.Properties = encodeFields(j.fields(nil), j.Extra)
+	for _, sub := range j.ExtraComponents {
+		c.AddComponent(sub.Clone())
+	}
+	return c
+}
+
+//decodeAlarms converts the VALARM subcomponents of c and returns copies of all other subcomponents.
+func decodeAlarms(c *go_contentline.Component, resolve go_contentline.TZResolver) ([]*Alarm, []*go_contentline.Component, error) {
+	var alarms []*Alarm
+	var extra []*go_contentline.Component
+	for _, sub := range c.Comps {
+		if sub.Name != "VALARM" {
+			extra = append(extra, sub.Clone())
+			continue
+		}
+		a, err := AlarmFromComponent(sub, resolve)
+		if err != nil {
+			return nil, nil, err
+		}
+		alarms = append(alarms, a)
+	}
+	return alarms, extra, nil
+}
+
+func encodeAlarms(c *go_contentline.Component, alarms []*Alarm, extra []*go_contentline.Component) {
+	for _, a := range alarms {
+		c.AddComponent(a.ToComponent())
+	}
+	for _, sub := range extra {
+		c.AddComponent(sub.Clone())
+	}
+}
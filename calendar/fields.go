@@ -0,0 +1,355 @@
+package calendar
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/recurrence"
+	"github.com/pkg/errors"
+)
+
+//field maps the properties with a given name onto a field of a model.
+type field struct {
+	name string
+	//multi is true if the property can occur more than once
+	multi bool
+	//decode stores the value of the property in the field
+	decode func(p *go_contentline.Property) error
+	//encode returns the properties for the value of the field, the property decoded last is the last one
+	encode func() []*go_contentline.Property
+	//reset reverts the last call of decode
+	reset func()
+	//lastValue returns the encoded value of the property decoded last, for fields which don't encode each property on
+	// its own. If it is nil, the value of the property encoded last is used.
+	lastValue func() string
+}
+
+//decodeFields decodes the properties into the fields and returns the properties which can't be represented by them:
+// unknown or repeated properties, properties with values which can't be decoded and properties whose value or
+// parameters would change when they are encoded again.
+func decodeFields(props []*go_contentline.Property, fields []field) []*go_contentline.Property {
+	byName := make(map[string]*field)
+	for i := range fields {
+		byName[fields[i].name] = &fields[i]
+	}
+	seen := make(map[string]bool)
+	var extra []*go_contentline.Property
+	for _, p := range props {
+		f := byName[strings.ToUpper(p.Name)]
+		if f == nil || p.Group != "" || (seen[f.name] && !f.multi) {
+			extra = append(extra, p.Clone())
+			continue
+		}
+		if err := f.decode(p); err != nil {
+			extra = append(extra, p.Clone())
+			continue
+		}
+		enc := f.encode()
+		if len(enc) == 0 || !f.lossless(enc[len(enc)-1], p) {
+			f.reset()
+			extra = append(extra, p.Clone())
+			continue
+		}
+		seen[f.name] = true
+	}
+	return extra
+}
+
+//lossless checks whether enc, the property encoded last, keeps the value and parameters of p, the property decoded
+// last.
+func (f *field) lossless(enc, p *go_contentline.Property) bool {
+	value := enc.Value
+	if f.lastValue != nil {
+		value = f.lastValue()
+	}
+	return value == p.Value && sameParameters(enc.Parameters, p.Parameters)
+}
+
+//encodeFields returns the properties of all fields, followed by copies of the extra properties.
+func encodeFields(fields []field, extra []*go_contentline.Property) []*go_contentline.Property {
+	var out []*go_contentline.Property
+	for _, f := range fields {
+		out = append(out, f.encode()...)
+	}
+	for _, p := range extra {
+		out = append(out, p.Clone())
+	}
+	return out
+}
+
+//sameParameters compares two sets of parameters, the names are compared case-insensitively.
+func sameParameters(a, b go_contentline.Parameters) bool {
+	count := func(ps go_contentline.Parameters) int {
+		n := 0
+		for _, vals := range ps {
+			if len(vals) > 0 {
+				n++
+			}
+		}
+		return n
+	}
+	if count(a) != count(b) {
+		return false
+	}
+	for k, vals := range a {
+		if len(vals) == 0 {
+			continue
+		}
+		var other []string
+		for k2, vals2 := range b {
+			if strings.EqualFold(k, k2) {
+				other = vals2
+			}
+		}
+		if len(other) != len(vals) {
+			return false
+		}
+		for i := range vals {
+			if vals[i] != other[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newProperty(name, value string) *go_contentline.Property {
+	return go_contentline.NewPropertyUnchecked(name, value, make(go_contentline.Parameters))
+}
+
+func single(p *go_contentline.Property) []*go_contentline.Property {
+	return []*go_contentline.Property{p}
+}
+
+func textField(name string, v *string) field {
+	return field{
+		name: name,
+		decode: func(p *go_contentline.Property) error {
+			*v = go_contentline.UnescapeText(p.Value)
+			return nil
+		},
+		encode: func() []*go_contentline.Property {
+			if *v == "" {
+				return nil
+			}
+			return single(newProperty(name, go_contentline.EscapeText(*v)))
+		},
+		reset: func() { *v = "" },
+	}
+}
+
+//rawField decodes properties whose values are not TEXT, like URL. The value is kept as it is, without unescaping.
+func rawField(name string, v *string) field {
+	return field{
+		name: name,
+		decode: func(p *go_contentline.Property) error {
+			*v = p.Value
+			return nil
+		},
+		encode: func() []*go_contentline.Property {
+			if *v == "" {
+				return nil
+			}
+			return single(newProperty(name, *v))
+		},
+		reset: func() { *v = "" },
+	}
+}
+
+//listField decodes properties with a list of TEXT values, like CATEGORIES. They are encoded as a single property.
+func listField(name string, v *[]string) field {
+	last := 0
+	escaped := func(vals []string) string {
+		out := make([]string, len(vals))
+		for i, s := range vals {
+			out[i] = go_contentline.EscapeText(s)
+		}
+		return strings.Join(out, ",")
+	}
+	return field{
+		name:  name,
+		multi: true,
+		decode: func(p *go_contentline.Property) error {
+			last = len(*v)
+			*v = append(*v, go_contentline.SplitText(p.Value)...)
+			return nil
+		},
+		encode: func() []*go_contentline.Property {
+			if len(*v) == 0 {
+				return nil
+			}
+			return single(newProperty(name, escaped(*v)))
+		},
+		reset:     func() { *v = (*v)[:last] },
+		lastValue: func() string { return escaped((*v)[last:]) },
+	}
+}
+
+func intField(name string, v *int) field {
+	return field{
+		name: name,
+		decode: func(p *go_contentline.Property) (err error) {
+			*v, err = strconv.Atoi(strings.TrimSpace(p.Value))
+			return err
+		},
+		encode: func() []*go_contentline.Property {
+			if *v == 0 {
+				return nil
+			}
+			return single(newProperty(name, strconv.Itoa(*v)))
+		},
+		reset: func() { *v = 0 },
+	}
+}
+
+func durationField(name string, v *time.Duration) field {
+	return field{
+		name: name,
+		decode: func(p *go_contentline.Property) (err error) {
+			*v, err = go_contentline.ParseDuration(p.Value)
+			return err
+		},
+		encode: func() []*go_contentline.Property {
+			if *v == 0 {
+				return nil
+			}
+			return single(newProperty(name, go_contentline.FormatDuration(*v)))
+		},
+		reset: func() { *v = 0 },
+	}
+}
+
+//timeField decodes DATE-TIME values, like DTSTAMP.
+func timeField(name string, v *time.Time, resolve go_contentline.TZResolver) field {
+	return field{
+		name: name,
+		decode: func(p *go_contentline.Property) error {
+			t, isDate, err := p.DateTime(resolve)
+			if err == nil && isDate {
+				err = errors.Errorf("%s: expected DATE-TIME", name)
+			}
+			*v = t
+			return err
+		},
+		encode: func() []*go_contentline.Property {
+			if v.IsZero() {
+				return nil
+			}
+			p := newProperty(name, "")
+			p.SetDateTime(*v, false)
+			return single(p)
+		},
+		reset: func() { *v = time.Time{} },
+	}
+}
+
+func dateTimeField(name string, v *DateTime, resolve go_contentline.TZResolver) field {
+	return field{
+		name: name,
+		decode: func(p *go_contentline.Property) (err error) {
+			v.Time, v.IsDate, err = p.DateTime(resolve)
+			return err
+		},
+		encode: func() []*go_contentline.Property {
+			if v.IsZero() {
+				return nil
+			}
+			return single(v.property(name))
+		},
+		reset: func() { *v = DateTime{} },
+	}
+}
+
+//dateListField decodes properties with lists of DATE or DATE-TIME values, like EXDATE. Each value is encoded as a
+// property of its own. PERIOD values are not supported.
+func dateListField(name string, v *[]DateTime, resolve go_contentline.TZResolver) field {
+	last := 0
+	return field{
+		name:  name,
+		multi: true,
+		decode: func(p *go_contentline.Property) error {
+			if strings.EqualFold(p.Parameters.Get("VALUE"), "PERIOD") {
+				return errors.New("PERIOD values are not supported")
+			}
+			ts, isDate, err := p.DateTimes(resolve)
+			if err != nil {
+				return err
+			}
+			last = len(*v)
+			for _, t := range ts {
+				*v = append(*v, DateTime{t, isDate})
+			}
+			return nil
+		},
+		encode: func() []*go_contentline.Property {
+			var out []*go_contentline.Property
+			for _, dt := range *v {
+				out = append(out, dt.property(name))
+			}
+			return out
+		},
+		reset: func() { *v = (*v)[:last] },
+		lastValue: func() string {
+			vals := make([]string, 0, len(*v)-last)
+			for _, dt := range (*v)[last:] {
+				vals = append(vals, dt.property(name).Value)
+			}
+			return strings.Join(vals, ",")
+		},
+	}
+}
+
+func ruleField(name string, v **recurrence.Rule) field {
+	return field{
+		name: name,
+		decode: func(p *go_contentline.Property) (err error) {
+			*v, err = recurrence.ParseRule(p.Value)
+			return err
+		},
+		encode: func() []*go_contentline.Property {
+			if *v == nil {
+				return nil
+			}
+			return single(newProperty(name, (*v).String()))
+		},
+		reset: func() { *v = nil },
+	}
+}
+
+func organizerField(v **Organizer) field {
+	return field{
+		name: "ORGANIZER",
+		decode: func(p *go_contentline.Property) error {
+			*v = organizerFromProperty(p)
+			return nil
+		},
+		encode: func() []*go_contentline.Property {
+			if *v == nil {
+				return nil
+			}
+			return single((*v).property())
+		},
+		reset: func() { *v = nil },
+	}
+}
+
+func attendeesField(v *[]*Attendee) field {
+	return field{
+		name:  "ATTENDEE",
+		multi: true,
+		decode: func(p *go_contentline.Property) error {
+			*v = append(*v, attendeeFromProperty(p))
+			return nil
+		},
+		encode: func() []*go_contentline.Property {
+			var out []*go_contentline.Property
+			for _, a := range *v {
+				out = append(out, a.property())
+			}
+			return out
+		},
+		reset: func() { *v = (*v)[:len(*v)-1] },
+	}
+}
@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"strings"
+
+	"github.com/mqus/go-contentline"
+)
+
+//Organizer is the ORGANIZER of a scheduled component (RFC5545, Section 3.8.4.3).
+type Organizer struct {
+	//Address is the calendar user address, e.g. mailto:boss@example.com.
+	Address    string
+	CommonName string //CN
+	SentBy     string //SENT-BY
+	Dir        string //DIR
+	Language   string //LANGUAGE
+
+	//Params contains all other parameters.
+	Params go_contentline.Parameters
+}
+
+//Attendee is an ATTENDEE of a scheduled component or an alarm (RFC5545, Section 3.8.4.1).
+type Attendee struct {
+	//Address is the calendar user address, e.g. mailto:a@example.com.
+	Address       string
+	CommonName    string   //CN
+	CUType        string   //CUTYPE, e.g. INDIVIDUAL or ROOM
+	Role          string   //ROLE, e.g. REQ-PARTICIPANT
+	PartStat      string   //PARTSTAT, e.g. ACCEPTED
+	RSVP          bool     //RSVP=TRUE
+	Member        []string //MEMBER
+	DelegatedTo   []string //DELEGATED-TO
+	DelegatedFrom []string //DELEGATED-FROM
+	SentBy        string   //SENT-BY
+	Dir           string   //DIR
+	Language      string   //LANGUAGE
+
+	//Params contains all other parameters, including RSVP if it is not TRUE.
+	Params go_contentline.Parameters
+}
+
+//paramTarget maps a parameter name onto a field.
+type paramTarget struct {
+	name   string
+	single *string
+	list   *[]string
+}
+
+func (o *Organizer) params() []paramTarget {
+	return []paramTarget{
+		{name: "CN", single: &o.CommonName},
+		{name: "SENT-BY", single: &o.SentBy},
+		{name: "DIR", single: &o.Dir},
+		{name: "LANGUAGE", single: &o.Language},
+	}
+}
+
+func (a *Attendee) params() []paramTarget {
+	return []paramTarget{
+		{name: "CN", single: &a.CommonName},
+		{name: "CUTYPE", single: &a.CUType},
+		{name: "ROLE", single: &a.Role},
+		{name: "PARTSTAT", single: &a.PartStat},
+		{name: "MEMBER", list: &a.Member},
+		{name: "DELEGATED-TO", list: &a.DelegatedTo},
+		{name: "DELEGATED-FROM", list: &a.DelegatedFrom},
+		{name: "SENT-BY", single: &a.SentBy},
+		{name: "DIR", single: &a.Dir},
+		{name: "LANGUAGE", single: &a.Language},
+	}
+}
+
+//decodeParams stores the parameters in the targets and returns all others.
+func decodeParams(ps go_contentline.Parameters, targets []paramTarget) go_contentline.Parameters {
+	rest := make(go_contentline.Parameters)
+outer:
+	for k, vals := range ps {
+		for _, t := range targets {
+			if !strings.EqualFold(k, t.name) {
+				continue
+			}
+			if t.list != nil {
+				*t.list = append([]string(nil), vals...)
+				continue outer
+			}
+			if len(vals) == 1 {
+				*t.single = vals[0]
+				continue outer
+			}
+		}
+		rest[k] = append([]string(nil), vals...)
+	}
+	return rest
+}
+
+//encodeParams creates a property with the parameters of the targets and the other parameters.
+func encodeParams(name, value string, targets []paramTarget, rest go_contentline.Parameters) *go_contentline.Property {
+	p := newProperty(name, value)
+	for _, t := range targets {
+		switch {
+		case t.list != nil && len(*t.list) > 0:
+			p.SetParameter(t.name, *t.list...)
+		case t.single != nil && *t.single != "":
+			p.SetParameter(t.name, *t.single)
+		}
+	}
+	for k, vals := range rest {
+		p.SetParameter(k, append([]string(nil), vals...)...)
+	}
+	return p
+}
+
+func organizerFromProperty(p *go_contentline.Property) *Organizer {
+	o := &Organizer{Address: p.Value}
+	o.Params = decodeParams(p.Parameters, o.params())
+	return o
+}
+
+func (o *Organizer) property() *go_contentline.Property {
+	return encodeParams("ORGANIZER", o.Address, o.params(), o.Params)
+}
+
+func attendeeFromProperty(p *go_contentline.Property) *Attendee {
+	a := &Attendee{Address: p.Value}
+	a.Params = decodeParams(p.Parameters, a.params())
+	if vals := a.Params["RSVP"]; len(vals) == 1 && vals[0] == "TRUE" {
+		a.RSVP = true
+		delete(a.Params, "RSVP")
+	}
+	return a
+}
+
+func (a *Attendee) property() *go_contentline.Property {
+	p := encodeParams("ATTENDEE", a.Address, a.params(), a.Params)
+	if a.RSVP {
+		p.SetParameter("RSVP", "TRUE")
+	}
+	return p
+}
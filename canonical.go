@@ -0,0 +1,166 @@
+package go_contentline
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"time"
+)
+
+//textProperties lists the properties of RFC5545 and RFC6350 whose values are of the type TEXT by default.
+var textProperties = map[string]bool{
+	"SUMMARY": true, "DESCRIPTION": true, "LOCATION": true, "COMMENT": true, "CONTACT": true, "RESOURCES": true,
+	"CATEGORIES": true, "TZNAME": true, "FN": true, "N": true, "NICKNAME": true, "ADR": true, "ORG": true,
+	"TITLE": true, "ROLE": true, "NOTE": true, "LABEL": true,
+}
+
+//isText reports whether the value of p is of the type TEXT, either by default (see textProperties) or given by the
+// VALUE parameter.
+func isText(p *Property) bool {
+	valueType := strings.ToUpper(p.Parameters.Get("VALUE"))
+	return valueType == "TEXT" || (valueType == "" && textProperties[strings.ToUpper(p.Name)])
+}
+
+//Canonicalize converts the component tree into a canonical form, so that equivalent components are encoded
+// byte-identically, e.g. to compare or hash them:
+//
+// All names, groups and parameter names are upper case. Parameter values are sorted. Properties are sorted by
+// name, then value, then group and parameters, except for the VERSION of a VCARD, which stays first. Subcomponents
+// are sorted by name and UID (and their encoded form if they have the same name and UID). TEXT values (of the
+// properties of RFC5545 and RFC6350 which are TEXT by default, or with VALUE=TEXT) use '\n' for line breaks and lose
+// unnecessary backslashes. DATE and DATE-TIME values are upper case and DATE-TIME values with TZID=UTC use the UTC
+// designator 'Z' instead.
+//
+// The component is changed in place. The canonical form keeps the meaning of the component, except for the order of
+// properties, components and parameter values, which has no meaning in most cases.
+func (c *Component) Canonicalize() {
+	c.Name = strings.ToUpper(c.Name)
+	for _, p := range c.Properties {
+		p.canonicalize()
+	}
+	keys := make(map[*Property]string, len(c.Properties))
+	for _, p := range c.Properties {
+		var buf bytes.Buffer
+		p.Encode(&buf)
+		keys[p] = buf.String()
+	}
+	sort.SliceStable(c.Properties, func(i, j int) bool {
+		a, b := c.Properties[i], c.Properties[j]
+		if a.Name != b.Name {
+			//VERSION has to be the first property of a VCARD
+			if c.Name == "VCARD" && (a.Name == "VERSION" || b.Name == "VERSION") {
+				return a.Name == "VERSION"
+			}
+			return a.Name < b.Name
+		}
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		return keys[a] < keys[b]
+	})
+
+	for _, sub := range c.Comps {
+		sub.Canonicalize()
+	}
+	encoded := make(map[*Component]string, len(c.Comps))
+	for _, sub := range c.Comps {
+		var buf bytes.Buffer
+		sub.Encode(&buf)
+		encoded[sub] = buf.String()
+	}
+	sort.SliceStable(c.Comps, func(i, j int) bool {
+		a, b := c.Comps[i], c.Comps[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if ua, ub := uidOf(a), uidOf(b); ua != ub {
+			return ua < ub
+		}
+		return encoded[a] < encoded[b]
+	})
+}
+
+//uidOf returns the UID of c, or an empty string if it has none.
+func uidOf(c *Component) string {
+	if p := c.GetProperty("UID"); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+//canonicalize converts the property into its canonical form, see Component.Canonicalize.
+func (p *Property) canonicalize() {
+	p.Name = strings.ToUpper(p.Name)
+	p.Group = strings.ToUpper(p.Group)
+	params := make(Parameters, len(p.Parameters))
+	for k, vals := range p.Parameters {
+		k = strings.ToUpper(k)
+		params[k] = append(params[k], vals...)
+		sort.Strings(params[k])
+	}
+	p.Parameters = params
+
+	valueType := strings.ToUpper(p.Parameters.Get("VALUE"))
+	switch {
+	case isText(p):
+		p.Value = canonicalText(p.Value)
+	case valueType == "DATE" || valueType == "DATE-TIME" || (valueType == "" && isDateTimeList(p.Value)):
+		p.Value = strings.ToUpper(p.Value)
+		tzid := strings.ToUpper(p.Parameters.Get("TZID"))
+		if (tzid == "UTC" || tzid == "ETC/UTC") && valueType != "DATE" {
+			values := strings.Split(p.Value, ",")
+			for i, v := range values {
+				if !strings.HasSuffix(v, "Z") && strings.Contains(v, "T") {
+					values[i] = v + "Z"
+				}
+			}
+			p.Value = strings.Join(values, ",")
+			delete(p.Parameters, "TZID")
+		}
+	}
+}
+
+//isDateTimeList reports whether s consists of DATE or DATE-TIME values, ignoring case.
+func isDateTimeList(s string) bool {
+	for _, v := range strings.Split(strings.ToUpper(s), ",") {
+		if _, err := ParseDateTime(v, time.UTC); err == nil {
+			continue
+		}
+		if len(v) != len(dateLayout) {
+			return false
+		}
+		if _, err := ParseDate(v, time.UTC); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+//canonicalText converts the escaping of a TEXT value into its canonical form: '\N' becomes '\n', backslashes
+// in front of other characters than '\', ';', ',' and 'n' are removed and a trailing backslash is escaped.
+func canonicalText(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+		if i+1 == len(s) {
+			out = append(out, '\\', '\\')
+			break
+		}
+		i++
+		switch s[i] {
+		case '\\', ';', ',', 'n':
+			out = append(out, '\\', s[i])
+		case 'N':
+			out = append(out, '\\', 'n')
+		default:
+			out = append(out, s[i])
+		}
+	}
+	return string(out)
+}
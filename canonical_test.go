@@ -0,0 +1,82 @@
+package go_contentline
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestComponent_Canonicalize(t *testing.T) {
+	a := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:2\r\n" +
+		"SUMMARY:a\\:b\\Nc\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:1\r\n" +
+		"DTSTART;TZID=UTC:20200101t100000\r\n" +
+		"ATTENDEE;ROLE=CHAIR;X-A=b,a:mailto:b@example.com\r\n" +
+		"ATTENDEE:mailto:a@example.com\r\n" +
+		"END:VEVENT\r\n" +
+		"PRODID:x\r\n" +
+		"END:VCALENDAR\r\n"
+	b := "BEGIN:vcalendar\r\n" +
+		"prodid:x\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"attendee:mailto:a@example.com\r\n" +
+		"DTSTART:20200101T100000Z\r\n" +
+		"Attendee;x-a=a,b;role=CHAIR:mailto:b@example.com\r\n" +
+		"UID:1\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:a:b\\nc\r\n" +
+		"UID:2\r\n" +
+		"END:VEVENT\r\n" +
+		"version:2.0\r\n" +
+		"END:vcalendar\r\n"
+	want := "BEGIN:VCALENDAR\r\n" +
+		"PRODID:x\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"ATTENDEE:mailto:a@example.com\r\n" +
+		"ATTENDEE;ROLE=CHAIR;X-A=a,b:mailto:b@example.com\r\n" +
+		"DTSTART:20200101T100000Z\r\n" +
+		"UID:1\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:a:b\\nc\r\n" +
+		"UID:2\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	for _, in := range []string{a, b} {
+		c := parseString(t, in)
+		c.Canonicalize()
+		var buf bytes.Buffer
+		c.Encode(&buf)
+		if buf.String() != want {
+			t.Errorf("Wanted\n%q\ngot\n%q", want, buf.String())
+		}
+	}
+
+	card := parseString(t, "BEGIN:VCARD\r\nFN:x\r\nVERSION:4.0\r\nEMAIL:a@example.com\r\nEND:VCARD\r\n")
+	card.Canonicalize()
+	var buf bytes.Buffer
+	card.Encode(&buf)
+	if want := "BEGIN:VCARD\r\nVERSION:4.0\r\nEMAIL:a@example.com\r\nFN:x\r\nEND:VCARD\r\n"; buf.String() != want {
+		t.Errorf("Wanted %q, got %q", want, buf.String())
+	}
+}
+
+func TestCanonicalText(t *testing.T) {
+	checks := map[string]string{
+		`plain`:    `plain`,
+		`a\,b\;c`:  `a\,b\;c`,
+		`a\Nb\nc`:  `a\nb\nc`,
+		`a\:b\\c\`: `a:b\\c\\`,
+	}
+	for in, want := range checks {
+		if got := canonicalText(in); got != want {
+			t.Errorf("%q: Wanted %q, got %q", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package go_contentline
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+//windows1252 contains the characters of Windows-1252 in the range 0x80-0x9F, where it differs from ISO-8859-1.
+// Unassigned positions are mapped to the C1 control character of the same value, as done by most decoders.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+//decodeCharset converts text in the given charset to UTF-8. Supported are UTF-8, US-ASCII, ISO-8859-1 and
+// Windows-1252, the charsets found in files written by older address books and phones.
+func decodeCharset(charset string, b []byte) (string, error) {
+	switch charsetName(charset) {
+	case "", "UTF8", "USASCII", "ASCII":
+		//ASCII is checked as UTF-8, which is compatible to it and often mislabeled as ASCII
+		if !utf8.Valid(b) {
+			return "", errors.New("invalid UTF-8")
+		}
+		return string(b), nil
+	case "ISO88591", "LATIN1":
+		out := make([]rune, len(b))
+		for i, c := range b {
+			out[i] = rune(c)
+		}
+		return string(out), nil
+	case "WINDOWS1252", "CP1252":
+		out := make([]rune, len(b))
+		for i, c := range b {
+			out[i] = rune(c)
+			if c >= 0x80 && c < 0xA0 {
+				out[i] = windows1252[c-0x80]
+			}
+		}
+		return string(out), nil
+	}
+	return "", errors.Errorf("unsupported charset %s", charset)
+}
+
+//charsetName normalizes the name of a charset for comparisons, e.g. "utf-8" to "UTF8".
+func charsetName(charset string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToUpper(charset))
+}
+
+//utf8BOM is the byte order mark of UTF-8.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+//decodeInput returns a reader which transcodes the input to UTF-8, see ParserOptions.Charset, and whether the input
+// is transcoded. Byte order marks are removed.
+func decodeInput(r io.Reader, charset string) (io.Reader, bool) {
+	br := bufio.NewReader(r)
+	start, _ := br.Peek(3)
+	name := charsetName(charset)
+	switch name {
+	case "", "UTF16", "UTF16BE", "UTF16LE":
+		switch {
+		case bytes.HasPrefix(start, utf8BOM) && name == "":
+			br.Discard(len(utf8BOM))
+			return br, false
+		case bytes.HasPrefix(start, []byte{0xFE, 0xFF}) && name != "UTF16LE":
+			br.Discard(2)
+			return &utf16Reader{r: br, bigEndian: true}, true
+		case bytes.HasPrefix(start, []byte{0xFF, 0xFE}) && name != "UTF16BE":
+			br.Discard(2)
+			return &utf16Reader{r: br}, true
+		//without BOM, UTF-16 is recognized by the zero byte of the first (ASCII) character
+		case name == "UTF16LE" || (name == "" && len(start) >= 2 && start[0] != 0 && start[1] == 0):
+			return &utf16Reader{r: br}, true
+		case name != "" || (len(start) >= 2 && start[0] == 0 && start[1] != 0):
+			return &utf16Reader{r: br, bigEndian: true}, true
+		}
+		return br, false
+	case "UTF8", "USASCII", "ASCII":
+		if bytes.HasPrefix(start, utf8BOM) {
+			br.Discard(len(utf8BOM))
+		}
+		return br, false
+	}
+	if _, err := decodeCharset(charset, nil); err != nil {
+		return &errReader{err}, false
+	}
+	return &charsetReader{r: br, charset: charset}, true
+}
+
+//errReader returns err on every read.
+type errReader struct {
+	err error
+}
+
+func (e *errReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
+
+//charsetReader transcodes a single-byte charset supported by decodeCharset to UTF-8.
+type charsetReader struct {
+	r       io.Reader
+	charset string
+	pending []byte
+}
+
+func (c *charsetReader) Read(b []byte) (int, error) {
+	if len(c.pending) == 0 {
+		//each byte becomes at most 3 bytes of UTF-8
+		buf := make([]byte, len(b)/3+1)
+		n, err := c.r.Read(buf)
+		if n == 0 {
+			return 0, err
+		}
+		s, err := decodeCharset(c.charset, buf[:n])
+		if err != nil {
+			return 0, err
+		}
+		c.pending = []byte(s)
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+//utf16Reader transcodes UTF-16 to UTF-8. Unpaired surrogates are replaced by U+FFFD.
+type utf16Reader struct {
+	r         *bufio.Reader
+	bigEndian bool
+	err       error
+	buf       [utf8.UTFMax]byte
+	pending   []byte
+}
+
+func (u *utf16Reader) Read(b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		if len(u.pending) == 0 {
+			if u.err != nil {
+				break
+			}
+			var r rune
+			if r, u.err = u.readRune(); u.err != nil {
+				break
+			}
+			u.pending = u.buf[:utf8.EncodeRune(u.buf[:], r)]
+		}
+		c := copy(b[n:], u.pending)
+		u.pending = u.pending[c:]
+		n += c
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, u.err
+}
+
+func (u *utf16Reader) unit(b []byte) rune {
+	if u.bigEndian {
+		return rune(b[0])<<8 | rune(b[1])
+	}
+	return rune(b[1])<<8 | rune(b[0])
+}
+
+func (u *utf16Reader) readRune() (rune, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, errors.New("invalid UTF-16: odd number of bytes")
+		}
+		return 0, err
+	}
+	r := u.unit(b[:])
+	if !utf16.IsSurrogate(r) {
+		return r, nil
+	}
+	//the low surrogate is only consumed if it completes the pair
+	next, err := u.r.Peek(2)
+	if err != nil {
+		return utf8.RuneError, nil
+	}
+	r = utf16.DecodeRune(r, u.unit(next))
+	if r != utf8.RuneError {
+		u.r.Discard(2)
+	}
+	return r, nil
+}
+
+//firstInvalidUTF8 returns the position of the first byte of s which is not valid UTF-8, or -1 if there is none.
+func firstInvalidUTF8(s string) int {
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				return i
+			}
+		}
+	}
+	return -1
+}
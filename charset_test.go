@@ -0,0 +1,86 @@
+package go_contentline
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+//encodeUTF16 encodes s as UTF-16 in the given byte order.
+func encodeUTF16(s string, bigEndian bool) string {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			out = append(out, byte(u>>8), byte(u))
+		} else {
+			out = append(out, byte(u), byte(u>>8))
+		}
+	}
+	return string(out)
+}
+
+func TestParser_Charset(t *testing.T) {
+	card := "BEGIN:VCARD\r\nFN:Jörg 😀\r\nEND:VCARD\r\n"
+	tests := []struct {
+		describe string
+		in       string
+		opts     ParserOptions
+		want     string
+	}{
+		{"UTF-8", card, ParserOptions{}, "Jörg 😀"},
+		{"UTF-8 BOM", "\xEF\xBB\xBF" + card, ParserOptions{}, "Jörg 😀"},
+		{"UTF-16LE BOM", "\xFF\xFE" + encodeUTF16(card, false), ParserOptions{}, "Jörg 😀"},
+		{"UTF-16BE BOM", "\xFE\xFF" + encodeUTF16(card, true), ParserOptions{}, "Jörg 😀"},
+		{"UTF-16LE", encodeUTF16(card, false), ParserOptions{}, "Jörg 😀"},
+		{"UTF-16BE", encodeUTF16(card, true), ParserOptions{}, "Jörg 😀"},
+		{"declared UTF-16LE", encodeUTF16(card, false), ParserOptions{Charset: "utf-16le"}, "Jörg 😀"},
+		{"Windows-1252", "BEGIN:VCARD\r\nFN:J\xF6rg \x80\r\nEND:VCARD\r\n", ParserOptions{Charset: "windows-1252"}, "Jörg €"},
+		{"transcoded legacy value", "BEGIN:VCARD\r\nFN;CHARSET=ISO-8859-1:J\xF6rg\r\nEND:VCARD\r\n",
+			ParserOptions{Charset: "latin1", Legacy: true}, "Jörg"},
+		{"quoted-printable legacy value", "BEGIN:VCARD\r\nFN;CHARSET=ISO-8859-1;ENCODING=QUOTED-PRINTABLE:J=F6rg\r\nEND:VCARD\r\n",
+			ParserOptions{Charset: "latin1", Legacy: true}, "Jörg"},
+		{"invalid UTF-8", "BEGIN:VCARD\r\nFN:J\xF6rg\r\nEND:VCARD\r\n", ParserOptions{}, "J\xF6rg"},
+	}
+	for _, tt := range tests {
+		c, err := InitParserWithOptions(strings.NewReader(tt.in), tt.opts).ParseNextObject()
+		if err != nil {
+			t.Errorf("%s: %v", tt.describe, err)
+			continue
+		}
+		fn := c.GetProperty("FN")
+		if fn.Value != tt.want || len(fn.Parameters) != 0 {
+			t.Errorf("%s: Wanted %q, got %q %v", tt.describe, tt.want, fn.Value, fn.Parameters)
+		}
+	}
+
+	if _, err := InitParserWithOptions(strings.NewReader(card), ParserOptions{Charset: "EBCDIC"}).ParseNextObject(); err == nil {
+		t.Error("expected an error for an unsupported charset")
+	}
+	if _, err := InitParser(strings.NewReader("\xFF\xFE" + encodeUTF16(card, false) + "\x00")).ParseNextObject(); err != nil {
+		t.Errorf("the first object should be complete before the odd byte: %v", err)
+	}
+}
+
+func TestParser_StrictUTF8(t *testing.T) {
+	opts := ParserOptions{StrictUTF8: true}
+	for _, in := range []string{
+		"BEGIN:VCARD\r\nFN:Jörg\r\nEND:VCARD\r\n",
+		"BEGIN:VCARD\r\nFN:J\xF6rg\r\nEND:VCARD\r\n",
+	} {
+		if _, err := InitParserWithOptions(strings.NewReader(in), ParserOptions{Charset: "latin1", StrictUTF8: true}).ParseNextObject(); err != nil {
+			t.Errorf("%q: unexpected error %v", in, err)
+		}
+	}
+
+	in := "BEGIN:VCARD\r\nN:Doe\r\nFN;X-P=\"a\xF6\":Jane\r\nEND:VCARD\r\n"
+	_, err := InitParserWithOptions(strings.NewReader(in), opts).ParseNextObject()
+	pe, ok := err.(*ParseError)
+	if !ok || pe.Line != 3 || !strings.Contains(err.Error(), "X-P of FN at byte 1") {
+		t.Errorf("unexpected error %v", err)
+	}
+	in = "BEGIN:VCARD\r\nFN:Ja\r\n ne \xF6\r\nEND:VCARD\r\n"
+	_, err = InitParserWithOptions(strings.NewReader(in), opts).ParseNextObject()
+	if pe, ok = err.(*ParseError); !ok || pe.Line != 2 || !strings.Contains(err.Error(), "value of FN at byte 5") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
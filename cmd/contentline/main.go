@@ -0,0 +1,455 @@
+//Command contentline formats, validates, converts, splits and joins iCalendar and vCard files.
+//
+// Usage:
+//
+//	contentline fmt [-w] [-legacy] [files...]
+//	contentline validate [-legacy] [files...]
+//	contentline convert -to ics|vcf|jcal|jcard|xcal|xcard [-version 2.1|3.0|4.0] [-legacy] [files...]
+//	contentline split -dir directory [-legacy] [files...]
+//	contentline join [-legacy] [files...]
+//	contentline query [-lines] [-legacy] expression [files...]
+//
+// All commands read the given files, or the standard input if there are none or a file is named "-". Besides
+// iCalendar and vCard, the input may be jCal/jCard or xCal/xCard, which is detected by its first character.
+// The exit code is 0 on success, 1 if an input is invalid or can't be read or written and 2 for invalid arguments.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/jcal"
+	"github.com/mqus/go-contentline/schema"
+	"github.com/mqus/go-contentline/split"
+	"github.com/mqus/go-contentline/vcard"
+	"github.com/mqus/go-contentline/xcal"
+)
+
+//The exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const usage = `usage: contentline <command> [flags] [files...]
+
+commands:
+  fmt       re-fold and normalize the content lines
+  validate  check the files against RFC5545 and RFC6350
+  convert   convert between ics/vcf, jCal/jCard and xCal/xCard
+  split     split calendars into one file per UID
+  join      join calendars into a single one
+  query     print the components or properties selected by a path expression
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+//command holds the flags and streams shared by all commands.
+type command struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	flags          *flag.FlagSet
+	legacy         bool
+	//files are the names of the input files, "-" for the standard input
+	files []string
+}
+
+//run executes the command given by args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	cmd := &command{stdin: stdin, stdout: stdout, stderr: stderr, flags: flag.NewFlagSet(args[0], flag.ContinueOnError)}
+	cmd.flags.SetOutput(stderr)
+	cmd.flags.BoolVar(&cmd.legacy, "legacy", false, "accept the syntax of vCard 2.1 and 3.0")
+
+	var exec func() int
+	switch args[0] {
+	case "fmt":
+		write := cmd.flags.Bool("w", false, "write the result to the files instead of the standard output")
+		exec = func() int { return cmd.format(*write) }
+	case "validate":
+		exec = cmd.validate
+	case "convert":
+		to := cmd.flags.String("to", "", "the output format: ics, vcf, jcal, jcard, xcal or xcard")
+		version := cmd.flags.String("version", "", "the vCard version of the output (2.1, 3.0 or 4.0)")
+		exec = func() int { return cmd.convert(*to, *version) }
+	case "split":
+		dir := cmd.flags.String("dir", "", "the directory to write the files to")
+		exec = func() int { return cmd.split(*dir) }
+	case "join":
+		exec = cmd.join
+	case "query":
+		lines := cmd.flags.Bool("lines", false, "print the original content lines instead of the values of properties")
+		exec = func() int { return cmd.query(*lines) }
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "contentline: unknown command %q\n%s", args[0], usage)
+		return exitUsage
+	}
+	if err := cmd.flags.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	cmd.files = cmd.flags.Args()
+	return exec()
+}
+
+//input is a file given on the command line, or the standard input.
+type input struct {
+	name string
+	data []byte
+}
+
+//inputs reads all files given as arguments, or the standard input.
+func (cmd *command) inputs() ([]input, error) {
+	names := cmd.files
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	var out []input
+	for _, name := range names {
+		var data []byte
+		var err error
+		if name == "-" {
+			name = "<stdin>"
+			data, err = ioutil.ReadAll(cmd.stdin)
+		} else {
+			data, err = ioutil.ReadFile(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, input{name, data})
+	}
+	return out, nil
+}
+
+//parse returns all objects of the input. JSON and XML input is read as jCal/jCard and xCal/xCard. If positions is set,
+// the positions of iCalendar and vCard input are recorded, see go_contentline.ParserOptions.Positions.
+func (cmd *command) parse(in input, positions bool) ([]*go_contentline.Component, error) {
+	switch trimmed := bytes.TrimSpace(in.data); {
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		return jcal.Unmarshal(in.data)
+	case len(trimmed) > 0 && trimmed[0] == '<':
+		return xcal.Unmarshal(in.data)
+	}
+	p := go_contentline.InitParserWithOptions(bytes.NewReader(in.data), go_contentline.ParserOptions{
+		Legacy:    cmd.legacy,
+		Positions: positions,
+	})
+	var out []*go_contentline.Component
+	for {
+		c, err := p.ParseNextObject()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+}
+
+//readAll reads and parses all inputs. Errors are reported to stderr with the name of the input.
+func (cmd *command) readAll() ([]*go_contentline.Component, bool) {
+	ins, err := cmd.inputs()
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+		return nil, false
+	}
+	var out []*go_contentline.Component
+	ok := true
+	for _, in := range ins {
+		objs, err := cmd.parse(in, false)
+		if err != nil {
+			cmd.report(in.name, err)
+			ok = false
+			continue
+		}
+		out = append(out, objs...)
+	}
+	return out, ok
+}
+
+//report writes an error to stderr, with the line of a *ParseError.
+func (cmd *command) report(name string, err error) {
+	if pe, ok := err.(*go_contentline.ParseError); ok {
+		fmt.Fprintf(cmd.stderr, "%s:%d: %v\n", name, pe.Line, pe.Err)
+		return
+	}
+	fmt.Fprintf(cmd.stderr, "%s: %v\n", name, err)
+}
+
+//encode writes the objects as iCalendar or vCard. vCards keep the syntax of their version.
+func encode(w io.Writer, objects []*go_contentline.Component) {
+	for _, c := range objects {
+		var opts go_contentline.EncoderOptions
+		if c.Name == "VCARD" {
+			if v := vcard.Version(c); v == vcard.Version21 || v == vcard.Version30 {
+				opts.VCardVersion = v
+			}
+		}
+		c.EncodeWithOptions(w, opts)
+	}
+}
+
+func (cmd *command) format(write bool) int {
+	ins, err := cmd.inputs()
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+		return exitError
+	}
+	code := exitOK
+	for _, in := range ins {
+		objs, err := cmd.parse(in, false)
+		if err != nil {
+			cmd.report(in.name, err)
+			code = exitError
+			continue
+		}
+		if !write || in.name == "<stdin>" {
+			encode(cmd.stdout, objs)
+			continue
+		}
+		var buf bytes.Buffer
+		encode(&buf, objs)
+		if err := replaceFile(in.name, buf.Bytes()); err != nil {
+			fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+//replaceFile replaces the content of the file name with data. The data is written to a temporary file in the same
+// directory first, which is renamed to name, so that name is never left half written. The file mode is kept.
+func replaceFile(name string, data []byte) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(info.Mode())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (cmd *command) validate() int {
+	ins, err := cmd.inputs()
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+		return exitError
+	}
+	code := exitOK
+	for _, in := range ins {
+		objs, err := cmd.parse(in, true)
+		if err != nil {
+			cmd.report(in.name, err)
+			code = exitError
+			continue
+		}
+		for _, c := range objs {
+			for _, p := range schema.Validate(c) {
+				code = exitError
+				//the line of the property or component, which is unknown for jCal/xCal
+				pos := p.Component.Position()
+				if p.Property != nil {
+					pos = p.Property.Position()
+				}
+				if pos.StartLine > 0 {
+					fmt.Fprintf(cmd.stdout, "%s:%d: %s\n", in.name, pos.StartLine, p)
+				} else {
+					fmt.Fprintf(cmd.stdout, "%s: %s\n", in.name, p)
+				}
+			}
+		}
+	}
+	return code
+}
+
+func (cmd *command) convert(to, version string) int {
+	if version != "" && version != vcard.Version21 && version != vcard.Version30 && version != vcard.Version40 {
+		fmt.Fprintf(cmd.stderr, "contentline: unsupported vCard version %q\n", version)
+		return exitUsage
+	}
+	switch to {
+	case "ics", "vcf", "jcal", "jcard", "xcal", "xcard":
+	default:
+		fmt.Fprintf(cmd.stderr, "contentline: -to must be one of ics, vcf, jcal, jcard, xcal or xcard\n")
+		return exitUsage
+	}
+	objs, ok := cmd.readAll()
+	if !ok {
+		return exitError
+	}
+	if version != "" {
+		for i, c := range objs {
+			if c.Name != "VCARD" {
+				continue
+			}
+			converted, issues, err := vcard.Convert(c, version)
+			if err != nil {
+				fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+				return exitError
+			}
+			for _, issue := range issues {
+				fmt.Fprintf(cmd.stderr, "contentline: left out %s\n", issue)
+			}
+			objs[i] = converted
+		}
+	}
+
+	switch to {
+	case "ics", "vcf":
+		encode(cmd.stdout, objs)
+		return exitOK
+	case "jcal", "jcard":
+		var data []byte
+		var err error
+		if len(objs) == 1 {
+			data, err = jcal.Marshal(objs[0])
+		} else {
+			data, err = jcal.MarshalAll(objs)
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(cmd.stdout, "%s\n", data)
+		return exitOK
+	default:
+		data, err := xcal.Marshal(objs...)
+		if err != nil {
+			fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+			return exitError
+		}
+		cmd.stdout.Write(data)
+		return exitOK
+	}
+}
+
+func (cmd *command) split(dir string) int {
+	if dir == "" {
+		fmt.Fprintf(cmd.stderr, "contentline: split needs -dir\n")
+		return exitUsage
+	}
+	objs, ok := cmd.readAll()
+	if !ok {
+		return exitError
+	}
+	var pieces []*go_contentline.Component
+	for _, c := range objs {
+		if strings.EqualFold(c.Name, "VCALENDAR") {
+			pieces = append(pieces, split.Calendar(c)...)
+		} else {
+			pieces = append(pieces, c)
+		}
+	}
+	for i, c := range pieces {
+		ext := ".ics"
+		if c.Name == "VCARD" {
+			ext = ".vcf"
+		}
+		var buf bytes.Buffer
+		encode(&buf, []*go_contentline.Component{c})
+		name := filepath.Join(dir, fmt.Sprintf("%d%s", i+1, ext))
+		if err := ioutil.WriteFile(name, buf.Bytes(), 0666); err != nil {
+			fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+			return exitError
+		}
+	}
+	return exitOK
+}
+
+func (cmd *command) join() int {
+	objs, ok := cmd.readAll()
+	if !ok {
+		return exitError
+	}
+	encode(cmd.stdout, split.Join(objs))
+	return exitOK
+}
+
+//query prints the components selected by the path expression as objects. If it selects no components, it prints
+// the values (or the original content lines) of the selected properties instead. If there is more than one input, the
+// name of the input is printed in front of each value or line.
+func (cmd *command) query(lines bool) int {
+	if len(cmd.files) == 0 {
+		fmt.Fprintf(cmd.stderr, "contentline: query needs an expression\n")
+		return exitUsage
+	}
+	path, err := go_contentline.CompilePath(cmd.files[0])
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+		return exitUsage
+	}
+	cmd.files = cmd.files[1:]
+	ins, err := cmd.inputs()
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "contentline: %v\n", err)
+		return exitError
+	}
+	code := exitOK
+	for _, in := range ins {
+		objs, err := cmd.parse(in, false)
+		if err != nil {
+			cmd.report(in.name, err)
+			code = exitError
+			continue
+		}
+		prefix := ""
+		if len(ins) > 1 {
+			prefix = in.name + ": "
+		}
+		if comps := path.Components(objs...); len(comps) > 0 {
+			encode(cmd.stdout, comps)
+			continue
+		}
+		for _, p := range path.Properties(objs...) {
+			if lines {
+				fmt.Fprintf(cmd.stdout, "%s%s\n", prefix, originalLine(p))
+			} else {
+				fmt.Fprintf(cmd.stdout, "%s%s\n", prefix, p.Value)
+			}
+		}
+	}
+	return code
+}
+
+//originalLine returns the unfolded content line of p as it was read, or as it is encoded if p was not parsed from a
+// content line (e.g. from jCal).
+func originalLine(p *go_contentline.Property) string {
+	if line := p.OriginalLine(); line != "" {
+		return line
+	}
+	var buf bytes.Buffer
+	p.Encode(&buf)
+	return strings.TrimSuffix(strings.Replace(buf.String(), "\r\n ", "", -1), "\r\n")
+}
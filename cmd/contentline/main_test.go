@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Test//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"DTSTAMP:20200101T000000Z\r\n" +
+	"DTSTART:20200115T100000Z\r\n" +
+	"SUMMARY:A very long summary which has to be folded because it is longer tha\r\n" +
+	" n seventy-five characters\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:2\r\n" +
+	"DTSTAMP:20200101T000000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func runWith(stdin string, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestFmt(t *testing.T) {
+	in := strings.Replace(testCalendar, "tha\r\n n", "than", 1)
+	code, out, stderr := runWith(in, "fmt")
+	if code != exitOK || out != testCalendar {
+		t.Errorf("unexpected result %d, %q, %q", code, out, stderr)
+	}
+
+	dir, err := ioutil.TempDir("", "contentline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "test.ics")
+	if err := ioutil.WriteFile(name, []byte(in), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runWith("", "fmt", "-w", name); code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	if data, _ := ioutil.ReadFile(name); string(data) != testCalendar {
+		t.Errorf("unexpected file content %q", data)
+	}
+	if info, err := os.Stat(name); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the file mode to be kept, got %v", info.Mode())
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("expected no temporary files, got %d files", len(files))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if code, out, stderr := runWith(testCalendar, "validate"); code != exitOK {
+		t.Errorf("unexpected exit code %d: %s%s", code, out, stderr)
+	}
+
+	in := strings.Replace(testCalendar, "UID:2\r\n", "", 1)
+	code, out, _ := runWith(in, "validate")
+	if want := "<stdin>:11: VCALENDAR/VEVENT[2]: VEVENT must contain UID\n"; code != exitError || out != want {
+		t.Errorf("Wanted %q, got %d, %q", want, code, out)
+	}
+
+	in = strings.Replace(testCalendar, "DTSTAMP:20200101T000000Z\r\nEND", "DTSTAMP:2020\r\nEND", 1)
+	code, out, _ = runWith(in, "validate")
+	if !strings.HasPrefix(out, "<stdin>:13: VCALENDAR/VEVENT[2]/DTSTAMP: ") {
+		t.Errorf("expected a problem on line 13, got %d, %q", code, out)
+	}
+
+	in = strings.Replace(testCalendar, "UID:2\r\n", "UID\r\n", 1)
+	code, _, stderr := runWith(in, "validate")
+	if code != exitError || !strings.HasPrefix(stderr, "<stdin>:12: ") {
+		t.Errorf("expected an error on line 12, got %d, %q", code, stderr)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	code, jcal, stderr := runWith(testCalendar, "convert", "-to", "jcal")
+	if code != exitOK || !strings.HasPrefix(jcal, `["vcalendar",`) {
+		t.Fatalf("unexpected result %d, %q, %q", code, jcal, stderr)
+	}
+	code, xcal, stderr := runWith(jcal, "convert", "-to", "xcal")
+	if code != exitOK || !strings.Contains(xcal, "<icalendar") {
+		t.Fatalf("unexpected result %d, %q, %q", code, xcal, stderr)
+	}
+	code, ics, stderr := runWith(xcal, "convert", "-to", "ics")
+	if code != exitOK || ics != testCalendar {
+		t.Errorf("unexpected result %d, %q, %q", code, ics, stderr)
+	}
+
+	card := "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Jane\r\nEND:VCARD\r\n"
+	code, out, _ := runWith(card, "convert", "-to", "vcf", "-version", "3.0")
+	if want := "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane\r\nEND:VCARD\r\n"; code != exitOK || out != want {
+		t.Errorf("Wanted %q, got %d, %q", want, code, out)
+	}
+
+	if code, _, _ := runWith(card, "convert", "-to", "pdf"); code != exitUsage {
+		t.Errorf("expected exit code %d, got %d", exitUsage, code)
+	}
+}
+
+func TestSplitJoin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "contentline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if code, _, stderr := runWith(testCalendar, "split", "-dir", dir); code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.ics"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+	code, out, stderr := runWith("", append([]string{"join"}, files...)...)
+	if code != exitOK || out != testCalendar {
+		t.Errorf("unexpected result %d, %q, %q", code, out, stderr)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"unknown"}, {"fmt", "-unknown"}, {"split"}} {
+		if code, _, _ := runWith("", args...); code != exitUsage {
+			t.Errorf("%v: expected exit code %d, got %d", args, exitUsage, code)
+		}
+	}
+	if code, _, _ := runWith("", "validate", "does-not-exist.ics"); code != exitError {
+		t.Errorf("expected exit code %d, got %d", exitError, code)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"VEVENT/UID"}, "1\n2\n"},
+		{[]string{"-lines", "VEVENT[UID=1]/DTSTART"}, "DTSTART:20200115T100000Z\n"},
+		{[]string{"VEVENT[SUMMARY~=LONG]/UID"}, "1\n"},
+		{[]string{"VEVENT[!DTSTART]"}, "BEGIN:VEVENT\r\nUID:2\r\nDTSTAMP:20200101T000000Z\r\nEND:VEVENT\r\n"},
+		{[]string{"VTODO/UID"}, ""},
+		{[]string{"UID", "-", "-"}, "<stdin>: 1\n<stdin>: 2\n"},
+	}
+	for _, tt := range tests {
+		code, out, stderr := runWith(testCalendar, append([]string{"query"}, tt.args...)...)
+		if code != exitOK || out != tt.want {
+			t.Errorf("%v: Wanted %q, got %d, %q, %q", tt.args, tt.want, code, out, stderr)
+		}
+	}
+	for _, args := range [][]string{{"query"}, {"query", "VEVENT["}} {
+		if code, _, _ := runWith(testCalendar, args...); code != exitUsage {
+			t.Errorf("%v: expected exit code %d, got %d", args, exitUsage, code)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package go_contentline
 
-import "github.com/pkg/errors"
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
 
 //Component is the outermost structured part and can include multiple other Components and has Properties.
 // There are constraints on most Component types concerning which Properties to include and how often. These will not
@@ -16,6 +21,12 @@ type Component struct {
 
 	//Comps contains all included Components. For vcf-files, this field should be empty (nil), which will not be checked.
 	Comps []*Component
+
+	//field for remembering the BEGIN line before parsing, see Component.OriginalLine()
+	olds string
+
+	//pos is where the component was found in the input, see Component.Position()
+	pos *Position
 }
 
 //Property is the way to include Values into Components. Properties can also have Parameters.
@@ -37,6 +48,14 @@ type Property struct {
 
 	//field for remembering the original form before parsing, see Property.OriginalLine()
 	olds string
+
+	//Group is the group of this property, which is only used by vCards (RFC6350, Section 3.3) to mark properties
+	// belonging together, e.g. item1 for "item1.EMAIL:...". It is empty for properties without group.
+	// The group is case-insensitive and will be converted to uppercase when encoding/parsing.
+	Group string
+
+	//pos is where the property was found in the input, see Property.Position()
+	pos *Position
 }
 
 //NewPropertyUnchecked creates a new Property. The property name is checked for validity, see above.
@@ -51,7 +70,7 @@ func NewProperty(name, value string, p Parameters) (out *Property, err error) {
 
 //NewPropertyUnchecked creates a new Property, where the property name is not checked for validity
 func NewPropertyUnchecked(name, value string, p Parameters) *Property {
-	return &Property{name, value, p, ""}
+	return &Property{Name: name, Value: value, Parameters: p}
 }
 
 //Parameters is a type to represent property parameters as described
@@ -63,6 +82,30 @@ func NewPropertyUnchecked(name, value string, p Parameters) *Property {
 // more constraints (e.g. only a defined set of values for VALUE)
 type Parameters map[string][]string
 
+//names returns the sorted names of the parameters.
+func (ps Parameters) names() []string {
+	out := make([]string, 0, len(ps))
+	for k := range ps {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+//Get returns the first value of the parameter with the given name or an empty string if there is none.
+// Parsed parameter names are always upper case, other names are compared case-insensitively.
+func (ps Parameters) Get(key string) string {
+	if vals := ps[key]; len(vals) > 0 {
+		return vals[0]
+	}
+	for k, vals := range ps {
+		if strings.EqualFold(k, key) && len(vals) > 0 {
+			return vals[0]
+		}
+	}
+	return ""
+}
+
 //OriginalLine returns the unfolded line from the input, before it was parsed.
 // That can be useful for error messages in further conversion into calendar/contact objects.
 // This method will return an empty string if this Property was not parsed, but created
@@ -70,6 +113,12 @@ func (p *Property) OriginalLine() string {
 	return p.olds
 }
 
+//OriginalLine returns the unfolded BEGIN line of the Component from the input, before it was parsed.
+// This method will return an empty string if this Component was not parsed, but created
+func (c *Component) OriginalLine() string {
+	return c.olds
+}
+
 //AddComponent adds one or more Subcomponents
 func (c *Component) AddComponent(subcomps ...*Component) {
 	c.Comps = append(c.Comps, subcomps...)
@@ -85,6 +134,14 @@ func (p *Property) AddParameter(key string, val ...string) {
 	p.Parameters[key] = append(p.Parameters[key], val...)
 }
 
+//SetParameter replaces all values of a Parameter, the name is converted to upper case.
+func (p *Property) SetParameter(key string, val ...string) {
+	if p.Parameters == nil {
+		p.Parameters = make(Parameters)
+	}
+	p.Parameters[strings.ToUpper(key)] = val
+}
+
 //find all subcomponents which have the specified name
 func (c *Component) FindSubComponents(name string) []*Component {
 	var out []*Component = nil
@@ -106,3 +163,44 @@ func (c *Component) FindProperties(name string) []*Property {
 	}
 	return out
 }
+
+//GetProperty returns the first property which has the specified name or nil if there is none.
+func (c *Component) GetProperty(name string) *Property {
+	for _, val := range c.Properties {
+		if val.Name == name {
+			return val
+		}
+	}
+	return nil
+}
+
+//Clone returns a deep copy of the Component, including all Properties and Subcomponents. The copies keep the original
+// lines and positions of the parsed input.
+func (c *Component) Clone() *Component {
+	out := &Component{Name: c.Name, olds: c.olds, pos: c.pos}
+	if c.Properties != nil {
+		out.Properties = make([]*Property, len(c.Properties))
+		for i, p := range c.Properties {
+			out.Properties[i] = p.Clone()
+		}
+	}
+	if c.Comps != nil {
+		out.Comps = make([]*Component, len(c.Comps))
+		for i, sub := range c.Comps {
+			out.Comps[i] = sub.Clone()
+		}
+	}
+	return out
+}
+
+//Clone returns a deep copy of the Property, including its Parameters, the original line and its position.
+func (p *Property) Clone() *Property {
+	out := *p
+	if p.Parameters != nil {
+		out.Parameters = make(Parameters, len(p.Parameters))
+		for k, vals := range p.Parameters {
+			out.Parameters[k] = append([]string(nil), vals...)
+		}
+	}
+	return &out
+}
@@ -0,0 +1,41 @@
+package go_contentline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComponent_Clone(t *testing.T) {
+	c := parseString(t, testCalendar)
+	clone := c.Clone()
+	if !reflect.DeepEqual(c, clone) {
+		t.Fatalf("Differences found, Wanted:\n%v\nGot:\n%v\n", c, clone)
+	}
+
+	//changes to the clone must not change the original
+	clone.Comps[0].Properties[2].AddParameter("PARTSTAT", "TENTATIVE")
+	clone.Comps[0].Properties[0].Value = "changed"
+	clone.Comps[1].Comps = nil
+	if vals := c.Comps[0].Properties[2].Parameters["PARTSTAT"]; len(vals) != 1 {
+		t.Errorf("parameters of the original were changed: %v", vals)
+	}
+	if c.Comps[0].Properties[0].Value != "first" || len(c.Comps[1].Comps) != 2 {
+		t.Error("the original was changed")
+	}
+}
+
+func TestParameters_Get(t *testing.T) {
+	ps := Parameters{"TZID": {"Europe/Berlin", "x"}, "value": {"DATE"}, "EMPTY": {}}
+	checks := map[string]string{
+		"TZID":    "Europe/Berlin",
+		"tzid":    "Europe/Berlin",
+		"VALUE":   "DATE",
+		"EMPTY":   "",
+		"MISSING": "",
+	}
+	for key, want := range checks {
+		if got := ps.Get(key); got != want {
+			t.Errorf("%s: Wanted %q, Got %q", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package go_contentline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dateLayout        = "20060102"
+	dateTimeLayout    = "20060102T150405"
+	dateTimeUTCLayout = "20060102T150405Z"
+)
+
+//TZResolver resolves the value of a TZID parameter to a time.Location. It is called with an empty string for
+// floating values, which have neither a TZID parameter nor the UTC designator 'Z'.
+type TZResolver func(tzid string) (*time.Location, error)
+
+//DefaultTZResolver resolves IANA time zone names (e.g. Europe/Berlin) using time.LoadLocation.
+// Floating values are interpreted as UTC.
+func DefaultTZResolver(tzid string) (*time.Location, error) {
+	return time.LoadLocation(tzid)
+}
+
+//ParseDate parses a value of the type DATE (RFC5545, Section 3.3.4), e.g. "19970714", as midnight in loc.
+func ParseDate(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(dateLayout, s, loc)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid DATE value %q", s)
+	}
+	return t, nil
+}
+
+//ParseDateTime parses a value of the type DATE-TIME (RFC5545, Section 3.3.5), e.g. "19970714T133000".
+// If the value ends with 'Z', it is returned in UTC, otherwise it is interpreted as wall clock time in loc.
+func ParseDateTime(s string, loc *time.Location) (time.Time, error) {
+	var t time.Time
+	var err error
+	if strings.HasSuffix(s, "Z") {
+		t, err = time.Parse(dateTimeUTCLayout, s)
+	} else {
+		t, err = time.ParseInLocation(dateTimeLayout, s, loc)
+	}
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid DATE-TIME value %q", s)
+	}
+	return t, nil
+}
+
+//FormatDate formats t as a value of the type DATE.
+func FormatDate(t time.Time) string {
+	return t.Format(dateLayout)
+}
+
+//FormatDateTime formats t as a value of the type DATE-TIME. Times in UTC are written with the UTC designator 'Z',
+// all others as wall clock time, which means that the TZID parameter has to be set separately.
+func FormatDateTime(t time.Time) string {
+	if t.Location() == time.UTC {
+		return t.Format(dateTimeUTCLayout)
+	}
+	return t.Format(dateTimeLayout)
+}
+
+//DateTime interprets the Value of the Property as DATE or DATE-TIME (e.g. for DTSTART or DUE) and returns the
+// time and whether it is a DATE. The TZID parameter is resolved using resolve, which defaults to DefaultTZResolver
+// if nil. DATE values are returned as midnight in the location resolve returns for floating values.
+func (p *Property) DateTime(resolve TZResolver) (t time.Time, isDate bool, err error) {
+	ts, isDate, err := p.DateTimes(resolve)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if len(ts) != 1 {
+		return time.Time{}, false, errors.Errorf("%s: expected exactly one value, got %d", p.Name, len(ts))
+	}
+	return ts[0], isDate, nil
+}
+
+//DateTimes works like DateTime but allows a comma-separated list of values, as used by RDATE and EXDATE.
+// For values of the type PERIOD, the start of each period is returned.
+func (p *Property) DateTimes(resolve TZResolver) (ts []time.Time, isDate bool, err error) {
+	if resolve == nil {
+		resolve = DefaultTZResolver
+	}
+	loc, err := resolve(p.Parameters.Get("TZID"))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "%s: could not resolve TZID", p.Name)
+	}
+	valType := strings.ToUpper(p.Parameters.Get("VALUE"))
+	for _, v := range strings.Split(p.Value, ",") {
+		v = strings.TrimSpace(v)
+		if valType == "PERIOD" {
+			v = strings.SplitN(v, "/", 2)[0]
+		}
+		var t time.Time
+		if valType == "DATE" || (valType == "" && len(v) == len(dateLayout)) {
+			isDate = true
+			t, err = ParseDate(v, loc)
+		} else {
+			t, err = ParseDateTime(v, loc)
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(err, p.Name)
+		}
+		ts = append(ts, t)
+	}
+	return ts, isDate, nil
+}
+
+//SetDateTime sets the Value of the Property to t and adjusts the parameters VALUE and TZID accordingly.
+// If isDate is set, the value is written as DATE. Otherwise times in UTC get the UTC designator, times in
+// time.Local are written as floating time and times in all other locations get a TZID parameter with the name
+// of the location.
+func (p *Property) SetDateTime(t time.Time, isDate bool) {
+	if p.Parameters == nil {
+		p.Parameters = make(Parameters)
+	}
+	delete(p.Parameters, "TZID")
+	delete(p.Parameters, "VALUE")
+	if isDate {
+		p.Value = FormatDate(t)
+		p.Parameters["VALUE"] = []string{"DATE"}
+		return
+	}
+	if loc := t.Location(); loc != time.UTC && loc != time.Local {
+		p.Parameters["TZID"] = []string{loc.String()}
+	}
+	p.Value = FormatDateTime(t)
+}
+
+//ParseDuration parses a value of the type DURATION (RFC5545, Section 3.3.6), e.g. "P15DT5H0M20S" or "-PT15M".
+// Days and weeks are treated as exact multiples of 24 hours.
+func ParseDuration(s string) (time.Duration, error) {
+	in := s
+	neg := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, errors.Errorf("invalid DURATION value %q", in)
+	}
+	s = s[1:]
+	var d time.Duration
+	inTime := false
+	//units which are allowed next, in this order
+	units := "WDTHMS"
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime || !strings.ContainsRune(units, 'T') || len(s) == 1 {
+				return 0, errors.Errorf("invalid DURATION value %q", in)
+			}
+			inTime = true
+			units = "HMS"
+			s = s[1:]
+			continue
+		}
+		n := 0
+		i := 0
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			n = n*10 + int(s[i]-'0')
+		}
+		if i == 0 || i == len(s) {
+			return 0, errors.Errorf("invalid DURATION value %q", in)
+		}
+		unit := s[i]
+		pos := strings.IndexByte(units, unit)
+		if pos == -1 || (inTime != strings.ContainsRune("HMS", rune(unit))) {
+			return 0, errors.Errorf("invalid DURATION value %q", in)
+		}
+		units = units[pos+1:]
+		switch unit {
+		case 'W':
+			d += time.Duration(n) * 7 * 24 * time.Hour
+			units = ""
+		case 'D':
+			d += time.Duration(n) * 24 * time.Hour
+		case 'H':
+			d += time.Duration(n) * time.Hour
+		case 'M':
+			d += time.Duration(n) * time.Minute
+		case 'S':
+			d += time.Duration(n) * time.Second
+		}
+		s = s[i+1:]
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+//FormatDuration formats d as a value of the type DURATION, using days for multiples of 24 hours.
+// Fractions of a second are discarded.
+func FormatDuration(d time.Duration) string {
+	out := "P"
+	if d < 0 {
+		out = "-P"
+		d = -d
+	}
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		if days%7 == 0 && d < time.Second {
+			return out + strconv.Itoa(int(days/7)) + "W"
+		}
+		out += strconv.Itoa(int(days)) + "D"
+	}
+	if d < time.Second {
+		if days == 0 {
+			return out + "T0S"
+		}
+		return out
+	}
+	out += "T"
+	if h := d / time.Hour; h > 0 {
+		out += strconv.Itoa(int(h)) + "H"
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		out += strconv.Itoa(int(m)) + "M"
+		d -= m * time.Minute
+	}
+	if s := d / time.Second; s > 0 {
+		out += strconv.Itoa(int(s)) + "S"
+	}
+	return out
+}
+
+//ParseUTCOffset parses a value of the type UTC-OFFSET (RFC5545, Section 3.3.14), e.g. "-0500" or "+013045",
+// and returns the offset in seconds east of UTC.
+func ParseUTCOffset(s string) (int, error) {
+	if (len(s) != 5 && len(s) != 7) || (s[0] != '+' && s[0] != '-') {
+		return 0, errors.Errorf("invalid UTC-OFFSET value %q", s)
+	}
+	off := 0
+	for i, max := range []int{23, 59, 59} {
+		if 1+2*i >= len(s) {
+			break
+		}
+		n, err := strconv.Atoi(s[1+2*i : 3+2*i])
+		if err != nil || n < 0 || n > max {
+			return 0, errors.Errorf("invalid UTC-OFFSET value %q", s)
+		}
+		off = off*60 + n
+	}
+	if len(s) == 5 {
+		off *= 60
+	}
+	if s[0] == '-' {
+		off = -off
+	}
+	return off, nil
+}
+
+//FormatUTCOffset formats an offset in seconds east of UTC as a value of the type UTC-OFFSET.
+func FormatUTCOffset(off int) string {
+	sign := "+"
+	if off < 0 {
+		sign = "-"
+		off = -off
+	}
+	out := fmt.Sprintf("%s%02d%02d", sign, off/3600, off/60%60)
+	if off%60 != 0 {
+		out += fmt.Sprintf("%02d", off%60)
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package go_contentline
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProperty_DateTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	checks := []struct {
+		prop   *Property
+		want   time.Time
+		isDate bool
+	}{
+		{NewPropertyUnchecked("DTSTART", "19970714T133000Z", nil), time.Date(1997, 7, 14, 13, 30, 0, 0, time.UTC), false},
+		{NewPropertyUnchecked("DTSTART", "19970714T133000", nil), time.Date(1997, 7, 14, 13, 30, 0, 0, time.UTC), false},
+		{NewPropertyUnchecked("DTSTART", "19970714T133000", Parameters{"TZID": {"America/New_York"}}), time.Date(1997, 7, 14, 13, 30, 0, 0, ny), false},
+		{NewPropertyUnchecked("DTSTART", "19970714", nil), time.Date(1997, 7, 14, 0, 0, 0, 0, time.UTC), true},
+		{NewPropertyUnchecked("DTSTART", "19970714", Parameters{"VALUE": {"DATE"}}), time.Date(1997, 7, 14, 0, 0, 0, 0, time.UTC), true},
+		{NewPropertyUnchecked("RDATE", "19970714T133000Z/PT1H", Parameters{"VALUE": {"PERIOD"}}), time.Date(1997, 7, 14, 13, 30, 0, 0, time.UTC), false},
+	}
+	for _, c := range checks {
+		got, isDate, err := c.prop.DateTime(nil)
+		if err != nil {
+			t.Errorf("%s: %v", c.prop.Value, err)
+			continue
+		}
+		if !got.Equal(c.want) || got.Location().String() != c.want.Location().String() || isDate != c.isDate {
+			t.Errorf("%s: Wanted %v (%v), Got %v (%v)", c.prop.Value, c.want, c.isDate, got, isDate)
+		}
+
+		//check that the value survives a round trip
+		p := NewPropertyUnchecked(c.prop.Name, "", nil)
+		p.SetDateTime(got, isDate)
+		if again, _, err := p.DateTime(nil); err != nil || !again.Equal(got) {
+			t.Errorf("%s: round trip failed, got %s (%v)", c.prop.Value, p.Value, err)
+		}
+	}
+
+	for _, p := range []*Property{
+		NewPropertyUnchecked("DTSTART", "1997-07-14", nil),
+		NewPropertyUnchecked("DTSTART", "19970714T1330", nil),
+		NewPropertyUnchecked("DTSTART", "19970714", Parameters{"VALUE": {"DATE-TIME"}}),
+		NewPropertyUnchecked("DTSTART", "19970714T133000", Parameters{"TZID": {"Nowhere/Atlantis"}}),
+		NewPropertyUnchecked("DTSTART", "19970714,19970715", nil),
+	} {
+		if _, _, err := p.DateTime(nil); err == nil {
+			t.Errorf("%s: expected an error", p.Value)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	checks := map[string]time.Duration{
+		"P15DT5H0M20S": 15*24*time.Hour + 5*time.Hour + 20*time.Second,
+		"P7W":          7 * 7 * 24 * time.Hour,
+		"-PT15M":       -15 * time.Minute,
+		"+PT1H30M":     90 * time.Minute,
+		"P1D":          24 * time.Hour,
+		"PT0S":         0,
+	}
+	for in, want := range checks {
+		got, err := ParseDuration(in)
+		if err != nil || got != want {
+			t.Errorf("%s: Wanted %v, Got %v (%v)", in, want, got, err)
+		}
+		if again, _ := ParseDuration(FormatDuration(got)); again != got {
+			t.Errorf("%s: round trip failed with %s", in, FormatDuration(got))
+		}
+	}
+	for _, in := range []string{"", "P", "PT", "1D", "P1", "P1H", "PT1D", "P1DT", "P1W2D", "PT1S2M", "P1D1D"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestParseUTCOffset(t *testing.T) {
+	checks := map[string]int{
+		"+0000":   0,
+		"-0500":   -5 * 3600,
+		"+0530":   5*3600 + 30*60,
+		"+013045": 3600 + 30*60 + 45,
+	}
+	for in, want := range checks {
+		got, err := ParseUTCOffset(in)
+		if err != nil || got != want {
+			t.Errorf("%s: Wanted %d, Got %d (%v)", in, want, got, err)
+		}
+		if out := FormatUTCOffset(got); out != in {
+			t.Errorf("%d: Wanted %s, Got %s", got, in, out)
+		}
+	}
+	for _, in := range []string{"", "0500", "+5", "+2500", "-0560", "+05:00", "+05000"} {
+		if _, err := ParseUTCOffset(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
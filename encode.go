@@ -12,16 +12,45 @@ import (
 // The maximal Length of a resulting line, any more characters will be folded as described below.
 const foldingLength = 75
 
+//EncoderOptions change the output of EncodeWithOptions.
+type EncoderOptions struct {
+	//VCardVersion selects the syntax of an older vCard version: VCard21 or VCard30 (RFC2426). In vCard 2.1, TYPE
+	// values are written without parameter name, values with line breaks or non-ASCII characters are encoded as
+	// quoted-printable and base64 values are written on indented lines. In vCard 3.0, parameter values are not
+	// escaped as described in RFC6868 and ENCODING=BASE64 is written as ENCODING=b.
+	// Only the syntax is changed, properties are neither converted nor removed.
+	// The default (an empty string) is the syntax of RFC5545 and RFC6350.
+	VCardVersion string
+
+	//Repair repairs values and parameters which can't be encoded as they are, instead of writing invalid content
+	// lines: invalid UTF-8 is removed, line breaks in TEXT values are escaped as '\n' and all other control characters
+	// besides HTAB (including line breaks in other values) are removed. All values and parameters are normalized to
+	// the Unicode normalization form NFC. The encoded properties themselves are not changed.
+	Repair bool
+
+	//Normalize, if not nil, is applied to all values and parameters after the NFC normalization of Repair, e.g. to
+	// normalize to NFKC instead. It is only used if Repair is set.
+	Normalize func(string) string
+
+	//Repaired, if not nil, is called for each change made by Repair, e.g. to log changes of exported data.
+	Repaired func(Repair)
+}
+
 //Encode encodes the component as described in RFC5545, Section 3.4 and 3.6ff or also RFC6350, Section 6.1.1/6.1.2,
 // including encoding all Properties and writes it to the Writer interface. This writer must be closed by the calling function
 // and is left open for more objects.
 func (c *Component) Encode(w io.Writer) {
+	c.EncodeWithOptions(w, EncoderOptions{})
+}
+
+//EncodeWithOptions encodes the component like Encode, but with the given options.
+func (c *Component) EncodeWithOptions(w io.Writer, opts EncoderOptions) {
 	fmt.Fprintf(w, "%s:%s\r\n", sBEGIN, strings.ToUpper(c.Name))
 	for _, p := range c.Properties {
-		p.Encode(w)
+		p.EncodeWithOptions(w, opts)
 	}
 	for _, c := range c.Comps {
-		c.Encode(w)
+		c.EncodeWithOptions(w, opts)
 	}
 
 	fmt.Fprintf(w, "%s:%s\r\n", sEND, strings.ToUpper(c.Name))
@@ -31,10 +60,22 @@ func (c *Component) Encode(w io.Writer) {
 // folds it (if neccessary) and writes it to the Writer interface. This writer must be closed by the calling function
 // and is left open for more objects.
 func (p *Property) Encode(w io.Writer) {
-	out := strings.ToUpper(p.Name)
-	//log.Printf("NoPARAM: %v\n", p.Parameters)
-	for k, vals := range p.Parameters {
-		//log.Println("INPARAM")
+	p.EncodeWithOptions(w, EncoderOptions{})
+}
+
+//EncodeWithOptions encodes the property like Encode, but with the given options.
+func (p *Property) EncodeWithOptions(w io.Writer, opts EncoderOptions) {
+	if opts.Repair {
+		p = repairProperty(p, opts)
+	}
+	if opts.VCardVersion == VCard21 || opts.VCardVersion == VCard30 {
+		p.encodeLegacy(w, opts.VCardVersion)
+		return
+	}
+	out := p.fullName()
+	//the parameters are sorted, so that the output doesn't depend on the order of the map
+	for _, k := range p.Parameters.names() {
+		vals := p.Parameters[k]
 		out = out + ";" + strings.ToUpper(k) + "="
 		for i, v := range vals {
 			if i > 0 {
@@ -51,6 +92,14 @@ func (p *Property) Encode(w io.Writer) {
 	writeFolded(w, out)
 }
 
+//fullName returns the upper case name of the property, preceded by its group.
+func (p *Property) fullName() string {
+	if p.Group != "" {
+		return strings.ToUpper(p.Group + "." + p.Name)
+	}
+	return strings.ToUpper(p.Name)
+}
+
 //writeFolded folds the ContentLine (s) as described in RFC5545, Section 3.1 or also RFC6350, Section 3.2
 // and then writes it to the given Writer interface.
 func writeFolded(w io.Writer, s string) {
@@ -34,7 +34,7 @@ func TestComponent_Encode(t *testing.T) {
 	c = &Component{
 		Name: "House",
 		Comps: []*Component{
-			{"Flat", nil, nil},
+			{"Flat", nil, nil, "", nil},
 		},
 	}
 	encodeCompare(t, c, "BEGIN:HOUSE\r\nBEGIN:FLAT\r\nEND:FLAT\r\nEND:HOUSE\r\n", false)
@@ -68,7 +68,7 @@ func TestComponent_Encode(t *testing.T) {
 		Comps: []*Component{
 			{"Flat", []*Property{
 				NewPropertyUnchecked("Heating2", "electric2", map[string][]string{"vendor": {"YourGas Co\"", "City:Energy LLC"}, "comment": {"This is a very long comment,more than 2^3 monkeys hat to sit 20 hours to write this \n thing with linebreaks."}}),
-			}, nil},
+			}, nil, "", nil},
 		},
 		Properties: []*Property{
 			NewPropertyUnchecked("Heating", "electric", map[string][]string{"vendor": {"YourGas Co\"", "City:Energy LLC"}, "comment": {"This is a very long comment,more than 2^3 monkeys hat to sit 20 hours to write this \n thing with linebreaks."}}),
@@ -0,0 +1,135 @@
+//Package freebusy computes free/busy information (RFC5545, Section 3.6.4) from calendars.
+package freebusy
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/recurrence"
+	"github.com/mqus/go-contentline/timezone"
+	"github.com/pkg/errors"
+)
+
+//The values of the FBTYPE parameter, ordered by increasing priority. If periods of different types overlap,
+// the type with the higher priority is reported for the overlapping time.
+const (
+	BusyTentative   = "BUSY-TENTATIVE"
+	Busy            = "BUSY"
+	BusyUnavailable = "BUSY-UNAVAILABLE"
+)
+
+var priorities = []string{BusyTentative, Busy, BusyUnavailable}
+
+//Period is a busy time range [Start, End) in UTC.
+type Period struct {
+	Start, End time.Time
+	Type       string
+}
+
+//Periods returns the merged busy periods of all VEVENTs in the VCALENDARs cals which overlap the time range
+// [start, end), clipped to that range and sorted by their start. Recurring events are expanded, TZIDs are resolved
+// with the VTIMEZONEs contained in the same calendar or as IANA time zone names. Transparent events (TRANSP:TRANSPARENT)
+// and cancelled events or instances are ignored, tentative ones (STATUS:TENTATIVE) are reported as BUSY-TENTATIVE.
+func Periods(start, end time.Time, cals ...*go_contentline.Component) ([]Period, error) {
+	//for every priority, the number of events covering a point in time changes by +1 at their start and -1 at their end
+	deltas := make(map[time.Time][]int)
+	for _, cal := range cals {
+		instances, err := recurrence.Instances(cal, start, end, timezone.Resolver(cal, nil))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not expand events")
+		}
+		for _, inst := range instances {
+			if inst.Source.Name != "VEVENT" {
+				continue
+			}
+			if transp := inst.Source.GetProperty("TRANSP"); transp != nil && strings.EqualFold(transp.Value, "TRANSPARENT") {
+				continue
+			}
+			prio := 1
+			if status := inst.Source.GetProperty("STATUS"); status != nil && strings.EqualFold(status.Value, "TENTATIVE") {
+				prio = 0
+			}
+			from, to := inst.Start.UTC(), inst.End.UTC()
+			if from.Before(start) {
+				from = start.UTC()
+			}
+			if to.After(end) {
+				to = end.UTC()
+			}
+			if !from.Before(to) {
+				continue
+			}
+			addDelta(deltas, from, prio, 1)
+			addDelta(deltas, to, prio, -1)
+		}
+	}
+
+	var times []time.Time
+	for t := range deltas {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	var out []Period
+	counts := make([]int, len(priorities))
+	for i, t := range times {
+		for p, d := range deltas[t] {
+			counts[p] += d
+		}
+		typ := ""
+		for p := range counts {
+			if counts[p] > 0 {
+				typ = priorities[p]
+			}
+		}
+		if typ == "" || i+1 == len(times) {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].Type == typ && out[n-1].End.Equal(t) {
+			out[n-1].End = times[i+1]
+			continue
+		}
+		out = append(out, Period{t, times[i+1], typ})
+	}
+	return out, nil
+}
+
+func addDelta(deltas map[time.Time][]int, t time.Time, prio, d int) {
+	if deltas[t] == nil {
+		deltas[t] = make([]int, len(priorities))
+	}
+	deltas[t][prio] += d
+}
+
+//Compute returns a VFREEBUSY component for the time range [start, end), containing one FREEBUSY property per FBTYPE
+// with all periods computed by Periods.
+func Compute(start, end time.Time, cals ...*go_contentline.Component) (*go_contentline.Component, error) {
+	periods, err := Periods(start, end, cals...)
+	if err != nil {
+		return nil, err
+	}
+	out := &go_contentline.Component{Name: "VFREEBUSY"}
+	for _, p := range []struct {
+		name string
+		t    time.Time
+	}{{"DTSTAMP", time.Now()}, {"DTSTART", start}, {"DTEND", end}} {
+		prop := go_contentline.NewPropertyUnchecked(p.name, "", make(go_contentline.Parameters))
+		prop.SetDateTime(p.t.UTC().Truncate(time.Second), false)
+		out.AddProperty(prop)
+	}
+
+	props := make(map[string]*go_contentline.Property)
+	for _, p := range periods {
+		val := go_contentline.FormatDateTime(p.Start) + "/" + go_contentline.FormatDateTime(p.End)
+		if prop := props[p.Type]; prop != nil {
+			prop.Value += "," + val
+			continue
+		}
+		prop := go_contentline.NewPropertyUnchecked("FREEBUSY", val, go_contentline.Parameters{"FBTYPE": {p.Type}})
+		props[p.Type] = prop
+		out.AddProperty(prop)
+	}
+	return out, nil
+}
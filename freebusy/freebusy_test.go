@@ -0,0 +1,107 @@
+package freebusy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mqus/go-contentline"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20180102T100000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20180102T110000\r\n" +
+	"RRULE:FREQ=DAILY;COUNT=3\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20180103T100000\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:2\r\n" +
+	"DTSTART:20180102T093000Z\r\n" +
+	"DURATION:PT2H\r\n" +
+	"STATUS:TENTATIVE\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:3\r\n" +
+	"DTSTART:20180104T000000Z\r\n" +
+	"DURATION:PT2H\r\n" +
+	"TRANSP:TRANSPARENT\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:4\r\n" +
+	"DTSTART:20180104T000000Z\r\n" +
+	"DURATION:PT2H\r\n" +
+	"END:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+const testCalendar2 = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:5\r\n" +
+	"DTSTART:20180104T085000Z\r\n" +
+	"DTEND:20180104T090000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:6\r\n" +
+	"DTSTART:20180104T100000Z\r\n" +
+	"DTEND:20180104T120000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func parseString(t *testing.T, in string) *go_contentline.Component {
+	t.Helper()
+	c, err := go_contentline.InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestPeriods(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skip(err)
+	}
+	cals := []*go_contentline.Component{parseString(t, testCalendar), parseString(t, testCalendar2)}
+	ps, err := Periods(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 4, 11, 0, 0, 0, time.UTC), cals...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range ps {
+		got = append(got, fmt.Sprintf("%s %s-%s", p.Type, p.Start.Format("02T15:04"), p.End.Format("02T15:04")))
+	}
+	want := "BUSY 02T09:00-02T10:00," +
+		"BUSY-TENTATIVE 02T10:00-02T11:30," +
+		"BUSY 04T08:50-04T11:00"
+	if g := strings.Join(got, ","); g != want {
+		t.Errorf("Wanted: %s\nGot:    %s", want, g)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skip(err)
+	}
+	fb, err := Compute(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 5, 0, 0, 0, 0, time.UTC), parseString(t, testCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fb.Name != "VFREEBUSY" || fb.GetProperty("DTSTART").Value != "20180101T000000Z" || fb.GetProperty("DTEND").Value != "20180105T000000Z" || fb.GetProperty("DTSTAMP") == nil {
+		t.Errorf("unexpected component: %v", fb)
+	}
+	props := fb.FindProperties("FREEBUSY")
+	if len(props) != 2 {
+		t.Fatalf("expected one FREEBUSY for each FBTYPE, got %d", len(props))
+	}
+	if props[0].Parameters.Get("FBTYPE") != Busy || props[0].Value != "20180102T090000Z/20180102T100000Z,20180104T090000Z/20180104T100000Z" {
+		t.Errorf("unexpected busy periods: %v", props[0])
+	}
+	if props[1].Parameters.Get("FBTYPE") != BusyTentative || props[1].Value != "20180102T100000Z/20180102T113000Z" {
+		t.Errorf("unexpected tentative periods: %v", props[1])
+	}
+}
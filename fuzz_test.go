@@ -0,0 +1,129 @@
+//go:build go1.18
+// +build go1.18
+
+package go_contentline
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//maxObjects limits the number of objects read from a single input, so that no input can keep a fuzz target busy.
+const maxObjects = 100
+
+//fuzzOptions are the parser options every input is parsed with.
+var fuzzOptions = []ParserOptions{
+	{},
+	{Legacy: true, Positions: true},
+	{SkipPreamble: true},
+	{Binary: func(p *Property, mediaType string, data io.Reader) error {
+		_, err := io.Copy(ioutil.Discard, data)
+		return err
+	}},
+}
+
+//addSeeds adds the files of testdata/quirks and some broken inputs to the seed corpus.
+func addSeeds(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "quirks", "*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	for _, s := range []string{
+		"", "\n", "\r\n", "BEGIN:A\r\n\n", "BEGIN:A\r\nX;P=\"", "BEGIN:A\r\n \r\n", "BEGIN:A\r\nEND:B\r\n",
+		"BEGIN:A\r\nX;=:\r\nEND:A\r\n", "BEGIN:\r\n", "END:A\r\n", "BEGIN:A\r\nX:=\r\n",
+		"BEGIN:A\r\nPHOTO;ENCODING=b:\r\nEND:A\r\n", "BEGIN:A\r\nPHOTO:data:\r\n",
+	} {
+		f.Add([]byte(s))
+	}
+}
+
+//parseAll returns all objects of the input, up to the first error.
+func parseAll(data []byte, opts ParserOptions) []*Component {
+	p := InitParserWithOptions(bytes.NewReader(data), opts)
+	var out []*Component
+	for len(out) < maxObjects {
+		c, err := p.ParseNextObject()
+		if err != nil {
+			break
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func FuzzParseNextObject(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range fuzzOptions {
+			parseAll(data, opts)
+		}
+	})
+}
+
+func FuzzUnescapeParamVal(f *testing.F) {
+	for _, s := range []string{"", "^", "^^", "^n", "^N", "^'", "^^n", "^^^n", "a^^ b", "\"quoted\"\nline"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		UnescapeParamVal(s)
+		//carriage returns are turned into newlines by EscapeParamVal
+		if strings.Contains(s, "\r") {
+			return
+		}
+		if got := UnescapeParamVal(EscapeParamVal(s)); got != s {
+			t.Errorf("Wanted %q, got %q", s, got)
+		}
+	})
+}
+
+//FuzzRoundTrip checks that every object which can be parsed can be encoded and parsed again, with the same result.
+func FuzzRoundTrip(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, c := range parseAll(data, ParserOptions{}) {
+			var buf bytes.Buffer
+			c.Encode(&buf)
+			again, err := InitParser(bytes.NewReader(buf.Bytes())).ParseNextObject()
+			if err != nil {
+				t.Fatalf("could not parse the encoded object %q: %v", buf.String(), err)
+			}
+			if msg := compareComponents(c, again); msg != "" {
+				t.Fatalf("%s after encoding it as %q", msg, buf.String())
+			}
+		}
+	})
+}
+
+//compareComponents describes the first difference between two component trees, ignoring the case of names and the
+// order of parameters. It returns an empty string if there is none.
+func compareComponents(a, b *Component) string {
+	if !strings.EqualFold(a.Name, b.Name) || len(a.Properties) != len(b.Properties) || len(a.Comps) != len(b.Comps) {
+		return fmt.Sprintf("component %v changed to %v", a, b)
+	}
+	for i, p := range a.Properties {
+		q := b.Properties[i]
+		if !strings.EqualFold(p.Name, q.Name) || !strings.EqualFold(p.Group, q.Group) || p.Value != q.Value ||
+			!reflect.DeepEqual(p.Parameters, q.Parameters) {
+			return fmt.Sprintf("property %#v changed to %#v", p, q)
+		}
+	}
+	for i, sub := range a.Comps {
+		if msg := compareComponents(sub, b.Comps[i]); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
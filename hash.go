@@ -0,0 +1,63 @@
+package go_contentline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+//VolatileProperties are properties which usually change with every export of an otherwise unchanged object, they
+// can be passed as HashOptions.Exclude.
+var VolatileProperties = []string{"DTSTAMP", "PRODID", "REV"}
+
+//HashOptions change the content hashed by HashWithOptions and Fingerprint.
+type HashOptions struct {
+	//Exclude lists the names of properties (case-insensitive) which are left out in all components, e.g.
+	// VolatileProperties.
+	Exclude []string
+}
+
+//Hash writes the canonical form (see Canonicalize) of the component into h, so that equivalent components result in
+// the same hash regardless of folding, the case of names and the order of properties and parameters. The component
+// itself is not changed.
+func (c *Component) Hash(h hash.Hash) {
+	c.HashWithOptions(h, HashOptions{})
+}
+
+//HashWithOptions writes the canonical form of the component into h like Hash, but with the given options.
+func (c *Component) HashWithOptions(h hash.Hash, opts HashOptions) {
+	exclude := make(map[string]bool, len(opts.Exclude))
+	for _, name := range opts.Exclude {
+		exclude[strings.ToUpper(name)] = true
+	}
+	clone := c.Clone()
+	removeProperties(clone, exclude)
+	clone.Canonicalize()
+	clone.Encode(h)
+}
+
+//Fingerprint returns the hex-encoded SHA-256 hash of the canonical form of the component (see HashWithOptions),
+// e.g. as a strong ETag for CalDAV or CardDAV. It has to be quoted to be used as an ETag header.
+func (c *Component) Fingerprint(opts HashOptions) string {
+	h := sha256.New()
+	c.HashWithOptions(h, opts)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+//removeProperties removes all properties with the given (upper case) names from c and its subcomponents.
+func removeProperties(c *Component, names map[string]bool) {
+	if len(names) == 0 {
+		return
+	}
+	kept := c.Properties[:0]
+	for _, p := range c.Properties {
+		if !names[strings.ToUpper(p.Name)] {
+			kept = append(kept, p)
+		}
+	}
+	c.Properties = kept
+	for _, sub := range c.Comps {
+		removeProperties(sub, names)
+	}
+}
@@ -0,0 +1,59 @@
+package go_contentline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestComponent_Fingerprint(t *testing.T) {
+	parse := func(s string) *Component {
+		c, err := InitParser(bytes.NewReader([]byte(s))).ParseNextObject()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	a := parse("BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"PRODID:-//a//EN\r\n" +
+		"REV:20200101T100000Z\r\n" +
+		"FN:Jane\r\n" +
+		"EMAIL;TYPE=work;PREF=1:jane@exam\r\n ple.com\r\n" +
+		"END:VCARD\r\n")
+	b := parse("BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"email;pref=1;type=work:jane@example.com\r\n" +
+		"FN:Jane\r\n" +
+		"REV:20210101T100000Z\r\n" +
+		"PRODID:-//b//EN\r\n" +
+		"END:VCARD\r\n")
+	encode := func(c *Component) string {
+		var buf bytes.Buffer
+		c.Encode(&buf)
+		return buf.String()
+	}
+	before := encode(a)
+
+	volatile := HashOptions{Exclude: VolatileProperties}
+	if fa, fb := a.Fingerprint(volatile), b.Fingerprint(volatile); fa != fb {
+		t.Errorf("Wanted equal fingerprints, got %s and %s", fa, fb)
+	}
+	if fa, fb := a.Fingerprint(HashOptions{}), b.Fingerprint(HashOptions{}); fa == fb {
+		t.Errorf("Wanted different fingerprints with REV and PRODID, got %s twice", fa)
+	}
+	b.GetProperty("FN").Value = "John"
+	if fa, fb := a.Fingerprint(volatile), b.Fingerprint(volatile); fa == fb {
+		t.Errorf("Wanted different fingerprints after a change, got %s twice", fa)
+	}
+	if after := encode(a); after != before {
+		t.Errorf("The component was changed from %q to %q", before, after)
+	}
+
+	h := sha256.New()
+	a.HashWithOptions(h, volatile)
+	if got, want := hex.EncodeToString(h.Sum(nil)), a.Fingerprint(volatile); got != want {
+		t.Errorf("Wanted %s, got %s", want, got)
+	}
+}
@@ -36,6 +36,13 @@ func errorf(line string, i *item, msg string) error {
 		msg = i.val
 		pos2 = pos1 + 1
 	}
+	//errors at the end of a line are reported after its last character
+	if int(pos1) > len(line) {
+		pos1 = pos(len(line))
+	}
+	if int(pos2) > len(line) {
+		pos2 = pos(len(line))
+	}
 
 	if pos1 > contentRadius {
 		prefix = "..." + line[pos1-contentRadius:pos1]
@@ -63,12 +70,12 @@ func split(in string, maxlen int) (out []string) {
 	if len(in) <= maxlen {
 		return []string{in}
 	}
-	inr := []rune(in)
 	out = nil
 	prev := 0
 	sum := 0
-	for _, r := range inr {
-		rl := utf8.RuneLen(r)
+	for sum < len(in) {
+		//invalid bytes are decoded with a length of 1, unlike utf8.RuneLen(utf8.RuneError)
+		_, rl := utf8.DecodeRuneInString(in[sum:])
 		if sum+rl-prev > maxlen {
 			//decrease maxlen for the space which will be added in writeFolded
 			if prev == 0 {
@@ -0,0 +1,158 @@
+//Package imip transports iTIP scheduling messages by email as described by iMIP (RFC6047).
+//
+// Scheduling messages are sent as MIME parts with the media type text/calendar, whose method parameter repeats the
+// METHOD of the VCALENDAR. Only the standard library is used to read and write MIME messages.
+package imip
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+//MediaType is the media type of iCalendar objects.
+const MediaType = "text/calendar"
+
+//Calendar is a text/calendar part found in a MIME message.
+type Calendar struct {
+	//Method is the method parameter of the Content-Type, which is empty if it is missing.
+	Method string
+	//Objects contains the parsed content of the part, usually a single VCALENDAR.
+	Objects []*go_contentline.Component
+}
+
+//ContentType returns the value of the Content-Type header for the VCALENDAR msg. It contains the charset and, if msg
+// has a METHOD property, the method parameter.
+func ContentType(msg *go_contentline.Component) string {
+	params := map[string]string{"charset": "utf-8"}
+	if method := msg.GetProperty("METHOD"); method != nil {
+		params["method"] = strings.ToUpper(method.Value)
+	}
+	return mime.FormatMediaType(MediaType, params)
+}
+
+//Header returns the MIME header of a part containing msg: its Content-Type (see ContentType) and the
+// Content-Transfer-Encoding, which is always quoted-printable, as the content lines may contain any UTF-8 text.
+func Header(msg *go_contentline.Component) textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", ContentType(msg))
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return h
+}
+
+//WriteBody encodes msg and writes it quoted-printable encoded to w, as the body of a part with the header returned
+// by Header.
+func WriteBody(w io.Writer, msg *go_contentline.Component) error {
+	qp := quotedprintable.NewWriter(w)
+	msg.Encode(qp)
+	return qp.Close()
+}
+
+//WritePart adds a part containing msg to the multipart message written by mw.
+func WritePart(mw *multipart.Writer, msg *go_contentline.Component) error {
+	w, err := mw.CreatePart(Header(msg))
+	if err != nil {
+		return err
+	}
+	return WriteBody(w, msg)
+}
+
+//ReadMessage reads an email (RFC5322) from r and returns all text/calendar parts it contains, see Extract.
+func ReadMessage(r io.Reader) ([]*Calendar, error) {
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read message")
+	}
+	return Extract(textproto.MIMEHeader(m.Header), m.Body)
+}
+
+//Extract returns all text/calendar parts of the entity with the given header and body, in the order they appear.
+// Multipart entities are searched recursively. The transfer encodings base64 and quoted-printable are decoded and
+// the content of each part is parsed with the charset of the part, see go_contentline.ParserOptions.Charset for the
+// supported charsets. As required by RFC6047, the METHOD of the objects must match the method parameter of the part.
+func Extract(header textproto.MIMEHeader, body io.Reader) ([]*Calendar, error) {
+	ct := header.Get("Content-Type")
+	if ct == "" {
+		ct = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid Content-Type %q", ct)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		var out []*Calendar
+		for {
+			//NextRawPart leaves the transfer encoding to decode(), which also handles base64
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return out, nil
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "could not read multipart body")
+			}
+			cals, err := Extract(part.Header, part)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, cals...)
+		}
+	}
+	if mediaType != MediaType {
+		return nil, nil
+	}
+
+	content, err := decode(header.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		return nil, err
+	}
+	//the line break before a boundary belongs to the boundary, but the parser needs it to end the last line
+	if !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\r', '\n')
+	}
+	cal := &Calendar{Method: strings.ToUpper(params["method"])}
+	p := go_contentline.InitParserWithOptions(bytes.NewReader(content), go_contentline.ParserOptions{
+		Charset: params["charset"],
+	})
+	for {
+		c, err := p.ParseNextObject()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if method := c.GetProperty("METHOD"); cal.Method != "" && method != nil && !strings.EqualFold(method.Value, cal.Method) {
+			return nil, errors.Errorf("METHOD %s does not match the method parameter %s", method.Value, cal.Method)
+		}
+		cal.Objects = append(cal.Objects, c)
+	}
+	return []*Calendar{cal}, nil
+}
+
+//decode reads the body of a part and removes the transfer encoding.
+func decode(encoding string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "7bit", "8bit", "binary":
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		//line breaks are ignored by the decoder
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	default:
+		return nil, errors.Errorf("unsupported Content-Transfer-Encoding %q", encoding)
+	}
+	content, err := ioutil.ReadAll(body)
+	return content, errors.Wrap(err, "could not decode part")
+}
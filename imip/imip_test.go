@@ -0,0 +1,118 @@
+package imip
+
+import (
+	"bytes"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/mqus/go-contentline"
+)
+
+const testRequest = "BEGIN:VCALENDAR\r\n" +
+	"PRODID:-//test//EN\r\n" +
+	"VERSION:2.0\r\n" +
+	"METHOD:REQUEST\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:meeting-1\r\n" +
+	"SUMMARY:Besprechung über Größen\r\n" +
+	"DTSTART:20180102T100000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestRoundTrip(t *testing.T) {
+	msg, err := go_contentline.InitParser(strings.NewReader(testRequest)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := ContentType(msg); ct != "text/calendar; charset=utf-8; method=REQUEST" {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	buf.WriteString("From: boss@example.com\r\n" +
+		"To: a@example.com\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary() + "\r\n\r\n")
+	w, _ := mw.CreatePart(map[string][]string{"Content-Type": {"text/plain"}})
+	w.Write([]byte("You are invited.\r\n"))
+	if err = WritePart(mw, msg); err != nil {
+		t.Fatal(err)
+	}
+	mw.Close()
+
+	cals, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cals) != 1 || cals[0].Method != "REQUEST" || len(cals[0].Objects) != 1 {
+		t.Fatalf("unexpected result: %v", cals)
+	}
+	if got := cals[0].Objects[0].Comps[0].GetProperty("SUMMARY").Value; got != "Besprechung über Größen" {
+		t.Errorf("unexpected SUMMARY: %s", got)
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	//a nested multipart message with a base64 encoded attachment
+	in := "From: boss@example.com\r\n" +
+		"Content-Type: multipart/mixed; boundary=outer\r\n" +
+		"\r\n" +
+		"--outer\r\n" +
+		"Content-Type: multipart/alternative; boundary=inner\r\n" +
+		"\r\n" +
+		"--inner\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Cancelled.\r\n" +
+		"--inner\r\n" +
+		"Content-Type: text/calendar; method=CANCEL\r\n" +
+		"\r\n" +
+		"BEGIN:VCALENDAR\r\nMETHOD:CANCEL\r\nEND:VCALENDAR\r\n" +
+		"--inner--\r\n" +
+		"--outer\r\n" +
+		"Content-Type: text/calendar; charset=\"UTF-8\"; method=CANCEL\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"QkVHSU46VkNBTEVOREFSDQpNRVRIT0Q6Q0FOQ0VMDQpFTkQ6VkNB\r\n" +
+		"TEVOREFSDQo=\r\n" +
+		"--outer--\r\n"
+	cals, err := ReadMessage(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cals) != 2 {
+		t.Fatalf("expected 2 calendars, got %d", len(cals))
+	}
+	for _, cal := range cals {
+		if cal.Method != "CANCEL" || len(cal.Objects) != 1 || cal.Objects[0].GetProperty("METHOD").Value != "CANCEL" {
+			t.Errorf("unexpected calendar: %v", cal)
+		}
+	}
+
+	mismatch := "Content-Type: text/calendar; method=REPLY\r\n\r\nBEGIN:VCALENDAR\r\nMETHOD:CANCEL\r\nEND:VCALENDAR\r\n"
+	if _, err = ReadMessage(strings.NewReader(mismatch)); err == nil {
+		t.Error("expected an error for a mismatching METHOD")
+	}
+	latin1 := "Content-Type: text/calendar; charset=ISO-8859-1\r\n\r\nBEGIN:VCALENDAR\r\nX-NAME:Caf\xe9\r\nEND:VCALENDAR"
+	cals, err = ReadMessage(strings.NewReader(latin1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cals[0].Objects[0].GetProperty("X-NAME").Value; got != "Café" {
+		t.Errorf("Wanted the transcoded value, got %q", got)
+	}
+	var le []byte
+	for _, c := range []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n") {
+		le = append(le, c, 0)
+	}
+	cals, err = ReadMessage(strings.NewReader("Content-Type: text/calendar; charset=UTF-16LE\r\n\r\n" + string(le)))
+	if err != nil || len(cals[0].Objects) != 1 {
+		t.Errorf("could not read UTF-16LE: %v", err)
+	}
+	charset := "Content-Type: text/calendar; charset=ISO-2022-JP\r\n\r\nBEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"
+	if _, err = ReadMessage(strings.NewReader(charset)); err == nil {
+		t.Error("expected an error for an unsupported charset")
+	}
+}
@@ -0,0 +1,347 @@
+//Package valuetype contains the value types of properties and the conversions between the values of content lines
+// and the typed values of jCal/jCard (RFC7265, RFC7095) and xCal/xCard (RFC6321, RFC6351).
+package valuetype
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+)
+
+//The names of the value types, as used by the VALUE parameter (in lower case) and by jCal and xCal.
+const (
+	Text          = "text"
+	Unknown       = "unknown"
+	Date          = "date"
+	DateTime      = "date-time"
+	Time          = "time"
+	DateAndOrTime = "date-and-or-time"
+	Timestamp     = "timestamp"
+	Duration      = "duration"
+	Period        = "period"
+	Recur         = "recur"
+	Integer       = "integer"
+	Float         = "float"
+	Boolean       = "boolean"
+	UTCOffset     = "utc-offset"
+	URI           = "uri"
+	CalAddress    = "cal-address"
+	Binary        = "binary"
+	LanguageTag   = "language-tag"
+)
+
+//calendarTypes contains the default value types of the properties of RFC5545, Section 3.8.
+var calendarTypes = map[string]string{
+	"COMPLETED": DateTime, "DTEND": DateTime, "DUE": DateTime, "DTSTART": DateTime, "RECURRENCE-ID": DateTime,
+	"EXDATE": DateTime, "RDATE": DateTime, "CREATED": DateTime, "DTSTAMP": DateTime, "LAST-MODIFIED": DateTime,
+	"DURATION": Duration, "TRIGGER": Duration, "FREEBUSY": Period, "GEO": Float,
+	"PERCENT-COMPLETE": Integer, "PRIORITY": Integer, "REPEAT": Integer, "SEQUENCE": Integer,
+	"ATTENDEE": CalAddress, "ORGANIZER": CalAddress, "TZURL": URI, "URL": URI, "ATTACH": URI,
+	"TZOFFSETFROM": UTCOffset, "TZOFFSETTO": UTCOffset, "RRULE": Recur, "EXRULE": Recur,
+}
+
+//cardTypes contains the default value types of the properties of RFC6350, Section 6.
+var cardTypes = map[string]string{
+	"SOURCE": URI, "PHOTO": URI, "IMPP": URI, "LOGO": URI, "MEMBER": URI, "RELATED": URI, "SOUND": URI, "UID": URI,
+	"URL": URI, "KEY": URI, "FBURL": URI, "CALADRURI": URI, "CALURI": URI, "GEO": URI,
+	"BDAY": DateAndOrTime, "ANNIVERSARY": DateAndOrTime, "REV": Timestamp, "LANG": LanguageTag,
+}
+
+//structured lists the properties whose values consist of components separated by semicolons.
+var structured = map[bool]map[string]bool{
+	false: {"REQUEST-STATUS": true, "GEO": true},
+	true:  {"N": true, "ADR": true, "ORG": true, "GENDER": true, "CLIENTPIDMAP": true},
+}
+
+//multiValued lists the properties which can have multiple values separated by commas.
+var multiValued = map[bool]map[string]bool{
+	false: {"CATEGORIES": true, "RESOURCES": true, "EXDATE": true, "RDATE": true, "FREEBUSY": true},
+	true:  {"NICKNAME": true, "CATEGORIES": true},
+}
+
+//Default returns the default value type of a property of a calendar or, if vcard is set, of a vCard.
+// Properties which are not known are of the type Unknown.
+func Default(name string, vcard bool) string {
+	name = strings.ToUpper(name)
+	table := calendarTypes
+	if vcard {
+		table = cardTypes
+	}
+	if t, ok := table[name]; ok {
+		return t
+	}
+	if strings.HasPrefix(name, "X-") || (!vcard && !knownCalendar[name]) || (vcard && !knownCard[name]) {
+		return Unknown
+	}
+	return Text
+}
+
+//knownCalendar and knownCard list the text properties, all other properties without entry in the tables of
+// default types are unknown.
+var (
+	knownCalendar = setOf("CALSCALE", "METHOD", "PRODID", "VERSION", "CATEGORIES", "CLASS", "COMMENT",
+		"DESCRIPTION", "LOCATION", "RESOURCES", "STATUS", "SUMMARY", "TRANSP", "TZID", "TZNAME", "CONTACT",
+		"RELATED-TO", "UID", "ACTION", "REQUEST-STATUS")
+	knownCard = setOf("VERSION", "FN", "N", "NICKNAME", "ADR", "TITLE", "ROLE", "ORG", "NOTE", "PRODID", "CATEGORIES",
+		"KIND", "GENDER", "EMAIL", "TEL", "TZ", "SORT-STRING", "LABEL", "MAILER", "NAME", "CLASS", "CLIENTPIDMAP",
+		"XML", "PROFILE")
+)
+
+func setOf(names ...string) map[string]bool {
+	out := make(map[string]bool)
+	for _, n := range names {
+		out[n] = true
+	}
+	return out
+}
+
+//Of returns the value type of p: the VALUE parameter in lower case or the default type.
+func Of(p *go_contentline.Property, vcard bool) string {
+	if v := p.Parameters.Get("VALUE"); v != "" {
+		return strings.ToLower(v)
+	}
+	return Default(p.Name, vcard)
+}
+
+//IsStructured reports whether the value of the property consists of components separated by semicolons.
+func IsStructured(name string, vcard bool) bool {
+	return structured[vcard][strings.ToUpper(name)]
+}
+
+//IsMultiValued reports whether the property can have multiple values separated by commas.
+func IsMultiValued(name string, vcard bool) bool {
+	return multiValued[vcard][strings.ToUpper(name)]
+}
+
+//Split splits the value of a property into its values (for multi-valued properties) and these into their components
+// (for structured properties) and their list elements. The text of TEXT values is unescaped. The result has at least
+// one value, each with at least one component containing at least one element.
+func Split(p *go_contentline.Property, vcard bool) [][][]string {
+	typ := Of(p, vcard)
+	values := []string{p.Value}
+	if IsMultiValued(p.Name, vcard) {
+		values = splitEscaped(p.Value, ',')
+	}
+	out := make([][][]string, len(values))
+	for i, v := range values {
+		components := []string{v}
+		if IsStructured(p.Name, vcard) {
+			components = splitEscaped(v, ';')
+		}
+		out[i] = make([][]string, len(components))
+		for j, c := range components {
+			elems := []string{c}
+			if IsStructured(p.Name, vcard) && typ == Text {
+				elems = splitEscaped(c, ',')
+			}
+			if typ == Text {
+				for k := range elems {
+					elems[k] = go_contentline.UnescapeText(elems[k])
+				}
+			}
+			out[i][j] = elems
+		}
+	}
+	return out
+}
+
+//Join is the reverse of Split and returns the value of a property.
+func Join(values [][][]string, typ string) string {
+	vals := make([]string, len(values))
+	for i, components := range values {
+		comps := make([]string, len(components))
+		for j, elems := range components {
+			escaped := make([]string, len(elems))
+			for k, e := range elems {
+				if typ == Text {
+					e = go_contentline.EscapeText(e)
+				}
+				escaped[k] = e
+			}
+			comps[j] = strings.Join(escaped, ",")
+		}
+		vals[i] = strings.Join(comps, ";")
+	}
+	return strings.Join(vals, ",")
+}
+
+//splitEscaped splits a value at the separators which are not escaped by a backslash.
+func splitEscaped(value string, sep byte) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			out = append(out, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, value[start:])
+}
+
+//Extend converts a value of the types DATE, DATE-TIME, TIME, DATE-AND-OR-TIME, TIMESTAMP, UTC-OFFSET and PERIOD
+// from the basic format of content lines (e.g. 20200115T100000Z) to the extended format of jCal and xCal
+// (2020-01-15T10:00:00Z). Values of other types are returned unchanged.
+func Extend(typ, value string) string {
+	switch typ {
+	case Date:
+		return extendDate(value)
+	case DateTime, DateAndOrTime, Timestamp:
+		if i := strings.IndexByte(value, 'T'); i >= 0 {
+			return extendDate(value[:i]) + "T" + extendTime(value[i+1:])
+		}
+		return extendDate(value)
+	case Time:
+		return extendTime(value)
+	case UTCOffset:
+		return extendTime(value)
+	case Period:
+		parts := strings.SplitN(value, "/", 2)
+		for i, part := range parts {
+			if !strings.HasPrefix(part, "P") && !strings.HasPrefix(part, "+P") && !strings.HasPrefix(part, "-P") {
+				parts[i] = Extend(DateTime, part)
+			}
+		}
+		return strings.Join(parts, "/")
+	}
+	return value
+}
+
+//Basic is the reverse of Extend.
+func Basic(typ, value string) string {
+	switch typ {
+	case Date, DateTime, DateAndOrTime, Timestamp:
+		date, tm := value, ""
+		if i := strings.IndexByte(value, 'T'); i >= 0 {
+			date, tm = value[:i], "T"+strings.Replace(value[i+1:], ":", "", -1)
+		}
+		//reduced dates like 1985-04 keep their hyphen
+		if len(date) == 10 && !strings.HasPrefix(date, "-") {
+			date = strings.Replace(date, "-", "", -1)
+		} else if strings.HasPrefix(date, "--") && len(date) == 7 {
+			date = "--" + strings.Replace(date[2:], "-", "", -1)
+		}
+		return date + tm
+	case Time, UTCOffset:
+		return strings.Replace(value, ":", "", -1)
+	case Period:
+		parts := strings.SplitN(value, "/", 2)
+		for i, part := range parts {
+			parts[i] = Basic(DateTime, part)
+		}
+		return strings.Join(parts, "/")
+	}
+	return value
+}
+
+//extendDate converts 20200115 to 2020-01-15 and --0415 to --04-15.
+func extendDate(s string) string {
+	switch {
+	case len(s) == 8 && isDigits(s):
+		return s[:4] + "-" + s[4:6] + "-" + s[6:]
+	case len(s) == 6 && strings.HasPrefix(s, "--") && isDigits(s[2:]):
+		return s[:4] + "-" + s[4:]
+	}
+	return s
+}
+
+//extendTime converts 100000Z to 10:00:00Z and +0100 to +01:00. Truncated times like -2200 keep their hyphen.
+func extendTime(s string) string {
+	zone := ""
+	if i := strings.LastIndexAny(s, "Z+"); i >= 0 {
+		s, zone = s[:i], s[i:]
+	} else if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s, zone = s[:i], s[i:]
+	}
+	if len(zone) == 5 && isDigits(zone[1:]) {
+		zone = zone[:3] + ":" + zone[3:]
+	}
+	lead := ""
+	for strings.HasPrefix(s, "-") {
+		lead, s = lead+"-", s[1:]
+	}
+	if !isDigits(s) || len(s)%2 != 0 {
+		return lead + s + zone
+	}
+	var groups []string
+	for i := 0; i < len(s); i += 2 {
+		groups = append(groups, s[i:i+2])
+	}
+	return lead + strings.Join(groups, ":") + zone
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+//RecurPart is a part of a recurrence rule, e.g. BYDAY=MO,TU.
+type RecurPart struct {
+	//Name is the name of the part in lower case, e.g. byday
+	Name   string
+	Values []string
+}
+
+//recurOrder is the order of the parts of a recurrence rule written by JoinRecur.
+var recurOrder = []string{"freq", "until", "count", "interval", "bysecond", "byminute", "byhour", "byday",
+	"bymonthday", "byyearday", "byweekno", "bymonth", "bysetpos", "wkst"}
+
+//IsNumericRecurPart reports whether the values of a part of a recurrence rule are integers.
+func IsNumericRecurPart(name string) bool {
+	switch name {
+	case "count", "interval", "bysecond", "byminute", "byhour", "bymonthday", "byyearday", "byweekno", "bymonth",
+		"bysetpos":
+		return true
+	}
+	return false
+}
+
+//SplitRecur splits a recurrence rule into its parts. UNTIL is converted to the extended format.
+func SplitRecur(value string) []RecurPart {
+	var out []RecurPart
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		name := strings.ToLower(kv[0])
+		vals := strings.Split(kv[1], ",")
+		if name == "until" {
+			vals = []string{Extend(DateTime, kv[1])}
+		}
+		out = append(out, RecurPart{name, vals})
+	}
+	return out
+}
+
+//JoinRecur is the reverse of SplitRecur. The parts are written in a canonical order with FREQ first.
+func JoinRecur(parts []RecurPart) string {
+	rank := make(map[string]int)
+	for i, name := range recurOrder {
+		rank[name] = i + 1
+	}
+	sorted := append([]RecurPart(nil), parts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := rank[sorted[i].Name], rank[sorted[j].Name]
+		if ri == 0 || rj == 0 {
+			return ri != 0 && rj == 0
+		}
+		return ri < rj
+	})
+	out := make([]string, len(sorted))
+	for i, part := range sorted {
+		vals := part.Values
+		if part.Name == "until" && len(vals) == 1 {
+			vals = []string{Basic(DateTime, vals[0])}
+		}
+		out[i] = strings.ToUpper(part.Name) + "=" + strings.Join(vals, ",")
+	}
+	return strings.Join(out, ";")
+}
@@ -0,0 +1,285 @@
+//Package itip implements the construction, validation and processing of scheduling messages as defined by
+// iTIP (RFC5546).
+//
+// All functions working on stored calendar objects expect a VCALENDAR component containing all components of a single
+// UID (the master and its overrides), as stored by CalDAV servers, together with the VTIMEZONEs they reference.
+package itip
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+//Method is the value of the METHOD property of an iTIP message.
+type Method string
+
+//The methods defined by RFC5546, Section 1.4.
+const (
+	Publish        Method = "PUBLISH"
+	Request        Method = "REQUEST"
+	Reply          Method = "REPLY"
+	Add            Method = "ADD"
+	Cancel         Method = "CANCEL"
+	Refresh        Method = "REFRESH"
+	Counter        Method = "COUNTER"
+	DeclineCounter Method = "DECLINECOUNTER"
+)
+
+//prodID is the value of the PRODID property of all generated messages.
+const prodID = "-//mqus//go-contentline itip//EN"
+
+//now returns the time used for DTSTAMP, it is only replaced by tests.
+var now = time.Now
+
+//scheduling returns whether the component can be the subject of a scheduling message.
+func scheduling(c *go_contentline.Component) bool {
+	return c.Name == "VEVENT" || c.Name == "VTODO" || c.Name == "VJOURNAL"
+}
+
+//NewMessage wraps copies of the components into a VCALENDAR with the given METHOD, sets their DTSTAMP to the current
+// time and validates the result, see Validate.
+func NewMessage(method Method, comps ...*go_contentline.Component) (*go_contentline.Component, error) {
+	msg := &go_contentline.Component{Name: "VCALENDAR"}
+	msg.AddProperty(
+		newProperty("PRODID", prodID),
+		newProperty("VERSION", "2.0"),
+		newProperty("METHOD", string(method)),
+	)
+	stamped := now().UTC().Truncate(time.Second)
+	for _, c := range comps {
+		c = c.Clone()
+		if scheduling(c) {
+			stamp := newProperty("DTSTAMP", "")
+			stamp.SetDateTime(stamped, false)
+			setProperty(c, stamp)
+		}
+		msg.AddComponent(c)
+	}
+	if err := Validate(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+//NewPublish creates a PUBLISH message for all components of the stored calendar object. Attendees are removed, as
+// published components must not contain them.
+func NewPublish(cal *go_contentline.Component) (*go_contentline.Component, error) {
+	comps := copyComps(cal)
+	for _, c := range comps {
+		if scheduling(c) {
+			removeProperties(c, "ATTENDEE")
+		}
+	}
+	return NewMessage(Publish, comps...)
+}
+
+//NewRequest creates a REQUEST message for all components of the stored calendar object, which is sent by the
+// organizer to invite the attendees or to send them an update. significant marks an update with significant changes
+// (e.g. of the time or location): the SEQUENCE of the message and of the stored object is incremented (see
+// BumpSequence), so that later replies are checked against the new SEQUENCE. cal is not changed if an error is
+// returned.
+func NewRequest(cal *go_contentline.Component, significant bool) (*go_contentline.Component, error) {
+	comps := copyComps(cal)
+	if significant {
+		for _, c := range comps {
+			if scheduling(c) {
+				setSequence(c, Sequence(c)+1)
+			}
+		}
+	}
+	msg, err := NewMessage(Request, comps...)
+	if err != nil {
+		return nil, err
+	}
+	if significant {
+		BumpSequence(cal)
+	}
+	return msg, nil
+}
+
+//NewAdd creates an ADD message, which adds the instances given in cal (overrides with RECURRENCE-ID are not allowed)
+// to an existing recurring component.
+func NewAdd(cal *go_contentline.Component) (*go_contentline.Component, error) {
+	return NewMessage(Add, copyComps(cal)...)
+}
+
+//NewCancel creates a CANCEL message. If no attendees are given, the whole calendar object (all its components) is
+// cancelled for everyone. Otherwise only the given attendees (calendar user addresses like mailto:a@example.com) are
+// removed from it. The SEQUENCE of the message is one higher than the one of the stored object, which should be
+// updated with BumpSequence (and by removing the attendees) after sending the message.
+func NewCancel(cal *go_contentline.Component, attendees ...string) (*go_contentline.Component, error) {
+	var comps []*go_contentline.Component
+	for _, c := range cal.Comps {
+		if !scheduling(c) {
+			continue
+		}
+		out := minimal(c, "UID", "ORGANIZER", "RECURRENCE-ID", "SEQUENCE", "DTSTART", "SUMMARY")
+		for _, att := range c.FindProperties("ATTENDEE") {
+			if len(attendees) == 0 || containsAddress(attendees, att.Value) {
+				out.AddProperty(att.Clone())
+			}
+		}
+		if len(attendees) == 0 {
+			setProperty(out, newProperty("STATUS", "CANCELLED"))
+		}
+		setSequence(out, Sequence(c)+1)
+		comps = append(comps, out)
+	}
+	return NewMessage(Cancel, comps...)
+}
+
+//NewReply creates a REPLY message of the attendee (a calendar user address like mailto:a@example.com), which sets
+// its participation status (e.g. ACCEPTED or DECLINED) for all components of the stored calendar object.
+func NewReply(cal *go_contentline.Component, attendee, partstat string) (*go_contentline.Component, error) {
+	var comps []*go_contentline.Component
+	for _, c := range cal.Comps {
+		if !scheduling(c) {
+			continue
+		}
+		att := findAttendee(c, attendee)
+		if att == nil {
+			return nil, errors.Errorf("%s is not an attendee of %s", attendee, uid(c))
+		}
+		out := minimal(c, "UID", "ORGANIZER", "RECURRENCE-ID", "SEQUENCE")
+		att = att.Clone()
+		att.SetParameter("PARTSTAT", strings.ToUpper(partstat))
+		delete(att.Parameters, "RSVP")
+		out.AddProperty(att)
+		comps = append(comps, out)
+	}
+	return NewMessage(Reply, comps...)
+}
+
+//NewRefresh creates a REFRESH message, by which the attendee requests the latest version of the calendar object.
+func NewRefresh(cal *go_contentline.Component, attendee string) (*go_contentline.Component, error) {
+	var comps []*go_contentline.Component
+	for _, c := range cal.Comps {
+		if !scheduling(c) || c.GetProperty("RECURRENCE-ID") != nil {
+			continue
+		}
+		out := minimal(c, "UID", "ORGANIZER")
+		out.AddProperty(newProperty("ATTENDEE", attendee))
+		comps = append(comps, out)
+	}
+	return NewMessage(Refresh, comps...)
+}
+
+//NewCounter creates a COUNTER message, by which an attendee proposes changes to the calendar object. cal contains the
+// components with the proposed changes.
+func NewCounter(cal *go_contentline.Component) (*go_contentline.Component, error) {
+	return NewMessage(Counter, copyComps(cal)...)
+}
+
+//NewDeclineCounter creates a DECLINECOUNTER message, by which the organizer rejects a received COUNTER message.
+func NewDeclineCounter(counter *go_contentline.Component) (*go_contentline.Component, error) {
+	var comps []*go_contentline.Component
+	for _, c := range counter.Comps {
+		if !scheduling(c) {
+			continue
+		}
+		out := minimal(c, "UID", "ORGANIZER", "RECURRENCE-ID", "SEQUENCE")
+		for _, att := range c.FindProperties("ATTENDEE") {
+			out.AddProperty(att.Clone())
+		}
+		comps = append(comps, out)
+	}
+	return NewMessage(DeclineCounter, comps...)
+}
+
+//Sequence returns the value of the SEQUENCE property of the component, which is 0 if it is missing or invalid.
+func Sequence(c *go_contentline.Component) int {
+	if p := c.GetProperty("SEQUENCE"); p != nil {
+		if n, err := strconv.Atoi(p.Value); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+//BumpSequence increments the SEQUENCE of all components of the stored calendar object.
+func BumpSequence(cal *go_contentline.Component) {
+	for _, c := range cal.Comps {
+		if scheduling(c) {
+			setSequence(c, Sequence(c)+1)
+		}
+	}
+}
+
+func setSequence(c *go_contentline.Component, n int) {
+	setProperty(c, newProperty("SEQUENCE", strconv.Itoa(n)))
+}
+
+//copyComps returns copies of all subcomponents.
+func copyComps(cal *go_contentline.Component) []*go_contentline.Component {
+	out := make([]*go_contentline.Component, len(cal.Comps))
+	for i, c := range cal.Comps {
+		out[i] = c.Clone()
+	}
+	return out
+}
+
+//minimal returns a new component with the same name and copies of the given properties of c, if present.
+func minimal(c *go_contentline.Component, names ...string) *go_contentline.Component {
+	out := &go_contentline.Component{Name: c.Name}
+	for _, name := range names {
+		for _, p := range c.FindProperties(name) {
+			out.AddProperty(p.Clone())
+		}
+	}
+	return out
+}
+
+//setProperty replaces all properties with the name of p by p.
+func setProperty(c *go_contentline.Component, p *go_contentline.Property) {
+	removeProperties(c, p.Name)
+	c.AddProperty(p)
+}
+
+func removeProperties(c *go_contentline.Component, name string) {
+	props := c.Properties[:0]
+	for _, p := range c.Properties {
+		if p.Name != name {
+			props = append(props, p)
+		}
+	}
+	c.Properties = props
+}
+
+//findAttendee returns the ATTENDEE with the given calendar user address.
+func findAttendee(c *go_contentline.Component, address string) *go_contentline.Property {
+	for _, att := range c.FindProperties("ATTENDEE") {
+		if sameAddress(att.Value, address) {
+			return att
+		}
+	}
+	return nil
+}
+
+func containsAddress(list []string, address string) bool {
+	for _, a := range list {
+		if sameAddress(a, address) {
+			return true
+		}
+	}
+	return false
+}
+
+//sameAddress compares two calendar user addresses. The scheme and email addresses are compared case-insensitively.
+func sameAddress(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+func uid(c *go_contentline.Component) string {
+	if p := c.GetProperty("UID"); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+func newProperty(name, value string) *go_contentline.Property {
+	return go_contentline.NewPropertyUnchecked(name, value, make(go_contentline.Parameters))
+}
@@ -0,0 +1,284 @@
+package itip
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+const testEvent = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:meeting-1\r\n" +
+	"SUMMARY:Planning\r\n" +
+	"DTSTART:20180102T100000Z\r\n" +
+	"DTEND:20180102T110000Z\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=5\r\n" +
+	"ORGANIZER:mailto:boss@example.com\r\n" +
+	"ATTENDEE;RSVP=TRUE;PARTSTAT=NEEDS-ACTION:mailto:a@example.com\r\n" +
+	"ATTENDEE;RSVP=TRUE;PARTSTAT=NEEDS-ACTION:mailto:b@example.com\r\n" +
+	"SEQUENCE:1\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func init() {
+	now = func() time.Time { return time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC) }
+}
+
+func parseString(t *testing.T, in string) *go_contentline.Component {
+	t.Helper()
+	c, err := go_contentline.InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func encode(c *go_contentline.Component) string {
+	var buf bytes.Buffer
+	c.Encode(&buf)
+	return buf.String()
+}
+
+func TestNewMessage(t *testing.T) {
+	cal := parseString(t, testEvent)
+	before := cal.Clone()
+
+	req, err := NewRequest(cal, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Sequence(req.Comps[0]) != 1 {
+		t.Errorf("expected the SEQUENCE of the stored object, got %d", Sequence(req.Comps[0]))
+	}
+	stored := cal.Clone()
+	update, err := NewRequest(stored, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Sequence(update.Comps[0]) != 2 || Sequence(stored.Comps[0]) != 2 {
+		t.Errorf("expected SEQUENCE 2 for a significant update, got %d in the message and %d in the stored object",
+			Sequence(update.Comps[0]), Sequence(stored.Comps[0]))
+	}
+	if MethodOf(req) != Request || req.GetProperty("PRODID") == nil || req.GetProperty("VERSION").Value != "2.0" {
+		t.Errorf("unexpected calendar properties: %v", req.Properties)
+	}
+	if stamp := req.Comps[0].GetProperty("DTSTAMP"); stamp == nil || stamp.Value != "20180101T120000Z" {
+		t.Errorf("unexpected DTSTAMP: %v", stamp)
+	}
+
+	pub, err := NewPublish(cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.Comps[0].FindProperties("ATTENDEE")) != 0 {
+		t.Error("PUBLISH must not contain attendees")
+	}
+
+	cancel, err := NewCancel(cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cancel.Comps[0]; Sequence(c) != 2 || c.GetProperty("STATUS").Value != "CANCELLED" || len(c.FindProperties("ATTENDEE")) != 2 || c.GetProperty("RRULE") != nil {
+		t.Errorf("unexpected CANCEL:\n%s", encode(cancel))
+	}
+	cancel, err = NewCancel(cal, "MAILTO:b@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cancel.Comps[0]; c.GetProperty("STATUS") != nil || len(c.FindProperties("ATTENDEE")) != 1 {
+		t.Errorf("unexpected CANCEL for one attendee:\n%s", encode(cancel))
+	}
+
+	refresh, err := NewRefresh(cal, "mailto:a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := refresh.Comps[0]; len(c.Properties) != 4 {
+		t.Errorf("unexpected REFRESH:\n%s", encode(refresh))
+	}
+
+	counter, err := NewCounter(cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decline, err := NewDeclineCounter(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if MethodOf(decline) != DeclineCounter || decline.Comps[0].GetProperty("SUMMARY") != nil {
+		t.Errorf("unexpected DECLINECOUNTER:\n%s", encode(decline))
+	}
+
+	if _, err := NewReply(cal, "mailto:c@example.com", "ACCEPTED"); err == nil {
+		t.Error("expected an error for an unknown attendee")
+	}
+
+	//the stored object is not changed by creating messages
+	if !reflect.DeepEqual(cal, before) {
+		t.Errorf("the stored object was changed:\n%s", encode(cal))
+	}
+
+	BumpSequence(cal)
+	if Sequence(cal.Comps[0]) != 2 {
+		t.Errorf("expected SEQUENCE 2, got %d", Sequence(cal.Comps[0]))
+	}
+}
+
+func TestApplyReply(t *testing.T) {
+	stored := parseString(t, testEvent)
+	attendeeCopy := parseString(t, testEvent)
+
+	reply, err := NewReply(attendeeCopy, "mailto:a@example.com", "accepted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := reply.Comps[0]; len(c.FindProperties("ATTENDEE")) != 1 || c.GetProperty("DTSTART") != nil {
+		t.Errorf("unexpected REPLY:\n%s", encode(reply))
+	}
+	if err = ApplyReply(stored, reply); err != nil {
+		t.Fatal(err)
+	}
+	att := stored.Comps[0].FindProperties("ATTENDEE")[0]
+	if att.Parameters.Get("PARTSTAT") != "ACCEPTED" || att.Parameters.Get("RSVP") != "" {
+		t.Errorf("attendee was not updated: %v", att)
+	}
+
+	//a reply for a single instance creates an override
+	reply.Comps[0].AddProperty(go_contentline.NewPropertyUnchecked("RECURRENCE-ID", "20180109T100000Z", go_contentline.Parameters{}))
+	reply.Comps[0].FindProperties("ATTENDEE")[0].SetParameter("PARTSTAT", "DECLINED")
+	if err = ApplyReply(stored, reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Comps) != 2 {
+		t.Fatalf("expected an override, got:\n%s", encode(stored))
+	}
+	override := stored.Comps[1]
+	if override.GetProperty("RRULE") != nil || override.GetProperty("DTSTART").Value != "20180109T100000Z" ||
+		override.GetProperty("DTEND").Value != "20180109T110000Z" || override.FindProperties("ATTENDEE")[0].Parameters.Get("PARTSTAT") != "DECLINED" {
+		t.Errorf("unexpected override:\n%s", encode(override))
+	}
+	//the second reply for the instance uses the override
+	if err = ApplyReply(stored, reply); err != nil || len(stored.Comps) != 2 {
+		t.Errorf("expected the existing override to be used (%v)", err)
+	}
+
+	//outdated replies are rejected
+	BumpSequence(stored)
+	if err = ApplyReply(stored, reply); err == nil {
+		t.Error("expected an error for an outdated reply")
+	}
+
+	//only replies are accepted
+	req, _ := NewRequest(stored, false)
+	if err = ApplyReply(stored, req); err == nil {
+		t.Error("expected an error for a REQUEST")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	checks := map[string]string{
+		"BEGIN:VCALENDAR\r\nPRODID:x\r\nVERSION:2.0\r\nEND:VCALENDAR\r\n": "" +
+			"VCALENDAR must contain METHOD exactly once, found 0; no VEVENT, VTODO or VJOURNAL found",
+		"BEGIN:VCALENDAR\r\nPRODID:x\r\nVERSION:2.0\r\nMETHOD:REPLY\r\n" +
+			"BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20180101T000000Z\r\nEND:VEVENT\r\n" +
+			"BEGIN:VTODO\r\nUID:2\r\nDTSTAMP:20180101T000000Z\r\nORGANIZER:mailto:x@example.com\r\nATTENDEE:mailto:y@example.com\r\nEND:VTODO\r\n" +
+			"END:VCALENDAR\r\n": "" +
+			"VEVENT must contain ATTENDEE exactly once in a REPLY, found 0; " +
+			"VEVENT must contain ORGANIZER exactly once in a REPLY, found 0; " +
+			"all components must have the same UID, found 2",
+		"BEGIN:VCALENDAR\r\nPRODID:x\r\nVERSION:2.0\r\nMETHOD:PUBLISH\r\n" +
+			"BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20180101T000000Z\r\nORGANIZER:mailto:x@example.com\r\nATTENDEE:mailto:y@example.com\r\nSEQUENCE:x\r\nEND:VEVENT\r\n" +
+			"END:VCALENDAR\r\n": "" +
+			"VEVENT must not contain ATTENDEE in a PUBLISH; VEVENT must contain DTSTART in a PUBLISH; invalid SEQUENCE \"x\"",
+		"BEGIN:VCALENDAR\r\nPRODID:x\r\nVERSION:2.0\r\nMETHOD:SUBSCRIBE\r\n" +
+			"BEGIN:VTODO\r\nUID:2\r\nEND:VTODO\r\n" +
+			"END:VCALENDAR\r\n": "" +
+			"unknown METHOD SUBSCRIBE",
+	}
+	for in, want := range checks {
+		err := Validate(parseString(t, in))
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("expected a *ValidationError, got %v", err)
+			continue
+		}
+		if got := strings.Join(verr.Problems, "; "); got != want {
+			t.Errorf("Wanted: %s\nGot:    %s", want, got)
+		}
+	}
+}
+
+func TestApplyReplyTimezones(t *testing.T) {
+	const vtimezone = "BEGIN:VTIMEZONE\r\n" +
+		"TZID:W. Europe Standard Time\r\n" +
+		"BEGIN:STANDARD\r\n" +
+		"DTSTART:16010101T000000\r\n" +
+		"TZOFFSETFROM:+0100\r\n" +
+		"TZOFFSETTO:+0100\r\n" +
+		"END:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n"
+	stored := parseString(t, "BEGIN:VCALENDAR\r\n"+
+		vtimezone+
+		"BEGIN:VEVENT\r\n"+
+		"UID:meeting-2\r\n"+
+		"DTSTART;TZID=W. Europe Standard Time:20180102T100000\r\n"+
+		"DTEND;TZID=W. Europe Standard Time:20180102T110000\r\n"+
+		"RRULE:FREQ=WEEKLY;COUNT=5\r\n"+
+		"ORGANIZER:mailto:boss@example.com\r\n"+
+		"ATTENDEE;PARTSTAT=NEEDS-ACTION:mailto:a@example.com\r\n"+
+		"SEQUENCE:3\r\n"+
+		"END:VEVENT\r\n"+
+		"END:VCALENDAR\r\n")
+	before := encode(stored)
+	reply := func(rid, sequence string) *go_contentline.Component {
+		return parseString(t, "BEGIN:VCALENDAR\r\n"+
+			"PRODID:x\r\nVERSION:2.0\r\nMETHOD:REPLY\r\n"+
+			"BEGIN:VEVENT\r\n"+
+			"UID:meeting-2\r\n"+
+			"DTSTAMP:20180101T000000Z\r\n"+
+			"ORGANIZER:mailto:boss@example.com\r\n"+
+			"ATTENDEE;PARTSTAT=DECLINED:mailto:a@example.com\r\n"+
+			rid+
+			"SEQUENCE:3\r\n"+
+			"END:VEVENT\r\n"+
+			"BEGIN:VEVENT\r\n"+
+			"UID:meeting-2\r\n"+
+			"DTSTAMP:20180101T000000Z\r\n"+
+			"ORGANIZER:mailto:boss@example.com\r\n"+
+			"ATTENDEE;PARTSTAT=DECLINED:mailto:a@example.com\r\n"+
+			"RECURRENCE-ID;TZID=W. Europe Standard Time:20180116T100000\r\n"+
+			"SEQUENCE:"+sequence+"\r\n"+
+			"END:VEVENT\r\n"+
+			"END:VCALENDAR\r\n")
+	}
+
+	//the outdated second component is only found after the override for the first one was created
+	err := ApplyReply(stored, reply("RECURRENCE-ID;TZID=W. Europe Standard Time:20180109T100000\r\n", "1"))
+	if errors.Cause(err) != ErrOutdated {
+		t.Fatalf("expected an error for an outdated reply, got %v", err)
+	}
+	if after := encode(stored); after != before {
+		t.Errorf("the stored object was changed by a failed reply:\n%s", after)
+	}
+
+	r := reply("RECURRENCE-ID:20180109T090000Z\r\n", "3")
+	if err := ApplyReply(stored, r); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Comps) != 4 {
+		t.Fatalf("expected two overrides, got:\n%s", encode(stored))
+	}
+	override := stored.Comps[2]
+	if override.GetProperty("DTSTART").Value != "20180109T090000Z" || override.GetProperty("DTEND").Value != "20180109T100000Z" {
+		t.Errorf("unexpected override:\n%s", encode(override))
+	}
+	//the UTC RECURRENCE-ID matches the instance in the VTIMEZONE of the stored object
+	if err := ApplyReply(stored, r); err != nil || len(stored.Comps) != 4 {
+		t.Errorf("expected the existing overrides to be used (%v):\n%s", err, encode(stored))
+	}
+}
@@ -0,0 +1,138 @@
+package itip
+
+import (
+	"strings"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/timezone"
+	"github.com/pkg/errors"
+)
+
+//ErrOutdated is returned by ApplyReply if the reply refers to an older SEQUENCE than the stored component.
+var ErrOutdated = errors.New("reply refers to an outdated version")
+
+//ApplyReply processes a REPLY message received by the organizer: it updates the PARTSTAT (and DELEGATED-TO) parameters
+// of the replying attendee in the matching components of the stored calendar object. Attendees which are not yet
+// listed are added. If the reply refers to an instance of a recurring component for which no override exists,
+// an override is created from the master component. TZIDs are resolved with the VTIMEZONEs of the reply and of cal.
+// If an error is returned, cal is not changed.
+func ApplyReply(cal, reply *go_contentline.Component) error {
+	if err := Validate(reply); err != nil {
+		return err
+	}
+	if m := MethodOf(reply); m != Reply {
+		return errors.Errorf("expected a %s, got %s", Reply, m)
+	}
+
+	//find and check all targets first, so that cal is only changed if all of them are valid
+	resolve := timezone.Resolver(cal, nil)
+	replyResolve := timezone.Resolver(reply, resolve)
+	var replied, targets, overrides []*go_contentline.Component
+	for _, rc := range reply.Comps {
+		if !scheduling(rc) {
+			continue
+		}
+		comps := append(cal.Comps[:len(cal.Comps):len(cal.Comps)], overrides...)
+		target, isNew, err := findTarget(comps, rc, resolve, replyResolve)
+		if err != nil {
+			return err
+		}
+		if Sequence(rc) < Sequence(target) {
+			return errors.Wrapf(ErrOutdated, "SEQUENCE %d < %d", Sequence(rc), Sequence(target))
+		}
+		if isNew {
+			overrides = append(overrides, target)
+		}
+		replied = append(replied, rc)
+		targets = append(targets, target)
+	}
+
+	cal.AddComponent(overrides...)
+	for i, rc := range replied {
+		target := targets[i]
+		ratt := rc.GetProperty("ATTENDEE")
+		att := findAttendee(target, ratt.Value)
+		if att == nil {
+			att = ratt.Clone()
+			target.AddProperty(att)
+		}
+		if partstat := ratt.Parameters.Get("PARTSTAT"); partstat != "" {
+			att.SetParameter("PARTSTAT", strings.ToUpper(partstat))
+		}
+		if vals := ratt.Parameters["DELEGATED-TO"]; len(vals) > 0 {
+			att.SetParameter("DELEGATED-TO", vals...)
+		}
+		delete(att.Parameters, "RSVP")
+	}
+	return nil
+}
+
+//findTarget returns the stored component (one of comps) to which the component of a reply refers. If it refers to an
+// instance without override, a new override is returned, which is not added to the stored calendar object yet.
+// Stored TZIDs are resolved with resolve, those of the reply with replyResolve.
+func findTarget(comps []*go_contentline.Component, rc *go_contentline.Component,
+	resolve, replyResolve go_contentline.TZResolver) (target *go_contentline.Component, isNew bool, err error) {
+	var master *go_contentline.Component
+	rid := rc.GetProperty("RECURRENCE-ID")
+	for _, c := range comps {
+		if c.Name != rc.Name || uid(c) != uid(rc) {
+			continue
+		}
+		crid := c.GetProperty("RECURRENCE-ID")
+		switch {
+		case crid == nil && rid == nil:
+			return c, false, nil
+		case crid == nil:
+			master = c
+		case rid != nil && sameInstant(crid, rid, resolve, replyResolve):
+			return c, false, nil
+		}
+	}
+	if master == nil {
+		return nil, false, errors.Errorf("no %s with UID %s found", rc.Name, uid(rc))
+	}
+	target, err = newOverride(master, rid, resolve, replyResolve)
+	return target, err == nil, err
+}
+
+//sameInstant compares a stored RECURRENCE-ID a with the RECURRENCE-ID b of a reply.
+func sameInstant(a, b *go_contentline.Property, resolve, replyResolve go_contentline.TZResolver) bool {
+	ta, _, err1 := a.DateTime(resolve)
+	tb, _, err2 := b.DateTime(replyResolve)
+	if err1 != nil || err2 != nil {
+		return a.Value == b.Value && a.Parameters.Get("TZID") == b.Parameters.Get("TZID")
+	}
+	return ta.Equal(tb)
+}
+
+//newOverride returns an override for the instance rid (of a reply) of master.
+func newOverride(master *go_contentline.Component, rid *go_contentline.Property,
+	resolve, replyResolve go_contentline.TZResolver) (*go_contentline.Component, error) {
+	start, isDate, err := rid.DateTime(replyResolve)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid RECURRENCE-ID")
+	}
+	out := master.Clone()
+	removeProperties(out, "RRULE")
+	removeProperties(out, "RDATE")
+	removeProperties(out, "EXDATE")
+
+	if dtstart := out.GetProperty("DTSTART"); dtstart != nil {
+		mstart, _, err := dtstart.DateTime(resolve)
+		if err != nil {
+			return nil, err
+		}
+		if end := out.GetProperty("DTEND"); end != nil {
+			mend, endIsDate, err := end.DateTime(resolve)
+			if err != nil {
+				return nil, err
+			}
+			end.SetDateTime(start.Add(mend.Sub(mstart)), endIsDate)
+		}
+		dtstart.SetDateTime(start, isDate)
+	}
+	r := rid.Clone()
+	delete(r.Parameters, "RANGE")
+	out.AddProperty(r)
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package itip
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+)
+
+//ValidationError lists all violations of the rules of RFC5546 found in a message.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid iTIP message: " + strings.Join(e.Problems, "; ")
+}
+
+//cardinality describes how often a property may occur, max is -1 for no limit.
+type cardinality struct {
+	min, max int
+}
+
+var (
+	once       = cardinality{1, 1}
+	optional   = cardinality{0, 1}
+	oneOrMore  = cardinality{1, -1}
+	notAllowed = cardinality{0, 0}
+)
+
+//rules contains the restrictions on the properties of scheduling components for each method, as given by
+// the tables in RFC5546, Section 3.2 to 3.4. Only the properties needed to process a message are listed.
+var rules = map[Method]map[string]cardinality{
+	Publish:        {"DTSTAMP": once, "ORGANIZER": once, "UID": once, "ATTENDEE": notAllowed, "SEQUENCE": optional},
+	Request:        {"DTSTAMP": once, "ORGANIZER": once, "UID": once, "ATTENDEE": oneOrMore, "SEQUENCE": optional, "SUMMARY": once},
+	Reply:          {"DTSTAMP": once, "ORGANIZER": once, "UID": once, "ATTENDEE": once, "SEQUENCE": optional},
+	Add:            {"DTSTAMP": once, "ORGANIZER": once, "UID": once, "ATTENDEE": oneOrMore, "SEQUENCE": once, "SUMMARY": once, "RECURRENCE-ID": notAllowed},
+	Cancel:         {"DTSTAMP": once, "ORGANIZER": once, "UID": once, "SEQUENCE": once},
+	Refresh:        {"DTSTAMP": once, "ORGANIZER": once, "UID": once, "ATTENDEE": once},
+	Counter:        {"DTSTAMP": once, "ORGANIZER": once, "UID": once, "SEQUENCE": optional, "SUMMARY": once},
+	DeclineCounter: {"DTSTAMP": once, "ORGANIZER": once, "UID": once, "SEQUENCE": optional},
+}
+
+//needsStart lists the methods for which a VEVENT must have a DTSTART.
+var needsStart = map[Method]bool{Publish: true, Request: true, Add: true, Counter: true}
+
+//Validate checks that msg is a VCALENDAR with exactly one METHOD and that its components follow the rules of
+// RFC5546 for that method: the required properties are present (and not repeated), all scheduling components share
+// the same UID and there is at least one of them. All violations are returned in a *ValidationError.
+func Validate(msg *go_contentline.Component) error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if msg.Name != "VCALENDAR" {
+		add("expected VCALENDAR, got %s", msg.Name)
+	}
+	for _, name := range []string{"PRODID", "VERSION", "METHOD"} {
+		if n := len(msg.FindProperties(name)); n != 1 {
+			add("VCALENDAR must contain %s exactly once, found %d", name, n)
+		}
+	}
+	method := MethodOf(msg)
+	table, known := rules[method]
+	if !known && method != "" {
+		add("unknown METHOD %s", method)
+	}
+
+	names := make([]string, 0, len(table))
+	for name := range table {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	uids := make(map[string]bool)
+	for _, c := range msg.Comps {
+		if !scheduling(c) {
+			continue
+		}
+		uids[uid(c)] = true
+		for _, name := range names {
+			card := table[name]
+			n := len(c.FindProperties(name))
+			if n < card.min || (card.max >= 0 && n > card.max) {
+				switch {
+				case card.max == 0:
+					add("%s must not contain %s in a %s", c.Name, name, method)
+				case card.max == -1:
+					add("%s must contain %s at least once in a %s", c.Name, name, method)
+				default:
+					add("%s must contain %s %s in a %s, found %d", c.Name, name, times(card), method, n)
+				}
+			}
+		}
+		if needsStart[method] && c.Name == "VEVENT" && c.GetProperty("DTSTART") == nil {
+			add("VEVENT must contain DTSTART in a %s", method)
+		}
+		if seq := c.GetProperty("SEQUENCE"); seq != nil {
+			if n, err := strconv.Atoi(seq.Value); err != nil || n < 0 {
+				add("invalid SEQUENCE %q", seq.Value)
+			}
+		}
+	}
+	switch {
+	case len(uids) == 0:
+		add("no VEVENT, VTODO or VJOURNAL found")
+	case len(uids) > 1:
+		add("all components must have the same UID, found %d", len(uids))
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{problems}
+	}
+	return nil
+}
+
+//times describes a cardinality with a minimum, e.g. "exactly once".
+func times(card cardinality) string {
+	if card.min == 0 {
+		return "at most once"
+	}
+	return "exactly once"
+}
+
+//MethodOf returns the METHOD of a message in upper case, or an empty string if there is none.
+func MethodOf(msg *go_contentline.Component) Method {
+	if p := msg.GetProperty("METHOD"); p != nil {
+		return Method(strings.ToUpper(p.Value))
+	}
+	return ""
+}
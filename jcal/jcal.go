@@ -0,0 +1,284 @@
+//Package jcal converts components to and from their JSON representations jCal (RFC7265) and jCard (RFC7095).
+//
+// VCARD components are written as jCard, all others as jCal. Values are written with their value types, e.g.
+// DATE-TIME values in the extended format 2020-01-15T10:00:00Z, integers as JSON numbers and recurrence rules as
+// objects. Unknown properties are written with the type "unknown" and their value is kept unchanged.
+package jcal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/internal/valuetype"
+	"github.com/pkg/errors"
+)
+
+//Marshal returns the jCal or jCard of a component.
+func Marshal(c *go_contentline.Component) ([]byte, error) {
+	return json.Marshal(toJSON(c, strings.EqualFold(c.Name, "VCARD")))
+}
+
+//MarshalAll returns a JSON array with the jCal or jCard of each component.
+func MarshalAll(cs []*go_contentline.Component) ([]byte, error) {
+	out := make([]interface{}, len(cs))
+	for i, c := range cs {
+		out[i] = toJSON(c, strings.EqualFold(c.Name, "VCARD"))
+	}
+	return json.Marshal(out)
+}
+
+//Unmarshal parses a single jCal or jCard object or a JSON array of them.
+func Unmarshal(data []byte) ([]*go_contentline.Component, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v []interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "invalid jCal")
+	}
+	if len(v) > 0 {
+		if _, ok := v[0].(string); ok {
+			v = []interface{}{v}
+		}
+	}
+	var out []*go_contentline.Component
+	for _, obj := range v {
+		c, err := fromJSON(obj, false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func toJSON(c *go_contentline.Component, vcard bool) []interface{} {
+	props := make([]interface{}, 0, len(c.Properties))
+	for _, p := range c.Properties {
+		props = append(props, propertyToJSON(p, vcard))
+	}
+	if vcard && len(c.Comps) == 0 {
+		return []interface{}{strings.ToLower(c.Name), props}
+	}
+	comps := make([]interface{}, 0, len(c.Comps))
+	for _, sub := range c.Comps {
+		comps = append(comps, toJSON(sub, vcard))
+	}
+	return []interface{}{strings.ToLower(c.Name), props, comps}
+}
+
+func propertyToJSON(p *go_contentline.Property, vcard bool) []interface{} {
+	params := make(map[string]interface{})
+	for k, vals := range p.Parameters {
+		if strings.EqualFold(k, "VALUE") || len(vals) == 0 {
+			continue
+		}
+		if len(vals) == 1 {
+			params[strings.ToLower(k)] = vals[0]
+		} else {
+			params[strings.ToLower(k)] = vals
+		}
+	}
+	if p.Group != "" {
+		params["group"] = strings.ToLower(p.Group)
+	}
+	typ := valuetype.Of(p, vcard)
+	out := []interface{}{strings.ToLower(p.Name), params, typ}
+
+	if typ == valuetype.Recur {
+		rule := make(map[string]interface{})
+		for _, part := range valuetype.SplitRecur(p.Value) {
+			vals := make([]interface{}, len(part.Values))
+			for i, v := range part.Values {
+				vals[i] = v
+				if n, err := strconv.Atoi(v); err == nil && valuetype.IsNumericRecurPart(part.Name) {
+					vals[i] = n
+				}
+			}
+			if len(vals) == 1 {
+				rule[part.Name] = vals[0]
+			} else {
+				rule[part.Name] = vals
+			}
+		}
+		return append(out, rule)
+	}
+
+	for _, value := range valuetype.Split(p, vcard) {
+		if !valuetype.IsStructured(p.Name, vcard) {
+			out = append(out, scalar(typ, value[0][0]))
+			continue
+		}
+		comps := make([]interface{}, len(value))
+		for i, elems := range value {
+			if len(elems) == 1 {
+				comps[i] = scalar(typ, elems[0])
+				continue
+			}
+			list := make([]interface{}, len(elems))
+			for j, e := range elems {
+				list[j] = scalar(typ, e)
+			}
+			comps[i] = list
+		}
+		out = append(out, comps)
+	}
+	return out
+}
+
+//scalar converts a single value into its JSON representation.
+func scalar(typ, s string) interface{} {
+	switch typ {
+	case valuetype.Integer:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case valuetype.Float:
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(strconv.FormatFloat(n, 'f', -1, 64))
+		}
+	case valuetype.Boolean:
+		switch strings.ToUpper(s) {
+		case "TRUE":
+			return true
+		case "FALSE":
+			return false
+		}
+	}
+	return valuetype.Extend(typ, s)
+}
+
+func fromJSON(v interface{}, vcard bool) (*go_contentline.Component, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) < 2 || len(arr) > 3 {
+		return nil, errors.New("invalid jCal: expected an array of name, properties and components")
+	}
+	name, ok := arr[0].(string)
+	if !ok {
+		return nil, errors.New("invalid jCal: the component name must be a string")
+	}
+	c := &go_contentline.Component{Name: strings.ToUpper(name)}
+	vcard = vcard || c.Name == "VCARD"
+	props, ok := arr[1].([]interface{})
+	if !ok {
+		return nil, errors.Errorf("invalid jCal: the properties of %s must be an array", c.Name)
+	}
+	for _, pv := range props {
+		p, err := propertyFromJSON(pv, vcard)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid jCal in %s", c.Name)
+		}
+		c.AddProperty(p)
+	}
+	if len(arr) == 3 {
+		comps, ok := arr[2].([]interface{})
+		if !ok {
+			return nil, errors.Errorf("invalid jCal: the components of %s must be an array", c.Name)
+		}
+		for _, cv := range comps {
+			sub, err := fromJSON(cv, vcard)
+			if err != nil {
+				return nil, err
+			}
+			c.AddComponent(sub)
+		}
+	}
+	return c, nil
+}
+
+func propertyFromJSON(v interface{}, vcard bool) (*go_contentline.Property, error) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) < 4 {
+		return nil, errors.New("expected an array of name, parameters, type and values")
+	}
+	name, ok1 := arr[0].(string)
+	params, ok2 := arr[1].(map[string]interface{})
+	typ, ok3 := arr[2].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, errors.New("expected an array of name, parameters, type and values")
+	}
+	p := go_contentline.NewPropertyUnchecked(strings.ToUpper(name), "", make(go_contentline.Parameters))
+	for k, pv := range params {
+		vals, err := stringValues(pv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parameter %s of %s", k, p.Name)
+		}
+		if vcard && strings.EqualFold(k, "group") && len(vals) == 1 {
+			p.Group = vals[0]
+			continue
+		}
+		p.SetParameter(k, vals...)
+	}
+	typ = strings.ToLower(typ)
+	if typ != valuetype.Unknown && typ != valuetype.Default(p.Name, vcard) {
+		if vcard {
+			p.SetParameter("VALUE", typ)
+		} else {
+			p.SetParameter("VALUE", strings.ToUpper(typ))
+		}
+	}
+
+	if typ == valuetype.Recur {
+		rule, ok := arr[3].(map[string]interface{})
+		if !ok || len(arr) != 4 {
+			return nil, errors.Errorf("%s: expected a single object for a recurrence rule", p.Name)
+		}
+		var parts []valuetype.RecurPart
+		for k, rv := range rule {
+			vals, err := stringValues(rv)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s: rule part %s", p.Name, k)
+			}
+			parts = append(parts, valuetype.RecurPart{Name: strings.ToLower(k), Values: vals})
+		}
+		p.Value = valuetype.JoinRecur(parts)
+		return p, nil
+	}
+
+	var values [][][]string
+	for _, vv := range arr[3:] {
+		var components [][]string
+		comps, structured := vv.([]interface{})
+		if !structured {
+			comps = []interface{}{vv}
+		}
+		for _, cv := range comps {
+			elems, err := stringValues(cv)
+			if err != nil {
+				return nil, errors.Wrap(err, p.Name)
+			}
+			for i := range elems {
+				elems[i] = valuetype.Basic(typ, elems[i])
+			}
+			components = append(components, elems)
+		}
+		values = append(values, components)
+	}
+	p.Value = valuetype.Join(values, typ)
+	return p, nil
+}
+
+//stringValues converts a JSON scalar or an array of them into strings.
+func stringValues(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case json.Number:
+		return []string{v.String()}, nil
+	case bool:
+		return []string{strings.ToUpper(strconv.FormatBool(v))}, nil
+	case []interface{}:
+		var out []string
+		for _, e := range v {
+			s, err := stringValues(e)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s...)
+		}
+		return out, nil
+	}
+	return nil, errors.Errorf("unexpected value %v", v)
+}
@@ -0,0 +1,106 @@
+package jcal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mqus/go-contentline"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Test//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20200115T100000\r\n" +
+	"DTSTAMP:20200101T000000Z\r\n" +
+	"SUMMARY:Meeting\\, with comma\r\n" +
+	"SEQUENCE:2\r\n" +
+	"GEO:37.386013;-122.082932\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=5;BYDAY=MO,WE\r\n" +
+	"CATEGORIES:A,B\r\n" +
+	"X-CUSTOM:some value\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+const testCard = "BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Jane Doe\r\n" +
+	"N:Doe;Jane;;;\r\n" +
+	"ITEM1.EMAIL;TYPE=work:jane@example.com\r\n" +
+	"BDAY:19850412\r\n" +
+	"END:VCARD\r\n"
+
+func parse(t *testing.T, s string) *go_contentline.Component {
+	c, err := go_contentline.InitParser(strings.NewReader(s)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func encode(c *go_contentline.Component) string {
+	var buf bytes.Buffer
+	c.Encode(&buf)
+	return buf.String()
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := Marshal(parse(t, testCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`["dtstart",{"tzid":"Europe/Berlin"},"date-time","2020-01-15T10:00:00"]`,
+		`["summary",{},"text","Meeting, with comma"]`,
+		`["sequence",{},"integer",2]`,
+		`["geo",{},"float",[37.386013,-122.082932]]`,
+		`["rrule",{},"recur",{"byday":["MO","WE"],"count":5,"freq":"WEEKLY"}]`,
+		`["categories",{},"text","A","B"]`,
+		`["x-custom",{},"unknown","some value"]`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("missing %s in %s", want, data)
+		}
+	}
+
+	data, err = Marshal(parse(t, testCard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`["vcard",[`,
+		`["n",{},"text",["Doe","Jane","","",""]]`,
+		`["email",{"group":"item1","type":"work"},"text","jane@example.com"]`,
+		`["bday",{},"date-and-or-time","1985-04-12"]`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("missing %s in %s", want, data)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, in := range []string{testCalendar, testCard} {
+		c := parse(t, in)
+		data, err := MarshalAll([]*go_contentline.Component{c, c})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Unmarshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 2 {
+			t.Fatalf("expected 2 components, got %d", len(out))
+		}
+		if got, want := encode(out[1]), encode(c); got != want {
+			t.Errorf("Wanted\n%s\ngot\n%s", want, got)
+		}
+	}
+
+	if _, err := Unmarshal([]byte(`["vcalendar",{}]`)); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,202 @@
+package go_contentline
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"strings"
+	"unicode"
+)
+
+//The versions of iCalendar and vCard, as given by their VERSION property. vCard 2.1 and 3.0 have a syntax differing
+// from RFC6350, see EncoderOptions.
+const (
+	ICalendar20 = "2.0"
+	VCard21     = "2.1"
+	VCard30     = "3.0"
+	VCard40     = "4.0"
+)
+
+//legacyParamNames maps the values which vCard 2.1 allows without parameter name to the name of their parameter.
+// All other values without name are TYPE values.
+var legacyParamNames = map[string]string{
+	"7BIT":             "ENCODING",
+	"8BIT":             "ENCODING",
+	"QUOTED-PRINTABLE": "ENCODING",
+	"BASE64":           "ENCODING",
+	"B":                "ENCODING",
+	"INLINE":           "VALUE",
+	"URL":              "VALUE",
+	"CONTENT-ID":       "VALUE",
+	"CID":              "VALUE",
+}
+
+//binaryProperties are the properties whose base64 encoded values are binary data instead of text.
+var binaryProperties = map[string]bool{"PHOTO": true, "LOGO": true, "SOUND": true, "KEY": true, "ATTACH": true}
+
+//bareParamName returns the parameter name for a parameter value given without name.
+func bareParamName(val string) string {
+	if name, ok := legacyParamNames[val]; ok {
+		return name
+	}
+	return "TYPE"
+}
+
+//isQuotedPrintable returns whether the parameters of the (unparsed) content line contain the value
+// QUOTED-PRINTABLE, either as ENCODING or without name.
+func isQuotedPrintable(line string) bool {
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.Contains(strings.ToUpper(line), "QUOTED-PRINTABLE")
+}
+
+//decodeLegacyValue decodes values encoded with ENCODING=QUOTED-PRINTABLE, ENCODING=BASE64/b and/or a CHARSET to
+// UTF-8 and removes these parameters. Line breaks in the decoded text are escaped as '\n'. Values of binary properties
+// stay base64 encoded and only lose their whitespace. If the input was transcoded, values which aren't encoded
+// already are UTF-8.
+func decodeLegacyValue(p *Property, transcoded bool) error {
+	charset := p.Parameters.Get("CHARSET")
+	var raw []byte
+	switch strings.ToUpper(p.Parameters.Get("ENCODING")) {
+	case "QUOTED-PRINTABLE":
+		var err error
+		if raw, err = ioutil.ReadAll(quotedprintable.NewReader(strings.NewReader(p.Value))); err != nil {
+			return err
+		}
+	case "BASE64", "B":
+		value := strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, p.Value)
+		if binaryProperties[p.Name] {
+			p.Value = value
+			return nil
+		}
+		var err error
+		if raw, err = base64.StdEncoding.DecodeString(value); err != nil {
+			return err
+		}
+	default:
+		if charset == "" {
+			return nil
+		}
+		if transcoded {
+			delete(p.Parameters, "CHARSET")
+			return nil
+		}
+		raw = []byte(p.Value)
+	}
+	s, err := decodeCharset(charset, raw)
+	if err != nil {
+		return err
+	}
+	p.Value = strings.NewReplacer("\r\n", "\\n", "\n", "\\n", "\r", "\\n").Replace(s)
+	delete(p.Parameters, "ENCODING")
+	delete(p.Parameters, "CHARSET")
+	return nil
+}
+
+//encodeLegacy encodes the property in the syntax of vCard 2.1 or 3.0.
+func (p *Property) encodeLegacy(w io.Writer, version string) {
+	out := p.fullName()
+	encoding := strings.ToUpper(p.Parameters.Get("ENCODING"))
+	isBase64 := encoding == "B" || encoding == "BASE64"
+
+	for _, k := range p.Parameters.names() {
+		name := strings.ToUpper(k)
+		vals := p.Parameters[k]
+		if version == VCard21 {
+			//vCard 2.1 has neither lists nor quoting, ENCODING and CHARSET are written with the value
+			if name == "ENCODING" || name == "CHARSET" {
+				continue
+			}
+			for _, v := range vals {
+				if name == "TYPE" {
+					out += ";" + legacyParamVal(v)
+				} else {
+					out += ";" + name + "=" + legacyParamVal(v)
+				}
+			}
+			continue
+		}
+		out += ";" + name + "="
+		for i, v := range vals {
+			if i > 0 {
+				out += ","
+			}
+			if name == "ENCODING" && isBase64 {
+				v = "b"
+			}
+			//vCard 3.0 does not know the escaping of RFC6868, so double quotes can't be represented
+			v = strings.Replace(v, "\"", "", -1)
+			if strings.ContainsAny(v, ",;:") {
+				v = "\"" + v + "\""
+			}
+			out += v
+		}
+	}
+	if version != VCard21 {
+		writeFolded(w, out+":"+p.Value)
+		return
+	}
+
+	switch {
+	case isBase64:
+		//base64 values are written on indented lines, followed by a blank line
+		io.WriteString(w, out+";ENCODING=BASE64:\r\n")
+		for v := p.Value; len(v) > 0; {
+			n := foldingLength - 1
+			if n > len(v) {
+				n = len(v)
+			}
+			io.WriteString(w, " "+v[:n]+"\r\n")
+			v = v[n:]
+		}
+		io.WriteString(w, "\r\n")
+	case needsQuotedPrintable(out, p.Value):
+		//vCard 2.1 folding keeps the whitespace, so long lines and line breaks are only possible with soft line breaks
+		// of quoted-printable values
+		var buf bytes.Buffer
+		qp := quotedprintable.NewWriter(&buf)
+		qp.Binary = true
+		io.WriteString(qp, legacyText(p.Value))
+		qp.Close()
+		io.WriteString(w, out+";CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:"+buf.String()+"\r\n")
+	default:
+		io.WriteString(w, out+":"+legacyText(p.Value)+"\r\n")
+	}
+}
+
+//needsQuotedPrintable returns whether the value has to be encoded as quoted-printable in vCard 2.1: if it contains
+// non-ASCII characters or line breaks or if the line would have to be folded.
+func needsQuotedPrintable(prefix, value string) bool {
+	if len(prefix)+1+len(value) > foldingLength || strings.Contains(value, "\\n") || strings.Contains(value, "\\N") {
+		return true
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+//legacyText converts a TEXT value to vCard 2.1, which does not escape commas and contains real line breaks.
+func legacyText(value string) string {
+	return strings.NewReplacer("\\\\", "\\\\", "\\n", "\r\n", "\\N", "\r\n", "\\,", ",").Replace(value)
+}
+
+//legacyParamVal removes the characters which can't be part of a vCard 2.1 parameter value.
+func legacyParamVal(v string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(";:,\"\r\n", r) {
+			return -1
+		}
+		return r
+	}, v)
+}
@@ -0,0 +1,126 @@
+package go_contentline
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testVCard21 = "BEGIN:VCARD\r\n" +
+	"VERSION:2.1\r\n" +
+	"N;CHARSET=ISO-8859-1:M\xfcller;J\xfcrgen\r\n" +
+	"TEL;CELL;VOICE:+49 170 1234567\r\n" +
+	"ADR;HOME;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:;;Hauptstra=C3=9Fe 1=0D=0A=\r\n" +
+	"Hinterhaus;Berlin;;10115\r\n" +
+	"NOTE;QUOTED-PRINTABLE;CHARSET=WINDOWS-1252:=80 100\r\n" +
+	"LABEL;BASE64:TGluZSAxCkxpbmUgMg==\r\n" +
+	"PHOTO;ENCODING=BASE64;TYPE=GIF:\r\n" +
+	"    R0lGODdhAQABAIAAAP///wAAACwAAAAAAQABAAACAkQBADs=\r\n" +
+	"\r\n" +
+	"END:VCARD\r\n"
+
+func TestParser_Legacy(t *testing.T) {
+	p := InitParserWithOptions(strings.NewReader(testVCard21), ParserOptions{Legacy: true})
+	c, err := p.ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name, value string
+		params      Parameters
+	}{
+		{"VERSION", "2.1", Parameters{}},
+		{"N", "Müller;Jürgen", Parameters{}},
+		{"TEL", "+49 170 1234567", Parameters{"TYPE": {"CELL", "VOICE"}}},
+		{"ADR", ";;Hauptstraße 1\\nHinterhaus;Berlin;;10115", Parameters{"TYPE": {"HOME"}}},
+		{"NOTE", "€ 100", Parameters{}},
+		{"LABEL", "Line 1\\nLine 2", Parameters{}},
+		{"PHOTO", "R0lGODdhAQABAIAAAP///wAAACwAAAAAAQABAAACAkQBADs=", Parameters{"ENCODING": {"BASE64"}, "TYPE": {"GIF"}}},
+	}
+	if len(c.Properties) != len(want) {
+		t.Fatalf("expected %d properties, got %d", len(want), len(c.Properties))
+	}
+	for i, w := range want {
+		got := c.Properties[i]
+		if got.Name != w.name || got.Value != w.value || !reflect.DeepEqual(got.Parameters, w.params) {
+			t.Errorf("Wanted: %s;%v:%s\nGot:    %s;%v:%s", w.name, w.params, w.value, got.Name, got.Parameters, got.Value)
+		}
+	}
+
+	//without legacy mode, parameters without name are an error
+	if _, err = InitParser(strings.NewReader(testVCard21)).ParseNextObject(); err == nil {
+		t.Error("expected an error without legacy mode")
+	}
+}
+
+func TestProperty_EncodeWithOptions(t *testing.T) {
+	checks := []struct {
+		prop        *Property
+		version     string
+		want        string
+		wantDecoded string
+	}{
+		{NewPropertyUnchecked("TEL", "+49 170 1234567", Parameters{"TYPE": {"CELL", "VOICE"}}), VCard21,
+			"TEL;CELL;VOICE:+49 170 1234567\r\n", ""},
+		{NewPropertyUnchecked("TEL", "+49 170 1234567", Parameters{"TYPE": {"CELL", "VOICE"}}), VCard30,
+			"TEL;TYPE=CELL,VOICE:+49 170 1234567\r\n", ""},
+		{NewPropertyUnchecked("NOTE", "Grüße\\nvon uns\\, allen", Parameters{}), VCard21,
+			"NOTE;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:Gr=C3=BC=C3=9Fe=0D=0Avon uns, allen\r\n", "Grüße\\nvon uns, allen"},
+		{NewPropertyUnchecked("NOTE", "x", Parameters{"X-Q": {"say \"hi\"; ^^"}}), VCard30,
+			"NOTE;X-Q=\"say hi; ^^\":x\r\n", ""},
+		{NewPropertyUnchecked("PHOTO", strings.Repeat("R0lG", 20), Parameters{"ENCODING": {"b"}}), VCard21,
+			"PHOTO;ENCODING=BASE64:\r\n " + strings.Repeat("R0lG", 18) + "R0\r\n lGR0lG\r\n\r\n", ""},
+		{NewPropertyUnchecked("PHOTO", "R0lG", Parameters{"ENCODING": {"BASE64"}}), VCard30,
+			"PHOTO;ENCODING=b:R0lG\r\n", ""},
+	}
+	for _, check := range checks {
+		var buf bytes.Buffer
+		check.prop.EncodeWithOptions(&buf, EncoderOptions{VCardVersion: check.version})
+		if got := buf.String(); got != check.want {
+			t.Errorf("Wanted:\n%q\nGot:\n%q", check.want, got)
+		}
+
+		//parse the result again
+		in := "BEGIN:VCARD\r\n" + buf.String() + "END:VCARD\r\n"
+		c, err := InitParserWithOptions(strings.NewReader(in), ParserOptions{Legacy: true}).ParseNextObject()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		want := check.prop.Value
+		if check.wantDecoded != "" {
+			want = check.wantDecoded
+		}
+		if got := c.Properties[0].Value; got != want {
+			t.Errorf("Wanted %q after parsing, got %q", want, got)
+		}
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	checks := map[string]string{
+		"ISO-8859-1":   "K\xf6ln \x80",
+		"windows-1252": "K\xf6ln \x80",
+		"utf-8":        "Köln",
+	}
+	want := map[string]string{
+		"ISO-8859-1":   "Köln \u0080",
+		"windows-1252": "Köln €",
+		"utf-8":        "Köln",
+	}
+	for charset, in := range checks {
+		got, err := decodeCharset(charset, []byte(in))
+		if err != nil || got != want[charset] {
+			t.Errorf("%s: Wanted %q, got %q (%v)", charset, want[charset], got, err)
+		}
+	}
+	for _, charset := range []string{"utf-8", ""} {
+		if _, err := decodeCharset(charset, []byte("K\xf6ln")); err == nil || err.Error() != "invalid UTF-8" {
+			t.Errorf("%q: expected an error for invalid UTF-8, got %v", charset, err)
+		}
+	}
+	if _, err := decodeCharset("KOI8-R", nil); err == nil {
+		t.Error("expected an error for an unsupported charset")
+	}
+}
@@ -40,6 +40,7 @@ const (
 	itemBegin                      // an indicator for the start of a component
 	itemEnd                        // an indicator for the end of a component
 	itemCompName                   // the component name
+	itemBareParam                  // a parameter value without name, only allowed in legacy mode (vCard 2.1)
 	//itemField      // alphanumeric identifier starting with '.'
 	//itemIdentifier // alphanumeric identifier not starting with '.'
 	//itemLeftDelim  // left action delimiter
@@ -76,12 +77,13 @@ const (
 )
 
 type lexer struct {
-	line  int       // documented for error messages
-	input string    // the string being scanned
-	pos   pos       // current position in the input
-	start pos       // start position of this item
-	width pos       // width of last rune read from input
-	items chan item // channel of scanned items
+	line   int       // documented for error messages
+	input  string    // the string being scanned
+	pos    pos       // current position in the input
+	start  pos       // start position of this item
+	width  pos       // width of last rune read from input
+	items  chan item // channel of scanned items
+	legacy bool      // accept parameters without name
 }
 
 type stateFn func(*lexer) stateFn
@@ -182,11 +184,12 @@ func (l *lexer) drain() {
 }
 
 // lex creates a new scanner for the input string.
-func lex(line int, input string) *lexer {
+func lex(line int, input string, legacy bool) *lexer {
 	l := &lexer{
-		input: input,
-		items: make(chan item),
-		line:  line,
+		input:  input,
+		items:  make(chan item),
+		line:   line,
+		legacy: legacy,
 	}
 	go l.run()
 	return l
@@ -202,12 +205,21 @@ func (l *lexer) run() {
 
 //state functions
 
-// lexPropName scans until a colon or a semicolon
+// lexPropName scans until a colon or a semicolon. The name can be preceded by a group (see RFC6350, Section 3.3).
 func lexPropName(l *lexer) stateFn {
 	l.acceptRun(parName)
 	if l.pos == l.start {
 		return l.errorf("expected one or more alphanumerical characters or '-'")
 	}
+	if l.accept(".") {
+		start := l.pos
+		l.acceptRun(parName)
+		if l.pos == start {
+			return l.errorf("expected one or more alphanumerical characters or '-' after the group")
+		}
+		l.emit(itemId)
+		return lexBeforeValue
+	}
 	if strings.ToUpper(l.input[l.start:l.pos]) == sBEGIN {
 		l.emit(itemBegin)
 		return lexBeforeCompName
@@ -258,6 +270,11 @@ func lexParamName(l *lexer) stateFn {
 	if l.pos == l.start {
 		return l.errorf("name must not be empty")
 	}
+	if l.legacy && l.peek() != '=' {
+		//vCard 2.1 allows parameters without name, e.g. TEL;CELL:...
+		l.emit(itemBareParam)
+		return lexAfterParamValue
+	}
 	l.emit(itemId)
 	if l.accept("=") {
 		l.ignore() //l.emit(itemEquals)
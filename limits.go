@@ -0,0 +1,91 @@
+package go_contentline
+
+import "fmt"
+
+//Limits restrict the size of the input a Parser accepts, e.g. for untrusted uploads. A zero value means no limit,
+// except for MaxDepth, which defaults to DefaultMaxDepth. If a limit is exceeded, ParseNextObject returns a *ParseError whose cause (see github.com/pkg/errors.Cause) is the
+// error type of that limit.
+type Limits struct {
+	//MaxLineLength is the maximal length of an unfolded content line in bytes, see LineLengthError. Values streamed to
+	// a BinaryHandler are not limited.
+	MaxLineLength int
+	//MaxDepth is the maximal nesting depth of components, the object itself has the depth 1. See DepthError. If it
+	// is zero, DefaultMaxDepth is used, as the components are parsed recursively.
+	MaxDepth int
+	//MaxProperties is the maximal number of properties of a single component, see PropertyCountError.
+	MaxProperties int
+	//MaxParameters is the maximal number of parameters of a single property, see ParameterCountError.
+	MaxParameters int
+	//MaxComponents is the maximal number of components (including subcomponents) read by the Parser, in total over
+	// all objects. See ComponentCountError.
+	MaxComponents int
+}
+
+//DefaultMaxDepth is the maximal nesting depth of components if Limits.MaxDepth is zero.
+const DefaultMaxDepth = 1000
+
+//LineLengthError is the cause of the error returned if a content line is longer than Limits.MaxLineLength.
+type LineLengthError struct {
+	Max int
+}
+
+func (e *LineLengthError) Error() string {
+	return fmt.Sprintf("content line longer than %d bytes", e.Max)
+}
+
+//DepthError is the cause of the error returned if components are nested deeper than Limits.MaxDepth.
+type DepthError struct {
+	Max int
+}
+
+func (e *DepthError) Error() string {
+	return fmt.Sprintf("components nested deeper than %d levels", e.Max)
+}
+
+//PropertyCountError is the cause of the error returned if a component has more than Limits.MaxProperties
+// properties.
+type PropertyCountError struct {
+	Component string
+	Max       int
+}
+
+func (e *PropertyCountError) Error() string {
+	return fmt.Sprintf("more than %d properties in %s", e.Max, e.Component)
+}
+
+//ParameterCountError is the cause of the error returned if a property has more than Limits.MaxParameters
+// parameters.
+type ParameterCountError struct {
+	Property string
+	Max      int
+}
+
+func (e *ParameterCountError) Error() string {
+	return fmt.Sprintf("more than %d parameters in %s", e.Max, e.Property)
+}
+
+//ComponentCountError is the cause of the error returned if the input has more than Limits.MaxComponents
+// components.
+type ComponentCountError struct {
+	Max int
+}
+
+func (e *ComponentCountError) Error() string {
+	return fmt.Sprintf("more than %d components", e.Max)
+}
+
+//maxDepth returns the maximal nesting depth of components, see Limits.MaxDepth.
+func (p *Parser) maxDepth() int {
+	if max := p.opts.Limits.MaxDepth; max > 0 {
+		return max
+	}
+	return DefaultMaxDepth
+}
+
+//checkLength returns a *LineLengthError if a line of n bytes is too long.
+func (p *Parser) checkLength(n int) error {
+	if max := p.opts.Limits.MaxLineLength; max > 0 && n > max {
+		return &LineLengthError{max}
+	}
+	return nil
+}
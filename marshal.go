@@ -0,0 +1,574 @@
+package go_contentline
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//ComponentName is the type of the struct field which holds the name of the component, like xml.Name in
+// encoding/xml. The tag of the field is the default name for Marshal and the expected name for Unmarshal:
+//  type Event struct {
+//  	Name    ComponentName `ical:"VEVENT"`
+//  	Summary string        `ical:"SUMMARY"`
+//  }
+type ComponentName string
+
+//PropertyMarshaler is implemented by types which can marshal themselves into a property.
+// The name of the returned property is replaced by the name in the tag of the field.
+type PropertyMarshaler interface {
+	MarshalProperty() (*Property, error)
+}
+
+//PropertyUnmarshaler is implemented by types which can unmarshal a property into themselves.
+type PropertyUnmarshaler interface {
+	UnmarshalProperty(p *Property) error
+}
+
+var (
+	componentNameType = reflect.TypeOf(ComponentName(""))
+	componentType     = reflect.TypeOf(&Component{})
+	propertyType      = reflect.TypeOf(&Property{})
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	marshalerType     = reflect.TypeOf((*PropertyMarshaler)(nil)).Elem()
+	unmarshalerType   = reflect.TypeOf((*PropertyUnmarshaler)(nil)).Elem()
+)
+
+//structField describes how a field of a struct is mapped by Marshal and Unmarshal.
+type structField struct {
+	index []int
+	//name is the name of the property or subcomponent
+	name string
+	//param is the name of the parameter, if the field holds a parameter of the property
+	param     string
+	omitEmpty bool
+	raw       bool
+	list      bool
+	date      bool
+	extra     bool
+	//component is true if the field holds subcomponents
+	component bool
+	//isName is true if the field holds the name of the component
+	isName bool
+}
+
+//Marshal returns the component for a struct, which works like encoding/json: the fields are mapped onto properties,
+// parameters and subcomponents by their tags (the key is "ical"). Fields without tag are ignored, the fields of
+// embedded structs without tag are treated like fields of the outer struct. The tag options are:
+//  `ical:"SUMMARY"`                    the value of the first SUMMARY property
+//  `ical:"CATEGORIES,list"`            a slice, written as comma-separated values of one property
+//  `ical:"ATTENDEE"`                   a slice, one property per element
+//  `ical:"DTSTART,param=TZID"`         the TZID parameter of the first DTSTART property (string or []string)
+//  `ical:"VALARM"`                     a struct, a pointer to a struct or a slice of these as subcomponents
+//  `ical:"DUE,omitempty"`              leaves the property out if the field has its zero value
+//  `ical:"DTSTART,date"`               writes a time.Time as DATE instead of DATE-TIME
+//  `ical:"URL,raw"`                    a string which is written without escaping (TEXT values are escaped)
+//  `ical:",extra"`                     a []*Property or []*Component with everything no other field maps
+// Supported value types are string, bool, integers, floats, time.Time, time.Duration, *Property and types which
+// implement PropertyMarshaler, as well as pointers and slices of these. Subcomponents can also be *Component.
+// The name of the component is taken from the field with the type ComponentName.
+func Marshal(v interface{}) (*Component, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.Errorf("cannot marshal %T, expected a struct", v)
+	}
+	c, err := marshalStruct(rv, "")
+	if err == nil && c.Name == "" {
+		err = errors.Errorf("cannot marshal %T: no component name, add a field of the type ComponentName", v)
+	}
+	return c, err
+}
+
+//Unmarshal stores the content of a component in the struct v points to, see Marshal for the mapping. Properties
+// and subcomponents which are not mapped by any field are ignored unless there is a field with the option extra.
+// TZID parameters are resolved using DefaultTZResolver.
+func Unmarshal(c *Component, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("cannot unmarshal into %T, expected a non-nil pointer to a struct", v)
+	}
+	return unmarshalStruct(c, rv.Elem())
+}
+
+//structFields returns the mapped fields of a struct type, including those of embedded structs.
+func structFields(t reflect.Type, index []int) ([]structField, error) {
+	var out []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup("ical")
+		idx := append(append([]int(nil), index...), i)
+		if !tagged && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			fields, err := structFields(sf.Type, idx)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, fields...)
+			continue
+		}
+		if !tagged && sf.Type == componentNameType {
+			tagged = true
+		}
+		if !tagged || tag == "-" || sf.PkgPath != "" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		f := structField{index: idx, name: strings.ToUpper(opts[0])}
+		for _, opt := range opts[1:] {
+			switch {
+			case opt == "omitempty":
+				f.omitEmpty = true
+			case opt == "raw":
+				f.raw = true
+			case opt == "list":
+				f.list = true
+			case opt == "date":
+				f.date = true
+			case opt == "extra":
+				f.extra = true
+			case strings.HasPrefix(opt, "param="):
+				f.param = strings.ToUpper(strings.TrimPrefix(opt, "param="))
+			default:
+				return nil, errors.Errorf("field %s: unknown tag option %q", sf.Name, opt)
+			}
+		}
+		switch {
+		case sf.Type == componentNameType:
+			f.isName = true
+		case f.extra:
+			if sf.Type != reflect.SliceOf(propertyType) && sf.Type != reflect.SliceOf(componentType) {
+				return nil, errors.Errorf("field %s: extra needs []*Property or []*Component", sf.Name)
+			}
+		case f.name == "":
+			return nil, errors.Errorf("field %s: missing name in tag", sf.Name)
+		case f.param != "":
+			if sf.Type.Kind() != reflect.String && sf.Type != reflect.TypeOf([]string(nil)) {
+				return nil, errors.Errorf("field %s: parameters need string or []string", sf.Name)
+			}
+		default:
+			f.component = isComponentType(sf.Type)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+//isComponentType reports whether a field of the type t holds subcomponents.
+func isComponentType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t == componentType {
+		return true
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType && !t.Implements(marshalerType) &&
+		!reflect.PtrTo(t).Implements(marshalerType) && !reflect.PtrTo(t).Implements(unmarshalerType)
+}
+
+func marshalStruct(rv reflect.Value, name string) (*Component, error) {
+	fields, err := structFields(rv.Type(), nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &Component{Name: name}
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+		switch {
+		case f.isName:
+			if fv.String() != "" {
+				c.Name = strings.ToUpper(fv.String())
+			} else if c.Name == "" {
+				c.Name = f.name
+			}
+		case f.param != "":
+		case f.extra:
+			for i := 0; i < fv.Len(); i++ {
+				if fv.Index(i).IsNil() {
+					continue
+				}
+				switch e := fv.Index(i).Interface().(type) {
+				case *Property:
+					c.AddProperty(e.Clone())
+				case *Component:
+					c.AddComponent(e.Clone())
+				}
+			}
+		case f.component:
+			comps, err := marshalComponents(fv, f.name)
+			if err != nil {
+				return nil, err
+			}
+			c.AddComponent(comps...)
+		default:
+			props, err := marshalProperties(fv, f)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not marshal %s", f.name)
+			}
+			c.AddProperty(props...)
+		}
+	}
+	//parameters are set after all values, so that they don't depend on the order of the fields
+	for _, f := range fields {
+		if f.param == "" {
+			continue
+		}
+		fv := rv.FieldByIndex(f.index)
+		var vals []string
+		if fv.Kind() == reflect.String {
+			if fv.String() != "" {
+				vals = []string{fv.String()}
+			}
+		} else {
+			vals = fv.Interface().([]string)
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		p := c.GetProperty(f.name)
+		if p == nil {
+			p = NewPropertyUnchecked(f.name, "", make(Parameters))
+			c.AddProperty(p)
+		}
+		p.SetParameter(f.param, vals...)
+	}
+	return c, nil
+}
+
+func marshalComponents(fv reflect.Value, name string) ([]*Component, error) {
+	if fv.Kind() == reflect.Slice {
+		var out []*Component
+		for i := 0; i < fv.Len(); i++ {
+			comps, err := marshalComponents(fv.Index(i), name)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, comps...)
+		}
+		return out, nil
+	}
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return nil, nil
+		}
+		if fv.Type() == componentType {
+			return []*Component{fv.Interface().(*Component).Clone()}, nil
+		}
+		fv = fv.Elem()
+	}
+	c, err := marshalStruct(fv, name)
+	if err != nil {
+		return nil, err
+	}
+	return []*Component{c}, nil
+}
+
+func marshalProperties(fv reflect.Value, f structField) ([]*Property, error) {
+	if f.omitEmpty && isEmptyValue(fv) {
+		return nil, nil
+	}
+	if fv.Kind() != reflect.Slice || fv.Type().Implements(marshalerType) {
+		p, err := marshalProperty(fv, f)
+		if p == nil || err != nil {
+			return nil, err
+		}
+		return []*Property{p}, nil
+	}
+	var out []*Property
+	for i := 0; i < fv.Len(); i++ {
+		p, err := marshalProperty(fv.Index(i), f)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			out = append(out, p)
+		}
+	}
+	if f.list && len(out) > 0 {
+		vals := make([]string, len(out))
+		for i, p := range out {
+			vals[i] = p.Value
+		}
+		out[0].Value = strings.Join(vals, ",")
+		out = out[:1]
+	}
+	return out, nil
+}
+
+//marshalProperty returns the property for a single value, or nil for a nil pointer.
+func marshalProperty(v reflect.Value, f structField) (*Property, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	if v.Type() == propertyType {
+		p := v.Interface().(*Property).Clone()
+		p.Name = f.name
+		return p, nil
+	}
+	if m, ok := marshaler(v); ok {
+		p, err := m.MarshalProperty()
+		if p != nil {
+			p.Name = f.name
+		}
+		return p, err
+	}
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	p := NewPropertyUnchecked(f.name, "", make(Parameters))
+	switch {
+	case v.Type() == timeType:
+		p.SetDateTime(v.Interface().(time.Time), f.date)
+	case v.Type() == durationType:
+		p.Value = FormatDuration(time.Duration(v.Int()))
+	case v.Kind() == reflect.String:
+		p.Value = v.String()
+		if !f.raw {
+			p.Value = EscapeText(p.Value)
+		}
+	case v.Kind() == reflect.Bool:
+		p.Value = strings.ToUpper(strconv.FormatBool(v.Bool()))
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		p.Value = strconv.FormatInt(v.Int(), 10)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		p.Value = strconv.FormatUint(v.Uint(), 10)
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		p.Value = strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	default:
+		return nil, errors.Errorf("unsupported type %s", v.Type())
+	}
+	return p, nil
+}
+
+func marshaler(v reflect.Value) (PropertyMarshaler, bool) {
+	if v.Type().Implements(marshalerType) {
+		return v.Interface().(PropertyMarshaler), true
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		return v.Addr().Interface().(PropertyMarshaler), true
+	}
+	return nil, false
+}
+
+//isEmptyValue reports whether v is empty in the sense of the omitempty option of encoding/json. Zero times are
+// empty as well.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return false
+}
+
+func unmarshalStruct(c *Component, rv reflect.Value) error {
+	fields, err := structFields(rv.Type(), nil)
+	if err != nil {
+		return err
+	}
+	usedProps := make(map[*Property]bool)
+	usedComps := make(map[*Component]bool)
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+		switch {
+		case f.isName:
+			if f.name != "" && !strings.EqualFold(f.name, c.Name) {
+				return errors.Errorf("expected %s, got %s", f.name, c.Name)
+			}
+			fv.SetString(c.Name)
+		case f.extra:
+		case f.param != "":
+			p := c.GetProperty(f.name)
+			if p == nil {
+				continue
+			}
+			var vals []string
+			for k, v := range p.Parameters {
+				if strings.EqualFold(k, f.param) {
+					vals = v
+				}
+			}
+			if fv.Kind() == reflect.String {
+				if len(vals) > 0 {
+					fv.SetString(vals[0])
+				}
+			} else {
+				fv.Set(reflect.ValueOf(append([]string(nil), vals...)))
+			}
+		case f.component:
+			comps := c.FindSubComponents(f.name)
+			if fv.Kind() != reflect.Slice && len(comps) > 1 {
+				comps = comps[:1]
+			}
+			for _, sub := range comps {
+				usedComps[sub] = true
+				if err := unmarshalComponent(sub, fv); err != nil {
+					return err
+				}
+			}
+		default:
+			props := c.FindProperties(f.name)
+			if (fv.Kind() != reflect.Slice || fv.Type().Implements(unmarshalerType)) && len(props) > 1 {
+				props = props[:1]
+			}
+			for _, p := range props {
+				usedProps[p] = true
+				if err := unmarshalProperties(p, fv, f); err != nil {
+					return errors.Wrapf(err, "could not unmarshal %s", p.Name)
+				}
+			}
+		}
+	}
+	for _, f := range fields {
+		if !f.extra {
+			continue
+		}
+		fv := rv.FieldByIndex(f.index)
+		if fv.Type().Elem() == propertyType {
+			for _, p := range c.Properties {
+				if !usedProps[p] {
+					fv.Set(reflect.Append(fv, reflect.ValueOf(p.Clone())))
+				}
+			}
+		} else {
+			for _, sub := range c.Comps {
+				if !usedComps[sub] {
+					fv.Set(reflect.Append(fv, reflect.ValueOf(sub.Clone())))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//unmarshalComponent stores sub in fv, which is appended to if it is a slice.
+func unmarshalComponent(sub *Component, fv reflect.Value) error {
+	if fv.Kind() == reflect.Slice {
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		if err := unmarshalComponent(sub, elem); err != nil {
+			return err
+		}
+		fv.Set(reflect.Append(fv, elem))
+		return nil
+	}
+	if fv.Type() == componentType {
+		fv.Set(reflect.ValueOf(sub.Clone()))
+		return nil
+	}
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+	return unmarshalStruct(sub, fv)
+}
+
+//unmarshalProperties stores the value of p in fv. Slices are appended to, with the option list once per value.
+func unmarshalProperties(p *Property, fv reflect.Value, f structField) error {
+	if fv.Kind() != reflect.Slice || fv.Type().Implements(unmarshalerType) {
+		return unmarshalProperty(p, fv, f)
+	}
+	values := []string{p.Value}
+	if f.list {
+		values = splitEscaped(p.Value, ',')
+	}
+	for _, value := range values {
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		if err := unmarshalProperty(&Property{p.Name, value, p.Parameters, p.olds, p.Group, p.pos}, elem, f); err != nil {
+			return err
+		}
+		fv.Set(reflect.Append(fv, elem))
+	}
+	return nil
+}
+
+func unmarshalProperty(p *Property, v reflect.Value, f structField) error {
+	if v.Type() == propertyType {
+		v.Set(reflect.ValueOf(p.Clone()))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if u, ok := v.Interface().(PropertyUnmarshaler); ok {
+			return u.UnmarshalProperty(p)
+		}
+		v = v.Elem()
+	}
+	if u, ok := v.Addr().Interface().(PropertyUnmarshaler); ok {
+		return u.UnmarshalProperty(p)
+	}
+	value := strings.TrimSpace(p.Value)
+	switch {
+	case v.Type() == timeType:
+		t, _, err := p.DateTime(nil)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+	case v.Type() == durationType:
+		d, err := ParseDuration(p.Value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		if f.raw {
+			v.SetString(p.Value)
+		} else {
+			v.SetString(UnescapeText(p.Value))
+		}
+	case v.Kind() == reflect.Bool:
+		switch strings.ToUpper(value) {
+		case "TRUE":
+			v.SetBool(true)
+		case "FALSE":
+			v.SetBool(false)
+		default:
+			return errors.Errorf("invalid BOOLEAN value %q", p.Value)
+		}
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return errors.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package go_contentline
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAlarm struct {
+	Name    ComponentName `ical:"VALARM"`
+	Action  string        `ical:"ACTION"`
+	Trigger time.Duration `ical:"TRIGGER"`
+}
+
+type testCommon struct {
+	UID      string `ical:"UID"`
+	Sequence int    `ical:"SEQUENCE,omitempty"`
+}
+
+type testStatus string
+
+func (s testStatus) MarshalProperty() (*Property, error) {
+	return NewPropertyUnchecked("", strings.ToUpper(string(s)), nil), nil
+}
+
+func (s *testStatus) UnmarshalProperty(p *Property) error {
+	*s = testStatus(strings.ToLower(p.Value))
+	return nil
+}
+
+type testEvent struct {
+	Name ComponentName `ical:"VEVENT"`
+	testCommon
+	Summary    string      `ical:"SUMMARY"`
+	Start      time.Time   `ical:"DTSTART"`
+	StartTZ    string      `ical:"DTSTART,param=TZID"`
+	End        *time.Time  `ical:"DTEND"`
+	Categories []string    `ical:"CATEGORIES,list"`
+	Attendees  []string    `ical:"ATTENDEE,raw"`
+	Status     testStatus  `ical:"STATUS"`
+	Priority   uint8       `ical:"PRIORITY,omitempty"`
+	Private    bool        `ical:"X-PRIVATE"`
+	Alarms     []testAlarm `ical:"VALARM"`
+	Ignored    string
+	Extra      []*Property  `ical:",extra"`
+	ExtraComps []*Component `ical:",extra"`
+}
+
+const testMarshalEvent = "BEGIN:VEVENT\r\n" +
+	"UID:1@example.com\r\n" +
+	"SUMMARY:Lunch\\, with friends\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20200115T120000\r\n" +
+	"CATEGORIES:FOOD,a\\,b\r\n" +
+	"ATTENDEE:mailto:a@example.com\r\n" +
+	"ATTENDEE:mailto:b@example.com\r\n" +
+	"STATUS:CONFIRMED\r\n" +
+	"X-PRIVATE:TRUE\r\n" +
+	"X-UNKNOWN:kept\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"END:VALARM\r\n" +
+	"BEGIN:X-OTHER\r\n" +
+	"END:X-OTHER\r\n" +
+	"END:VEVENT\r\n"
+
+func TestUnmarshal(t *testing.T) {
+	c, err := InitParser(strings.NewReader(testMarshalEvent)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e testEvent
+	if err := Unmarshal(c, &e); err != nil {
+		t.Fatal(err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	want := testEvent{
+		Name:       "VEVENT",
+		testCommon: testCommon{UID: "1@example.com"},
+		Summary:    "Lunch, with friends",
+		Start:      time.Date(2020, 1, 15, 12, 0, 0, 0, berlin),
+		StartTZ:    "Europe/Berlin",
+		Categories: []string{"FOOD", "a,b"},
+		Attendees:  []string{"mailto:a@example.com", "mailto:b@example.com"},
+		Status:     "confirmed",
+		Private:    true,
+		Alarms:     []testAlarm{{"VALARM", "DISPLAY", -15 * time.Minute}},
+	}
+	if len(e.Extra) != 1 || e.Extra[0].Name != "X-UNKNOWN" || len(e.ExtraComps) != 1 || e.ExtraComps[0].Name != "X-OTHER" {
+		t.Errorf("unexpected extra properties or components: %v, %v", e.Extra, e.ExtraComps)
+	}
+	e.Extra, e.ExtraComps = nil, nil
+	if !e.Start.Equal(want.Start) || e.Start.Location().String() != "Europe/Berlin" {
+		t.Errorf("unexpected DTSTART: %v", e.Start)
+	}
+	e.Start = want.Start
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("Wanted %+v, got %+v", want, e)
+	}
+
+	if err := Unmarshal(&Component{Name: "VTODO"}, &e); err == nil {
+		t.Error("expected an error for the wrong component name")
+	}
+	if err := Unmarshal(c, e); err == nil {
+		t.Error("expected an error for a non-pointer")
+	}
+	bad := &Component{Name: "VEVENT", Properties: []*Property{NewPropertyUnchecked("SEQUENCE", "one", nil)}}
+	if err := Unmarshal(bad, &e); err == nil {
+		t.Error("expected an error for an invalid integer")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	c, err := InitParser(strings.NewReader(testMarshalEvent)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e testEvent
+	if err := Unmarshal(c, &e); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Marshal(&e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	out.Encode(&buf)
+	if buf.String() != testMarshalEvent {
+		t.Errorf("Wanted %q, got %q", testMarshalEvent, buf.String())
+	}
+
+	end := time.Date(2020, 1, 15, 13, 0, 0, 0, time.UTC)
+	e = testEvent{testCommon: testCommon{UID: "2", Sequence: 3}, End: &end, Priority: 5, Status: "tentative"}
+	out, err = Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"UID:2", "SEQUENCE:3", "SUMMARY:", "DTSTART:00010101T000000Z", "DTEND:20200115T130000Z",
+		"STATUS:TENTATIVE", "PRIORITY:5", "X-PRIVATE:FALSE"}
+	if out.Name != "VEVENT" || !reflect.DeepEqual(describe(out), want) {
+		t.Errorf("Wanted %q, got %s %q", want, out.Name, describe(out))
+	}
+
+	//float32 values are formatted with their own precision
+	out, err = Marshal(struct {
+		Name ComponentName `ical:"X"`
+		F32  float32       `ical:"X-F32"`
+		F64  float64       `ical:"X-F64"`
+	}{F32: 0.1, F64: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(out); !reflect.DeepEqual(got, []string{"X-F32:0.1", "X-F64:0.1"}) {
+		t.Errorf("unexpected floats %q", got)
+	}
+
+	if _, err := Marshal(struct{ A string }{}); err == nil {
+		t.Error("expected an error for a missing component name")
+	}
+	if _, err := Marshal(struct {
+		Name ComponentName `ical:"X"`
+		A    []int         `ical:"A,bad"`
+	}{}); err == nil {
+		t.Error("expected an error for an unknown tag option")
+	}
+}
+
+//describe lists the properties of a component in the order they are stored.
+func describe(c *Component) []string {
+	var out []string
+	for _, p := range c.Properties {
+		out = append(out, p.Name+":"+p.Value)
+	}
+	return out
+}
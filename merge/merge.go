@@ -0,0 +1,367 @@
+//Package merge finds duplicates among calendar objects or vCards and merges them into one.
+//
+// Objects are duplicates if they have the same component name and share at least one key, e.g. the UID or an email
+// address. The properties of the duplicates are united: identical properties are only kept once and properties which
+// can only occur once are resolved by taking the one of the most recently modified object or by a callback.
+package merge
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+)
+
+//Key returns the keys of an object. Two objects are duplicates if they have at least one key in common.
+type Key func(c *go_contentline.Component) []string
+
+//ByUID uses the UID of an object, together with its RECURRENCE-ID, so that the overrides of a recurring event
+// are not merged into the master.
+func ByUID(c *go_contentline.Component) []string {
+	uid := c.GetProperty("UID")
+	if uid == nil || strings.TrimSpace(uid.Value) == "" {
+		return nil
+	}
+	key := strings.TrimSpace(uid.Value)
+	if rid := c.GetProperty("RECURRENCE-ID"); rid != nil {
+		key += "\x00" + rid.Value
+	}
+	return []string{key}
+}
+
+//ByEmail uses the EMAIL addresses of an object, compared case-insensitively and without mailto: prefix.
+func ByEmail(c *go_contentline.Component) []string {
+	var out []string
+	for _, p := range c.FindProperties("EMAIL") {
+		email := strings.ToLower(strings.TrimSpace(p.Value))
+		email = strings.TrimPrefix(email, "mailto:")
+		if email != "" {
+			out = append(out, email)
+		}
+	}
+	return out
+}
+
+var nonDigits = regexp.MustCompile(`[^0-9+]`)
+
+//ByPhone uses the TEL numbers of an object, compared by their digits (and a leading +) only.
+func ByPhone(c *go_contentline.Component) []string {
+	var out []string
+	for _, p := range c.FindProperties("TEL") {
+		tel := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(p.Value)), "tel:")
+		if i := strings.IndexByte(tel, ';'); i >= 0 {
+			tel = tel[:i]
+		}
+		tel = nonDigits.ReplaceAllString(tel, "")
+		if len(strings.TrimPrefix(tel, "+")) > 0 {
+			out = append(out, tel)
+		}
+	}
+	return out
+}
+
+//ByFormattedName uses the FN of an object, compared case-insensitively and with normalized white space.
+func ByFormattedName(c *go_contentline.Component) []string {
+	var out []string
+	for _, p := range c.FindProperties("FN") {
+		fn := strings.Join(strings.Fields(strings.ToLower(go_contentline.UnescapeText(p.Value))), " ")
+		if fn != "" {
+			out = append(out, fn)
+		}
+	}
+	return out
+}
+
+//SingleValued lists the properties which may occur only once in an object. Conflicting values of these properties
+// are resolved, all other properties are united.
+var SingleValued = map[string]bool{
+	//vCard
+	"VERSION": true, "FN": true, "N": true, "BDAY": true, "ANNIVERSARY": true, "GENDER": true, "KIND": true,
+	"PRODID": true, "REV": true, "UID": true, "SORT-STRING": true,
+	//iCalendar
+	"DTSTAMP": true, "DTSTART": true, "DTEND": true, "DUE": true, "DURATION": true, "SUMMARY": true,
+	"DESCRIPTION": true, "LOCATION": true, "STATUS": true, "CLASS": true, "PRIORITY": true, "SEQUENCE": true,
+	"CREATED": true, "LAST-MODIFIED": true, "RECURRENCE-ID": true, "ORGANIZER": true, "TRANSP": true, "URL": true,
+	"GEO": true, "PERCENT-COMPLETE": true, "COMPLETED": true, "RRULE": true,
+}
+
+//Options configures the matching and merging.
+type Options struct {
+	//Keys are used to find duplicates, ByUID is used if it is empty.
+	Keys []Key
+	//SingleValued overrides the package variable of the same name if it is not nil.
+	SingleValued map[string]bool
+	//Resolve chooses between two different values of a single-valued property: current is the one of the merged
+	// object so far, other the one of the next duplicate. It returns the property to keep, nil keeps current. If
+	// Resolve is nil, the property of the object with the most recent REV or LAST-MODIFIED is kept, current if they
+	// are equal.
+	Resolve func(current, other *go_contentline.Property) *go_contentline.Property
+}
+
+//Conflict describes a single-valued property with different values in two duplicates.
+type Conflict struct {
+	Kept    *go_contentline.Property
+	Dropped *go_contentline.Property
+}
+
+//Report describes the merge of a set of duplicates.
+type Report struct {
+	//Result is the merged object, as returned by Objects.
+	Result *go_contentline.Component
+	//Sources are the indexes of the duplicates in the input of Objects.
+	Sources []int
+	//Added are the properties which were added to the first duplicate.
+	Added []*go_contentline.Property
+	//Duplicates is the number of identical properties and subcomponents which were left out.
+	Duplicates int
+	//Conflicts lists the resolved conflicts of single-valued properties.
+	Conflicts []Conflict
+}
+
+//Objects merges the duplicates among objects. It returns the objects with all duplicates merged into the position
+// of the first one, and a report for every merge of at least two objects. The objects are not modified.
+func Objects(objects []*go_contentline.Component, opts Options) ([]*go_contentline.Component, []Report) {
+	keys := opts.Keys
+	if len(keys) == 0 {
+		keys = []Key{ByUID}
+	}
+	if opts.SingleValued == nil {
+		opts.SingleValued = SingleValued
+	}
+
+	//union-find over the indexes of the objects
+	parent := make([]int, len(objects))
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	owner := make(map[string]int)
+	for i, c := range objects {
+		parent[i] = i
+		for k, key := range keys {
+			for _, v := range key(c) {
+				v = c.Name + "\x00" + strconv.Itoa(k) + "\x00" + v
+				j, ok := owner[v]
+				if !ok {
+					owner[v] = i
+					continue
+				}
+				a, b := find(i), find(j)
+				if a < b {
+					a, b = b, a
+				}
+				parent[a] = b
+			}
+		}
+	}
+
+	groups := make(map[int][]int)
+	for i := range objects {
+		root := find(i)
+		groups[root] = append(groups[root], i)
+	}
+	var out []*go_contentline.Component
+	var reports []Report
+	for i := range objects {
+		group, ok := groups[i]
+		if !ok {
+			continue
+		}
+		if len(group) == 1 {
+			out = append(out, objects[i].Clone())
+			continue
+		}
+		r := Report{Result: objects[group[0]].Clone(), Sources: group}
+		for _, j := range group[1:] {
+			r.merge(objects[j], opts)
+		}
+		out = append(out, r.Result)
+		reports = append(reports, r)
+	}
+	return out, reports
+}
+
+//merge merges other into r.Result.
+func (r *Report) merge(other *go_contentline.Component, opts Options) {
+	c := r.Result
+	otherNewer := modified(other).After(modified(c))
+	groups := r.groupNames(other)
+	for _, p := range other.Properties {
+		p = p.Clone()
+		p.Group = groups[strings.ToUpper(p.Group)]
+		if contains(c.Properties, p) {
+			r.Duplicates++
+			continue
+		}
+		name := strings.ToUpper(p.Name)
+		if !opts.SingleValued[name] || p.Group != "" {
+			c.AddProperty(p)
+			r.Added = append(r.Added, p)
+			continue
+		}
+		i := index(c.Properties, name)
+		if i < 0 {
+			c.AddProperty(p)
+			r.Added = append(r.Added, p)
+			continue
+		}
+		current := c.Properties[i]
+		var kept *go_contentline.Property
+		switch {
+		case opts.Resolve != nil:
+			if kept = opts.Resolve(current, p); kept == nil {
+				kept = current
+			}
+		case otherNewer:
+			kept = p
+		default:
+			kept = current
+		}
+		dropped := p
+		if kept != current {
+			dropped = current
+			c.Properties[i] = kept
+		}
+		r.Conflicts = append(r.Conflicts, Conflict{kept, dropped})
+	}
+	for _, sub := range other.Comps {
+		if containsComponent(c.Comps, sub) {
+			r.Duplicates++
+			continue
+		}
+		c.AddComponent(sub.Clone())
+	}
+}
+
+//groupNames maps the property groups of other onto the groups to use in the merged object. Groups which also exist
+// in the merged object keep their name if all their properties are identical, otherwise they are renamed.
+func (r *Report) groupNames(other *go_contentline.Component) map[string]string {
+	used := make(map[string]bool)
+	for _, p := range r.Result.Properties {
+		used[strings.ToUpper(p.Group)] = true
+	}
+	names := map[string]string{"": ""}
+	for _, p := range other.Properties {
+		g := strings.ToUpper(p.Group)
+		if _, ok := names[g]; ok {
+			continue
+		}
+		names[g] = p.Group
+		if !used[g] {
+			continue
+		}
+		for _, q := range other.Properties {
+			if strings.EqualFold(q.Group, g) && !contains(r.Result.Properties, q) {
+				names[g] = freeGroup(used)
+				break
+			}
+		}
+	}
+	return names
+}
+
+//freeGroup returns an unused group name of the form itemN and marks it as used.
+func freeGroup(used map[string]bool) string {
+	for n := 1; ; n++ {
+		g := "item" + strconv.Itoa(n)
+		if !used[strings.ToUpper(g)] {
+			used[strings.ToUpper(g)] = true
+			return g
+		}
+	}
+}
+
+//modified returns the time of the last modification of an object, from REV or LAST-MODIFIED.
+func modified(c *go_contentline.Component) time.Time {
+	for _, name := range []string{"REV", "LAST-MODIFIED"} {
+		p := c.GetProperty(name)
+		if p == nil {
+			continue
+		}
+		v := strings.TrimSpace(p.Value)
+		if t, err := go_contentline.ParseDateTime(v, time.UTC); err == nil {
+			return t
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func index(props []*go_contentline.Property, name string) int {
+	for i, p := range props {
+		if strings.EqualFold(p.Name, name) && p.Group == "" {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(props []*go_contentline.Property, p *go_contentline.Property) bool {
+	for _, q := range props {
+		if Equal(p, q) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsComponent(comps []*go_contentline.Component, c *go_contentline.Component) bool {
+	for _, d := range comps {
+		if equalComponent(c, d) {
+			return true
+		}
+	}
+	return false
+}
+
+//Equal reports whether two properties are identical. Names, groups and parameters are compared
+// case-insensitively, the order of the parameter values does not matter.
+func Equal(a, b *go_contentline.Property) bool {
+	if !strings.EqualFold(a.Name, b.Name) || !strings.EqualFold(a.Group, b.Group) || a.Value != b.Value {
+		return false
+	}
+	return normalizeParams(a.Parameters) == normalizeParams(b.Parameters)
+}
+
+//normalizeParams returns a string which is equal for parameters which only differ in case and order.
+func normalizeParams(ps go_contentline.Parameters) string {
+	var out []string
+	for k, vals := range ps {
+		if len(vals) == 0 {
+			continue
+		}
+		lower := make([]string, len(vals))
+		for i, v := range vals {
+			lower[i] = strings.ToLower(v)
+		}
+		sort.Strings(lower)
+		out = append(out, strings.ToUpper(k)+"="+strings.Join(lower, "\x00"))
+	}
+	sort.Strings(out)
+	return strings.Join(out, "\x01")
+}
+
+func equalComponent(a, b *go_contentline.Component) bool {
+	if !strings.EqualFold(a.Name, b.Name) || len(a.Properties) != len(b.Properties) || len(a.Comps) != len(b.Comps) {
+		return false
+	}
+	for _, p := range a.Properties {
+		if !contains(b.Properties, p) {
+			return false
+		}
+	}
+	for _, sub := range a.Comps {
+		if !containsComponent(b.Comps, sub) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package merge
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mqus/go-contentline"
+)
+
+func parseAll(t *testing.T, in string) []*go_contentline.Component {
+	t.Helper()
+	p := go_contentline.InitParser(strings.NewReader(in))
+	var out []*go_contentline.Component
+	for {
+		c, err := p.ParseNextObject()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, c)
+	}
+}
+
+//lines returns the properties of a component as encoded lines, for comparisons which don't depend on the order.
+func lines(c *go_contentline.Component) map[string]bool {
+	out := make(map[string]bool)
+	for _, p := range c.Properties {
+		var buf bytes.Buffer
+		p.Encode(&buf)
+		out[strings.TrimSpace(buf.String())] = true
+	}
+	return out
+}
+
+const testCards = "BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Jane Doe\r\n" +
+	"EMAIL;TYPE=work:jane@example.com\r\n" +
+	"TEL:+1 555 0100\r\n" +
+	"REV:20200101T000000Z\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:John Smith\r\n" +
+	"EMAIL:john@example.com\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Jane M. Doe\r\n" +
+	"EMAIL;TYPE=WORK:Jane@Example.com\r\n" +
+	"EMAIL:jane@home.example\r\n" +
+	"item1.URL:https://jane.example\r\n" +
+	"REV:20210101T000000Z\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:J. Doe\r\n" +
+	"TEL;TYPE=cell:tel:+1-555-0100\r\n" +
+	"item1.X-ABLabel:Blog\r\n" +
+	"END:VCARD\r\n"
+
+func TestObjects_Cards(t *testing.T) {
+	cards := parseAll(t, testCards)
+	out, reports := Objects(cards, Options{Keys: []Key{ByEmail, ByPhone}})
+	if len(out) != 2 || len(reports) != 1 {
+		t.Fatalf("unexpected result: %d objects, %d reports", len(out), len(reports))
+	}
+	r := reports[0]
+	if r.Result != out[0] || len(r.Sources) != 3 || r.Sources[1] != 2 || r.Sources[2] != 3 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	want := map[string]bool{
+		"VERSION:4.0":                      true,
+		"FN:Jane M. Doe":                   true,
+		"EMAIL;TYPE=work:jane@example.com": true,
+		"EMAIL;TYPE=WORK:Jane@Example.com": true,
+		"EMAIL:jane@home.example":          true,
+		"TEL:+1 555 0100":                  true,
+		"TEL;TYPE=cell:tel:+1-555-0100":    true,
+		"ITEM1.URL:https://jane.example":   true,
+		"ITEM2.X-ABLABEL:Blog":             true,
+		"REV:20210101T000000Z":             true,
+	}
+	got := lines(out[0])
+	if len(got) != len(want) {
+		t.Errorf("Wanted %v, got %v", want, got)
+	}
+	for l := range want {
+		if !got[l] {
+			t.Errorf("missing %q in %v", l, got)
+		}
+	}
+	//FN and REV of the newer card won, the FN of the last card is older than the merged REV
+	if len(r.Conflicts) != 3 || r.Conflicts[0].Kept.Value != "Jane M. Doe" || r.Conflicts[2].Dropped.Value != "J. Doe" {
+		t.Errorf("unexpected conflicts: %+v", r.Conflicts)
+	}
+	if r.Duplicates != 2 {
+		t.Errorf("unexpected number of duplicates: %d", r.Duplicates)
+	}
+	if cards[0].GetProperty("FN").Value != "Jane Doe" {
+		t.Error("the input was modified")
+	}
+}
+
+const testEvents = "BEGIN:VEVENT\r\n" +
+	"UID:1@example.com\r\n" +
+	"SUMMARY:Old\r\n" +
+	"CATEGORIES:WORK\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1@example.com\r\n" +
+	"RECURRENCE-ID:20200108T100000Z\r\n" +
+	"SUMMARY:Override\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1@example.com\r\n" +
+	"SUMMARY:New\r\n" +
+	"CATEGORIES:WORK\r\n" +
+	"CATEGORIES:PRIVATE\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n"
+
+func TestObjects_Events(t *testing.T) {
+	var resolved []string
+	resolve := func(current, other *go_contentline.Property) *go_contentline.Property {
+		resolved = append(resolved, current.Value+"/"+other.Value)
+		return other
+	}
+	out, reports := Objects(parseAll(t, testEvents), Options{Resolve: resolve})
+	if len(out) != 2 || len(reports) != 1 {
+		t.Fatalf("unexpected result: %d objects, %d reports", len(out), len(reports))
+	}
+	e := out[0]
+	if e.GetProperty("SUMMARY").Value != "New" || len(e.FindProperties("CATEGORIES")) != 2 || len(e.Comps) != 1 {
+		t.Errorf("unexpected merged event: %v", lines(e))
+	}
+	if len(resolved) != 1 || resolved[0] != "Old/New" {
+		t.Errorf("unexpected calls of Resolve: %q", resolved)
+	}
+	if reports[0].Duplicates != 3 || len(reports[0].Added) != 1 {
+		t.Errorf("unexpected report: %+v", reports[0])
+	}
+	if out[1].GetProperty("SUMMARY").Value != "Override" {
+		t.Error("the override was merged")
+	}
+}
+
+func TestObjects_ResolveNil(t *testing.T) {
+	resolve := func(current, other *go_contentline.Property) *go_contentline.Property { return nil }
+	out, reports := Objects(parseAll(t, testEvents), Options{Resolve: resolve})
+	if got := out[0].GetProperty("SUMMARY").Value; got != "Old" {
+		t.Errorf("Wanted the current SUMMARY, got %q", got)
+	}
+	if c := reports[0].Conflicts; len(c) != 1 || c[0].Kept.Value != "Old" || c[0].Dropped.Value != "New" {
+		t.Errorf("unexpected conflicts: %+v", c)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := go_contentline.NewPropertyUnchecked("TEL", "1", go_contentline.Parameters{"TYPE": {"HOME", "voice"}})
+	b := go_contentline.NewPropertyUnchecked("tel", "1", go_contentline.Parameters{"type": {"Voice", "home"}})
+	if !Equal(a, b) {
+		t.Error("expected the properties to be equal")
+	}
+	b.Value = "2"
+	if Equal(a, b) {
+		t.Error("expected the properties to differ")
+	}
+}
@@ -2,7 +2,8 @@ package go_contentline
 
 import (
 	"bufio"
-	"bytes"
+	"context"
+	"fmt"
 
 	"io"
 
@@ -13,33 +14,143 @@ import (
 
 //Parser contains fields describing the state of the parser.
 type Parser struct {
-	r    *bufio.Reader
-	line int //not useful
-	l    *lexer
+	r *positionReader
+	//line is the number of lines read so far, start the line on which the current content line started and
+	// startOffset its byte offset
+	line        int
+	start       int
+	startOffset int64
+	l           *lexer
+	opts        ParserOptions
+	//pending is true if the value of the current line was not read yet, see readHead
+	pending bool
+	//depth is the nesting depth of the current component, components the number of components read so far
+	depth      int
+	components int
+	//transcoded is true if the input is transcoded to UTF-8, see ParserOptions.Charset
+	transcoded bool
+	//skipped is the number of bytes skipped so far, see ParserOptions.SkipPreamble
+	skipped int64
+}
+
+//ParserOptions change the behaviour of a Parser, see InitParserWithOptions.
+type ParserOptions struct {
+	//Legacy enables the syntax of vCard 2.1 and 3.0 (RFC2426): parameters without name (e.g. TEL;CELL:...) are
+	// read as TYPE (or ENCODING/VALUE for their known values), blank lines are skipped and quoted-printable values
+	// may continue on the next line after a soft line break ('='). Values encoded with ENCODING=QUOTED-PRINTABLE,
+	// ENCODING=BASE64 or a CHARSET parameter are decoded to UTF-8 and these parameters are removed. Binary values
+	// (e.g. PHOTO) keep their base64 encoding, but all whitespace is removed from them.
+	Legacy bool
+
+	//Binary, if not nil, receives the inline binary data of PHOTO, LOGO, SOUND, KEY and ATTACH properties
+	// (ENCODING=b/BASE64 or a base64 data: URI) while it is read. The data is decoded as it is read from the input
+	// instead of being stored in the value of the property, which stays empty. That way, values of several megabytes
+	// don't have to be kept in memory. The handler is called before the property is added to its component.
+	Binary BinaryHandler
+
+	//Context, if not nil, is checked before each content line is read. Once it is done, ParseNextObject returns its
+	// error (context.Canceled or context.DeadlineExceeded) unwrapped.
+	Context context.Context
+
+	//Limits restrict the size of the accepted input, see Limits.
+	Limits Limits
+
+	//Charset is the charset of the input, e.g. "windows-1252" for vCards written by older versions of Outlook. Besides
+	// the charsets of the CHARSET parameter (see Legacy), UTF-16, UTF-16BE and UTF-16LE are supported. The input is
+	// transcoded to UTF-8 before it is parsed. If Charset is empty, the input is read as UTF-8, unless it starts with
+	// the byte order mark of UTF-16 or with a zero byte, which marks UTF-16 without byte order mark.
+	// Byte order marks are removed. In legacy mode, values with a CHARSET parameter which are neither
+	// quoted-printable nor base64 encoded are already transcoded and only lose the parameter.
+	Charset string
+
+	//StrictUTF8 makes ParseNextObject return an error for properties whose values or parameters aren't valid UTF-8
+	// (after transcoding). Otherwise, invalid bytes are kept as they are.
+	StrictUTF8 bool
+
+	//Positions records where each Component and Property was found in the input, see Component.Position and
+	// Property.Position. This keeps the raw text of the input in memory, unless Binary is set.
+	Positions bool
+
+	//SkipPreamble skips all lines before the BEGIN line of each object, e.g. mail headers before the first object or
+	// blank lines between objects. ParseNextObject returns io.EOF if there is no object left. The number of skipped
+	// bytes is returned by Parser.Skipped.
+	SkipPreamble bool
 }
 
 //InitParser initializes the parser by creating a buffered Reader.
 func InitParser(reader io.Reader) *Parser {
-	return &Parser{bufio.NewReader(reader), 0, nil}
+	return InitParserWithOptions(reader, ParserOptions{})
+}
+
+//InitParserWithOptions initializes the parser like InitParser, but with the given options.
+func InitParserWithOptions(reader io.Reader, opts ParserOptions) *Parser {
+	r, transcoded := decodeInput(reader, opts.Charset)
+	return &Parser{r: &positionReader{Reader: bufio.NewReader(r)}, opts: opts, transcoded: transcoded}
+}
+
+//ParseError is returned by ParseNextObject if the input can't be parsed.
+type ParseError struct {
+	//Line is the line of the input (starting at 1) on which the invalid content line starts.
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+//Cause returns the underlying error, see github.com/pkg/errors.Cause.
+func (e *ParseError) Cause() error {
+	return e.Err
+}
+
+//Unwrap returns the cause of the error (see github.com/pkg/errors.Cause), so that errors.As of the standard library
+// finds the error types of Limits.
+func (e *ParseError) Unwrap() error {
+	return errors.Cause(e.Err)
 }
 
 //ParseNextObject parses the next Component and returns it. If the Parser encounters an EOF prematurely,
-// it returns 'nil, io.EOF'. For all other errors, a wrapped error is returned.
+// it returns 'nil, io.EOF'. If the Context of the ParserOptions is done, its error is returned.
+// For all other errors, a *ParseError with the line of the error is returned.
 func (p *Parser) ParseNextObject() (component *Component, err error) {
 	c, e := p.parseObject()
-	switch e {
-	case nil:
+	if e != nil {
+		p.dropLexer()
+	}
+	switch {
+	case e == nil:
 		return c, nil
-	case io.EOF:
+	case e == io.EOF:
 		return nil, io.EOF
+	case p.opts.Context != nil && e == p.opts.Context.Err():
+		return nil, e
 	default:
-		return nil, errors.Wrap(e, "error while parsing component(s)")
+		return nil, &ParseError{p.start, errors.Wrap(e, "error while parsing component(s)")}
 	}
 }
 
+//Skipped returns the number of bytes skipped so far because of ParserOptions.SkipPreamble.
+func (p *Parser) Skipped() int64 {
+	return p.skipped
+}
+
 //parseObject parses the next Object from the stream, expecting an itemBegin token. This function is wrapped by
 // ParseNextObject for better error messages.
 func (p *Parser) parseObject() (component *Component, err error) {
+	if p.opts.SkipPreamble {
+		if err = p.skipPreamble(); err != nil {
+			return nil, err
+		}
+	}
+	//record the input of the object for Position.Raw, unless it may contain large binary values
+	p.r.record = p.opts.Positions && p.opts.Binary == nil
+	start := p.r.offset
+	defer func() {
+		p.r.record = false
+		p.r.raw = nil
+	}()
+
 	var i *item
 	//checks if the first thing to read is the start of a component
 	i, err = p.getNextItem()
@@ -50,13 +161,49 @@ func (p *Parser) parseObject() (component *Component, err error) {
 		return nil, errorf(p.l.input, i, "Expected '"+sBEGIN+"'")
 	}
 	//if true, start recursively parsing components and properties
-	return p.parseComponent()
+	c, err := p.parseComponent()
+	if err != nil {
+		return nil, err
+	}
+	if p.r.record {
+		setRaw(c, string(p.r.raw), start)
+	}
+	return c, nil
+}
+
+//skipPreamble skips all lines up to the next line starting with "BEGIN:" (ignoring case). It returns io.EOF if there
+// is none.
+func (p *Parser) skipPreamble() error {
+	for {
+		if ctx := p.opts.Context; ctx != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		start, err := p.r.Peek(len(sBEGIN) + 1)
+		if hasPrefixFold(string(start), sBEGIN+":") {
+			return nil
+		}
+		if len(start) == 0 && err != nil {
+			return err
+		}
+		for {
+			chunk, err := p.r.ReadSlice('\n')
+			p.skipped += int64(len(chunk))
+			if err == io.EOF {
+				return err
+			}
+			if err != bufio.ErrBufferFull {
+				break
+			}
+		}
+		p.line++
+	}
 }
 
 //parseComponent parses the Component for which itemBegin was already read.
 func (p *Parser) parseComponent() (*Component, error) {
 	var i *item
 	var err error
+	begin := p.l.input
 	i, err = p.getNextItem()
 	if err != nil {
 		return nil, err
@@ -64,18 +211,32 @@ func (p *Parser) parseComponent() (*Component, error) {
 
 	out := &Component{
 		Name: i.val,
+		olds: begin,
+		pos:  p.position(),
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	if max := p.maxDepth(); p.depth > max {
+		return nil, &DepthError{max}
+	}
+	p.components++
+	if max := p.opts.Limits.MaxComponents; max > 0 && p.components > max {
+		return nil, &ComponentCountError{max}
 	}
 
 	i, e := p.getNextItem()
 	for ; e == nil && i.typ != itemEnd; i, e = p.getNextItem() {
 		switch i.typ {
 		case itemId:
-			p, e := p.parseProperty(i.val)
+			prop, e := p.parseProperty(i.val)
 			if e != nil {
 				return nil, e
 			}
 
-			out.Properties = append(out.Properties, p)
+			out.Properties = append(out.Properties, prop)
+			if max := p.opts.Limits.MaxProperties; max > 0 && len(out.Properties) > max {
+				return nil, &PropertyCountError{out.Name, max}
+			}
 
 		case itemBegin:
 			c, e := p.parseComponent()
@@ -97,6 +258,7 @@ func (p *Parser) parseComponent() (*Component, error) {
 	if namei.val != out.Name {
 		return nil, errorf(line, namei, "expected "+out.Name)
 	}
+	p.endPosition(out.pos)
 	return out, nil
 }
 
@@ -106,14 +268,30 @@ func (p *Parser) parseProperty(name string) (*Property, error) {
 		Name:       name,
 		Parameters: make(map[string][]string),
 		olds:       p.l.input,
+		pos:        p.position(),
+	}
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		out.Group, out.Name = name[:i], name[i+1:]
 	}
 
 	currentParam := ""
+	params := 0
 	i, e := p.getNextItem()
 	for ; e == nil && i.typ != itemPropValue; i, e = p.getNextItem() {
+		switch i.typ {
+		case itemId, itemBareParam:
+			params++
+			if max := p.opts.Limits.MaxParameters; max > 0 && params > max {
+				p.dropLexer()
+				return nil, &ParameterCountError{out.Name, max}
+			}
+		}
 		switch i.typ {
 		case itemId:
 			currentParam = i.val
+		case itemBareParam:
+			currentParam = bareParamName(i.val)
+			out.Parameters[currentParam] = append(out.Parameters[currentParam], i.val)
 		case itemParamValue:
 			out.Parameters[currentParam] = append(out.Parameters[currentParam], i.val)
 		}
@@ -122,19 +300,72 @@ func (p *Parser) parseProperty(name string) (*Property, error) {
 		return nil, e
 	}
 	out.Value = i.val
+	if p.pending {
+		p.pending = false
+		if e = p.readValue(out); e != nil {
+			return nil, e
+		}
+	}
+	if p.opts.Legacy {
+		if e = decodeLegacyValue(out, p.transcoded); e != nil {
+			return nil, errors.Wrapf(e, "could not decode %s", out.Name)
+		}
+	}
+	if p.opts.StrictUTF8 {
+		if e = checkUTF8(out); e != nil {
+			return nil, e
+		}
+	}
+	p.endPosition(out.pos)
 	return out, nil
 }
 
+//checkUTF8 returns an error if the value or a parameter of p is not valid UTF-8.
+func checkUTF8(p *Property) error {
+	if i := firstInvalidUTF8(p.Value); i >= 0 {
+		return errors.Errorf("invalid UTF-8 in the value of %s at byte %d", p.Name, i)
+	}
+	for name, vals := range p.Parameters {
+		for _, v := range vals {
+			if i := firstInvalidUTF8(v); i >= 0 {
+				return errors.Errorf("invalid UTF-8 in the parameter %s of %s at byte %d", name, p.Name, i)
+			}
+		}
+	}
+	return nil
+}
+
+//dropLexer stops the lexer of the current line, if there is one. Its goroutine only exits once all items are read.
+func (p *Parser) dropLexer() {
+	if p.l != nil {
+		p.l.drain()
+		p.l = nil
+	}
+}
+
 //getNextItem returns the next lexer item, feeding (unfolded) lines into the lexer if neccessary.
 // It also converts identifiers (itemCompName, itemID) into upper case, errors encountered by the
 // lexer into 'error' values and property parameter values into their original value (without escaped characters).
 func (p *Parser) getNextItem() (*item, error) {
 	if p.l == nil {
-		line, err := p.readUnfoldedLine()
+		if ctx := p.opts.Context; ctx != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var line string
+		var err error
+		p.start, p.startOffset = p.line+1, p.r.offset
+		if p.opts.Binary != nil {
+			line, err = p.readHead()
+		} else {
+			line, err = p.readLine()
+		}
 		if line == "" {
+			if err == nil {
+				err = errors.New("unexpected empty line")
+			}
 			return nil, err
 		}
-		p.l = lex(p.line, line)
+		p.l = lex(p.line, line, p.opts.Legacy)
 	}
 	i := p.l.nextItem()
 	switch i.typ {
@@ -147,7 +378,7 @@ func (p *Parser) getNextItem() (*item, error) {
 		fallthrough
 	case itemPropValue: //the last items of a line
 		p.l = nil
-	case itemId: // make it easier for string matching
+	case itemId, itemBareParam: // make it easier for string matching
 		i.val = strings.ToUpper(i.val)
 	case itemParamValue: // remove escape strings (^^,^n,^N,^')
 		i.val = UnescapeParamVal(i.val)
@@ -156,28 +387,70 @@ func (p *Parser) getNextItem() (*item, error) {
 
 }
 
+//readLine returns the next unfolded line. In legacy mode, blank lines are skipped and lines of quoted-printable
+// values ending with a soft line break are joined.
+func (p *Parser) readLine() (string, error) {
+	line, err := p.readUnfoldedLine()
+	if !p.opts.Legacy {
+		return line, err
+	}
+	for line == "" && err == nil {
+		p.start, p.startOffset = p.line+1, p.r.offset
+		line, err = p.readUnfoldedLine()
+	}
+	for err == nil && strings.HasSuffix(line, "=") && isQuotedPrintable(line) {
+		var next string
+		next, err = p.readUnfoldedLine()
+		line = line[:len(line)-1] + next
+		if err == nil {
+			err = p.checkLength(len(line))
+		}
+	}
+	return line, err
+}
+
 //readUnfoldedLine reads lines directly from the reader and unfolds them if neccessary.
 func (p *Parser) readUnfoldedLine() (string, error) {
-	buf, e := p.r.ReadBytes('\n')
-	if e != nil {
-		return "", e
-	}
+	var line []byte
+	for {
+		buf, e := p.readRawLine(len(line))
+		if e != nil {
+			return "", e
+		}
+		p.line++
 
-	if buf[len(buf)-2] != '\r' {
-		return "", errors.Errorf("Expected CRLF:%s, >%v<", buf, buf[len(buf)-2:])
+		if len(buf) < 2 || buf[len(buf)-2] != '\r' {
+			end := buf
+			if len(end) > 2 {
+				end = end[len(end)-2:]
+			}
+			return "", errors.Errorf("Expected CRLF:%s, >%v<", buf, end)
+		}
+		line = append(line, buf[:len(buf)-2]...)
+		b1, err2 := p.r.Peek(1)
+		if err2 != nil {
+			return string(line), err2
+		}
+		if b1[0] != ' ' && b1[0] != '\t' {
+			return string(line), nil
+		}
+		p.r.ReadByte()
 	}
-	b1, err2 := p.r.Peek(1)
+}
 
-	if err2 != nil {
-		return string(buf[:len(buf)-2]), err2
-	}
-	if bytes.Equal(b1, []byte(" ")) || bytes.Equal(b1, []byte("\t")) {
-		p.r.ReadByte()
-		s, e := p.readUnfoldedLine()
-		if s == "" {
-			return "", e
+//readRawLine reads the next line including the line break. It stops with a *LineLengthError as soon as the line,
+// appended to an unfolded line of n bytes, is too long.
+func (p *Parser) readRawLine(n int) ([]byte, error) {
+	var buf []byte
+	for {
+		chunk, err := p.r.ReadSlice('\n')
+		buf = append(buf, chunk...)
+		//the line break doesn't count
+		if e := p.checkLength(n + len(buf) - 2); e != nil {
+			return nil, e
+		}
+		if err != bufio.ErrBufferFull {
+			return buf, err
 		}
-		return string(buf[:len(buf)-2]) + s, e
 	}
-	return string(buf[:len(buf)-2]), nil
 }
@@ -1,10 +1,17 @@
 package go_contentline
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 func ExampleInitParser() {
@@ -22,7 +29,7 @@ func TestParser_ParseNextObject(t *testing.T) {
 	parseCompare(t,
 		"BEGIN:comp\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", nil, nil})
+		&Component{"COMP", nil, nil, "BEGIN:comp", nil})
 
 	//check Component with inner Component
 	parseCompare(t,
@@ -30,14 +37,14 @@ func TestParser_ParseNextObject(t *testing.T) {
 			"BEGIN:inner\r\n"+
 			"END:inner\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", nil, []*Component{{"INNER", nil, nil}}})
+		&Component{"COMP", nil, []*Component{{"INNER", nil, nil, "BEGIN:inner", nil}}, "BEGIN:comp", nil})
 
 	//check Property
 	parseCompare(t,
 		"BEGIN:comp\r\n"+
 			"FEATURE:Content:'!,;.'\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", []*Property{{"FEATURE", "Content:'!,;.'", make(Parameters), "FEATURE:Content:'!,;.'"}}, nil})
+		&Component{"COMP", []*Property{{"FEATURE", "Content:'!,;.'", make(Parameters), "FEATURE:Content:'!,;.'", "", nil}}, nil, "BEGIN:comp", nil})
 
 	//check unfolding
 	parseCompare(t,
@@ -45,28 +52,28 @@ func TestParser_ParseNextObject(t *testing.T) {
 			"FEATURE:Conten\r\n"+
 			" t:'!,;.'\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", []*Property{{"FEATURE", "Content:'!,;.'", make(Parameters), "FEATURE:Content:'!,;.'"}}, nil})
+		&Component{"COMP", []*Property{{"FEATURE", "Content:'!,;.'", make(Parameters), "FEATURE:Content:'!,;.'", "", nil}}, nil, "BEGIN:comp", nil})
 
 	//check Parameter
 	parseCompare(t,
 		"BEGIN:comp\r\n"+
 			"FEATURE;LANG=en:LoremIpsum\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"en"}}, "FEATURE;LANG=en:LoremIpsum"}}, nil})
+		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"en"}}, "FEATURE;LANG=en:LoremIpsum", "", nil}}, nil, "BEGIN:comp", nil})
 
 	//check quoted Parameter
 	parseCompare(t,
 		"BEGIN:comp\r\n"+
 			"FEATURE;LAng=\"e;n\":LoremIpsum\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"e;n"}}, "FEATURE;LAng=\"e;n\":LoremIpsum"}}, nil})
+		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"e;n"}}, "FEATURE;LAng=\"e;n\":LoremIpsum", "", nil}}, nil, "BEGIN:comp", nil})
 
 	//check RFC6868-Escaping
 	parseCompare(t,
 		"BEGIN:comp\r\n"+
 			"FEATURE;LANG=e^^^n:LoremIpsum\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"e^\n"}}, "FEATURE;LANG=e^^^n:LoremIpsum"}}, nil})
+		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"e^\n"}}, "FEATURE;LANG=e^^^n:LoremIpsum", "", nil}}, nil, "BEGIN:comp", nil})
 
 	//check multiple Parameters with multiple values, variably encoded and folded
 	parseCompare(t,
@@ -74,7 +81,7 @@ func TestParser_ParseNextObject(t *testing.T) {
 			"FEATURE;Par1=e^'^n,\"other^,val\";PAR2=\"\r\n"+
 			" display:none;\",not interesting:LoremIpsum\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"PAR1": {"e\"\n", "other^,val"}, "PAR2": {"display:none;", "not interesting"}}, "FEATURE;Par1=e^'^n,\"other^,val\";PAR2=\"display:none;\",not interesting:LoremIpsum"}}, nil})
+		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"PAR1": {"e\"\n", "other^,val"}, "PAR2": {"display:none;", "not interesting"}}, "FEATURE;Par1=e^'^n,\"other^,val\";PAR2=\"display:none;\",not interesting:LoremIpsum", "", nil}}, nil, "BEGIN:comp", nil})
 
 	//check property in nested Component
 	parseCompare(t,
@@ -83,7 +90,7 @@ func TestParser_ParseNextObject(t *testing.T) {
 			"FEATURE;LAng=\"e;n\":LoremIpsum\r\n"+
 			"END:InNeRcOmP\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", nil, []*Component{{"INNERCOMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"e;n"}}, "FEATURE;LAng=\"e;n\":LoremIpsum"}}, nil}}})
+		&Component{"COMP", nil, []*Component{{"INNERCOMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"e;n"}}, "FEATURE;LAng=\"e;n\":LoremIpsum", "", nil}}, nil, "BEGIN:iNnErCoMp", nil}}, "BEGIN:comp", nil})
 
 	//check property next to nested Component
 	parseCompare(t,
@@ -93,7 +100,7 @@ func TestParser_ParseNextObject(t *testing.T) {
 			"END:InNeRcOmP\r\n"+
 			"FEATURE;LAng2=\"e;n\":LoremIpsum\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"e;n"}}, "FEATURE;LAng=\"e;n\":LoremIpsum"}, {"FEATURE", "LoremIpsum", map[string][]string{"LANG2": {"e;n"}}, "FEATURE;LAng2=\"e;n\":LoremIpsum"}}, []*Component{{"INNERCOMP", nil, nil}}})
+		&Component{"COMP", []*Property{{"FEATURE", "LoremIpsum", map[string][]string{"LANG": {"e;n"}}, "FEATURE;LAng=\"e;n\":LoremIpsum", "", nil}, {"FEATURE", "LoremIpsum", map[string][]string{"LANG2": {"e;n"}}, "FEATURE;LAng2=\"e;n\":LoremIpsum", "", nil}}, []*Component{{"INNERCOMP", nil, nil, "BEGIN:iNnErCoMp", nil}}, "BEGIN:comp", nil})
 
 	//check empty property
 	parseCompare(t,
@@ -103,7 +110,7 @@ func TestParser_ParseNextObject(t *testing.T) {
 			"END:InNeRcOmP\r\n"+
 			"FEATURE;LAng2=\"e;n\":\r\n"+
 			"END:Comp\r\n",
-		&Component{"COMP", []*Property{{"FEATURE", "", map[string][]string{}, "FEATURE:"}, {"FEATURE", "", map[string][]string{"LANG2": {"e;n"}}, "FEATURE;LAng2=\"e;n\":"}}, []*Component{{"INNERCOMP", nil, nil}}})
+		&Component{"COMP", []*Property{{"FEATURE", "", map[string][]string{}, "FEATURE:", "", nil}, {"FEATURE", "", map[string][]string{"LANG2": {"e;n"}}, "FEATURE;LAng2=\"e;n\":", "", nil}}, []*Component{{"INNERCOMP", nil, nil, "BEGIN:iNnErCoMp", nil}}, "BEGIN:comp", nil})
 
 }
 
@@ -122,3 +129,175 @@ func parseCompare(t *testing.T, in string, want *Component) {
 	}
 
 }
+
+func TestParser_Group(t *testing.T) {
+	parseCompare(t,
+		"BEGIN:VCARD\r\n"+
+			"item1.EMAIL;TYPE=work:a@example.com\r\n"+
+			"END:VCARD\r\n",
+		&Component{"VCARD", []*Property{{"EMAIL", "a@example.com", map[string][]string{"TYPE": {"work"}}, "item1.EMAIL;TYPE=work:a@example.com", "ITEM1", nil}}, nil, "BEGIN:VCARD", nil})
+
+	var buf bytes.Buffer
+	NewPropertyUnchecked("EMAIL", "a@example.com", Parameters{}).Encode(&buf)
+	p := &Property{Name: "X-ABLabel", Group: "item1", Value: "Work"}
+	p.Encode(&buf)
+	if got := buf.String(); got != "EMAIL:a@example.com\r\nITEM1.X-ABLABEL:Work\r\n" {
+		t.Errorf("unexpected encoding: %q", got)
+	}
+}
+
+func TestParser_ParseError(t *testing.T) {
+	in := "BEGIN:A\r\n" +
+		"X:1\r\n" +
+		"Y;LONG=\r\n" +
+		" folded:2\r\n" +
+		"Z;=broken:3\r\n" +
+		"END:A\r\n"
+	_, err := InitParser(strings.NewReader(in)).ParseNextObject()
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected a *ParseError, got %v", err)
+	}
+	if pe.Line != 5 || !strings.HasPrefix(pe.Error(), "line 5: ") {
+		t.Errorf("unexpected error: %v", pe)
+	}
+}
+
+func TestParser_Limits(t *testing.T) {
+	in := "BEGIN:A\r\n" +
+		"X;P=1;Q=2:" + strings.Repeat("a", 60) + "\r\n" +
+		" " + strings.Repeat("b", 60) + "\r\n" +
+		"Y:1\r\n" +
+		"BEGIN:B\r\n" +
+		"BEGIN:C\r\n" +
+		"END:C\r\n" +
+		"END:B\r\n" +
+		"BEGIN:B\r\n" +
+		"END:B\r\n" +
+		"END:A\r\n"
+	tests := []struct {
+		limits Limits
+		want   error
+	}{
+		{Limits{MaxLineLength: 130, MaxDepth: 3, MaxProperties: 2, MaxParameters: 2, MaxComponents: 4}, nil},
+		{Limits{MaxLineLength: 100}, &LineLengthError{100}},
+		{Limits{MaxDepth: 2}, &DepthError{2}},
+		{Limits{MaxProperties: 1}, &PropertyCountError{"A", 1}},
+		{Limits{MaxParameters: 1}, &ParameterCountError{"X", 1}},
+		{Limits{MaxComponents: 3}, &ComponentCountError{3}},
+	}
+	for _, tt := range tests {
+		_, err := InitParserWithOptions(strings.NewReader(in), ParserOptions{Limits: tt.limits}).ParseNextObject()
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %v", tt.limits, err)
+			}
+			continue
+		}
+		if _, ok := err.(*ParseError); !ok {
+			t.Errorf("%+v: expected a *ParseError, got %v", tt.limits, err)
+		}
+		if got := errors.Cause(err); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: Wanted %v, got %v", tt.limits, tt.want, got)
+		}
+	}
+}
+
+func TestParser_DefaultMaxDepth(t *testing.T) {
+	in := strings.Repeat("BEGIN:A\r\n", 3*DefaultMaxDepth)
+	_, err := InitParser(strings.NewReader(in)).ParseNextObject()
+	if got, want := errors.Cause(err), (&DepthError{DefaultMaxDepth}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted %v, got %v", want, got)
+	}
+	if pe, ok := err.(*ParseError); !ok || pe.Line != DefaultMaxDepth+1 {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParser_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := InitParserWithOptions(strings.NewReader("BEGIN:A\r\nEND:A\r\nBEGIN:A\r\nEND:A\r\n"), ParserOptions{Context: ctx})
+	if _, err := p.ParseNextObject(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := p.ParseNextObject(); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParser_ManyFoldedLines(t *testing.T) {
+	in := "BEGIN:A\r\nX:" + strings.Repeat("a\r\n ", 1000000) + "\r\nEND:A\r\n"
+	c, err := InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(c.Properties[0].Value); got != 1000000 {
+		t.Errorf("expected a value of 1000000 bytes, got %d", got)
+	}
+}
+
+func TestParser_SkipPreamble(t *testing.T) {
+	preamble := "Content-Type: text/calendar\n\r\n"
+	in := preamble +
+		"BEGIN:A\r\nX:1\r\nEND:A\r\n" +
+		"\r\n\r\n" +
+		"begin:A\r\nEND:A\r\n" +
+		"\r\n"
+	p := InitParserWithOptions(strings.NewReader(in), ParserOptions{SkipPreamble: true, Positions: true})
+	for i := 0; i < 2; i++ {
+		c, err := p.ParseNextObject()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if c.Name != "A" {
+			t.Errorf("%d: Wanted A, got %s", i, c.Name)
+		}
+		if i == 0 && (p.Skipped() != int64(len(preamble)) || c.Position().StartLine != 3) {
+			t.Errorf("Wanted %d skipped bytes before line 3, got %d before line %d", len(preamble), p.Skipped(),
+				c.Position().StartLine)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.ParseNextObject(); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	}
+	if got, want := p.Skipped(), int64(len(preamble)+6); got != want {
+		t.Errorf("Wanted %d skipped bytes, got %d", want, got)
+	}
+
+	if _, err := InitParser(strings.NewReader(in)).ParseNextObject(); err == nil {
+		t.Error("expected an error without SkipPreamble")
+	}
+}
+
+func TestParser_ErrorsStopLexer(t *testing.T) {
+	inputs := []struct {
+		in   string
+		opts ParserOptions
+	}{
+		{"X;P=a;Q=b:v\r\n", ParserOptions{}},
+		{"BEGIN:A\r\nX;P=a;Q=b;R=c:v\r\nEND:A\r\n", ParserOptions{Limits: Limits{MaxParameters: 1}}},
+		{"BEGIN:A\r\nX;P=a:\xff\r\nEND:A\r\n", ParserOptions{StrictUTF8: true}},
+		{"BEGIN:A\r\nX;P=\"a:v\r\nEND:A\r\n", ParserOptions{}},
+		{"BEGIN:A\r\nPHOTO;ENCODING=b;P=a:*\r\nEND:A\r\n", ParserOptions{Binary: func(p *Property, mediaType string, data io.Reader) error {
+			return errors.New("rejected")
+		}}},
+	}
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		for _, in := range inputs {
+			if _, err := InitParserWithOptions(strings.NewReader(in.in), in.opts).ParseNextObject(); err == nil {
+				t.Fatalf("%q: expected an error", in.in)
+			}
+		}
+	}
+	//exiting goroutines may still be counted for a moment
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines left running", after-before)
+	}
+}
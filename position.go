@@ -0,0 +1,90 @@
+package go_contentline
+
+import "bufio"
+
+//Position describes where a parsed Component or Property was found in the input, see ParserOptions.Positions. Lines
+// start at 1, byte offsets at 0 and refer to the input after transcoding to UTF-8 (see ParserOptions.Charset).
+type Position struct {
+	//StartLine and EndLine are the first and last physical (folded) line, EndLine includes folded continuation lines
+	// and is the line of END for components.
+	StartLine, EndLine int
+	//Start is the byte offset of the first byte, End the offset behind the line break of the last line.
+	Start, End int64
+	//Raw is the folded text of the input between Start and End, including line breaks. It is empty if the input was
+	// parsed with a BinaryHandler (see ParserOptions.Binary), as the input is not kept in memory then.
+	Raw string
+}
+
+//Position returns where the component was found in the input. It returns the zero value if the component was not
+// parsed with ParserOptions.Positions, but created.
+func (c *Component) Position() Position {
+	if c.pos == nil {
+		return Position{}
+	}
+	return *c.pos
+}
+
+//Position returns where the property was found in the input. It returns the zero value if the property was not
+// parsed with ParserOptions.Positions, but created.
+func (p *Property) Position() Position {
+	if p.pos == nil {
+		return Position{}
+	}
+	return *p.pos
+}
+
+//position returns the position of a component or property starting on the current line, or nil if positions aren't
+// recorded.
+func (p *Parser) position() *Position {
+	if !p.opts.Positions {
+		return nil
+	}
+	return &Position{StartLine: p.start, Start: p.startOffset}
+}
+
+//endPosition sets the end of pos to the current position of the parser, pos may be nil.
+func (p *Parser) endPosition(pos *Position) {
+	if pos != nil {
+		pos.EndLine, pos.End = p.line, p.r.offset
+	}
+}
+
+//positionReader counts the bytes read from the input and records them while record is set.
+type positionReader struct {
+	*bufio.Reader
+	offset int64
+	record bool
+	raw    []byte
+}
+
+func (r *positionReader) ReadByte() (byte, error) {
+	c, err := r.Reader.ReadByte()
+	if err == nil {
+		r.offset++
+		if r.record {
+			r.raw = append(r.raw, c)
+		}
+	}
+	return c, err
+}
+
+func (r *positionReader) ReadSlice(delim byte) ([]byte, error) {
+	line, err := r.Reader.ReadSlice(delim)
+	r.offset += int64(len(line))
+	if r.record {
+		r.raw = append(r.raw, line...)
+	}
+	return line, err
+}
+
+//setRaw sets the raw text of c and all of its properties and subcomponents, raw is the text of the input starting at
+// the byte offset start.
+func setRaw(c *Component, raw string, start int64) {
+	c.pos.Raw = raw[c.pos.Start-start : c.pos.End-start]
+	for _, p := range c.Properties {
+		p.pos.Raw = raw[p.pos.Start-start : p.pos.End-start]
+	}
+	for _, sub := range c.Comps {
+		setRaw(sub, raw, start)
+	}
+}
@@ -0,0 +1,105 @@
+package go_contentline
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestParser_Positions(t *testing.T) {
+	in := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:a long\r\n" +
+		"  summary\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"FN:Jane\r\n" +
+		"END:VCARD\r\n"
+	p := InitParserWithOptions(strings.NewReader(in), ParserOptions{Positions: true})
+	cal, err := p.ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := p.ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := cal.Comps[0]
+	checks := []struct {
+		got                Position
+		startLine, endLine int
+		raw                string
+	}{
+		{cal.Position(), 1, 7, in[:strings.Index(in, "BEGIN:VCARD")]},
+		{cal.Properties[0].Position(), 2, 2, "VERSION:2.0\r\n"},
+		{event.Position(), 3, 6, "BEGIN:VEVENT\r\nSUMMARY:a long\r\n  summary\r\nEND:VEVENT\r\n"},
+		{event.Properties[0].Position(), 4, 5, "SUMMARY:a long\r\n  summary\r\n"},
+		{card.Position(), 8, 10, "BEGIN:VCARD\r\nFN:Jane\r\nEND:VCARD\r\n"},
+		{card.Properties[0].Position(), 9, 9, "FN:Jane\r\n"},
+		//clones keep the positions
+		{event.Clone().Position(), 3, 6, "BEGIN:VEVENT\r\nSUMMARY:a long\r\n  summary\r\nEND:VEVENT\r\n"},
+		{event.Properties[0].Clone().Position(), 4, 5, "SUMMARY:a long\r\n  summary\r\n"},
+	}
+	for i, c := range checks {
+		if c.got.StartLine != c.startLine || c.got.EndLine != c.endLine {
+			t.Errorf("%d: Wanted lines %d-%d, got %d-%d", i, c.startLine, c.endLine, c.got.StartLine, c.got.EndLine)
+		}
+		if c.got.Raw != c.raw {
+			t.Errorf("%d: Wanted raw text %q, got %q", i, c.raw, c.got.Raw)
+		}
+		if s := in[c.got.Start:c.got.End]; s != c.raw {
+			t.Errorf("%d: Wanted the offsets of %q, got %q", i, c.raw, s)
+		}
+	}
+	if got := event.Properties[0].OriginalLine(); got != "SUMMARY:a long summary" {
+		t.Errorf("Wanted the unfolded line, got %q", got)
+	}
+	if got := event.OriginalLine(); got != "BEGIN:VEVENT" {
+		t.Errorf("Wanted the BEGIN line, got %q", got)
+	}
+
+	//without the option, no positions are recorded
+	c, err := InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Comps[0].Properties[0].Position(); got != (Position{}) {
+		t.Errorf("Wanted no position, got %+v", got)
+	}
+}
+
+func TestParser_PositionsLegacyAndBinary(t *testing.T) {
+	in := "BEGIN:VCARD\r\n" +
+		"\r\n" +
+		"PHOTO;ENCODING=b:AAEC\r\n" +
+		" Aw==\r\n" +
+		"END:VCARD\r\n"
+	for _, opts := range []ParserOptions{
+		{Positions: true, Legacy: true},
+		{Positions: true, Legacy: true, Binary: func(p *Property, mediaType string, data io.Reader) error {
+			_, err := io.Copy(ioutil.Discard, data)
+			return err
+		}},
+	} {
+		p := InitParserWithOptions(bytes.NewReader([]byte(in)), opts)
+		c, err := p.ParseNextObject()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := c.Properties[0].Position()
+		if got.StartLine != 3 || got.EndLine != 4 || in[got.Start:got.End] != "PHOTO;ENCODING=b:AAEC\r\n Aw==\r\n" {
+			t.Errorf("Wanted the position of PHOTO, got %+v", got)
+		}
+		if opts.Binary == nil && got.Raw != in[got.Start:got.End] {
+			t.Errorf("Wanted the raw text of PHOTO, got %q", got.Raw)
+		}
+		if opts.Binary != nil && got.Raw != "" {
+			t.Errorf("Wanted no raw text with a BinaryHandler, got %q", got.Raw)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package go_contentline
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//Path is a compiled path expression selecting Components or Properties from a component tree.
+//
+// A path consists of steps separated by '/'. Each step is a component name (or '*' for any name), optionally
+// followed by one or more predicates in square brackets, all of which have to hold for a Component to match:
+//
+//	[NAME]         the Component has at least one Property NAME
+//	[NAME=value]   the Component has a Property NAME with exactly this value
+//	[NAME~=value]  the Component has a Property NAME whose value contains this value, ignoring case
+//	[!NAME]        the Component has no Property NAME, '!' negates all other predicates in the same way
+//
+// The value may be enclosed in double quotes if it contains ']' or leading/trailing spaces.
+//
+// By default, the first step may match a Component at any depth of the tree. If the path starts with '/', the first
+// step has to match the root Component(s) the Path is applied to. For example "VCALENDAR/VEVENT[UID=x]/VALARM" and
+// "VEVENT[UID=x]/VALARM" both select all alarms of the event with the UID x in a calendar, while
+// "/VEVENT" selects nothing in it.
+//
+// When selecting Properties, the last step names the Property instead and its predicates test the parameters of
+// the Property, e.g. "VEVENT/ATTENDEE[PARTSTAT=ACCEPTED]".
+type Path struct {
+	expr     string
+	anchored bool
+	steps    []pathStep
+}
+
+//pathStep is a single part of a Path
+type pathStep struct {
+	name  string
+	preds []pathPredicate
+}
+
+//pathPredicate is a condition given in square brackets. If hasValue is false, it only tests for existence. If
+// contains is true, the value only has to be contained in the tested value.
+type pathPredicate struct {
+	name     string
+	negate   bool
+	hasValue bool
+	contains bool
+	value    string
+}
+
+//CompilePath parses a path expression, see Path for the syntax.
+func CompilePath(expr string) (*Path, error) {
+	p := &Path{expr: expr}
+	s := strings.TrimSpace(expr)
+	if strings.HasPrefix(s, "/") {
+		p.anchored = true
+		s = s[1:]
+	}
+	if s == "" {
+		return nil, errors.Errorf("invalid path %q: no steps given", expr)
+	}
+	for {
+		step, rest, err := parsePathStep(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid path %q", expr)
+		}
+		p.steps = append(p.steps, step)
+		if rest == "" {
+			return p, nil
+		}
+		if rest[0] != '/' {
+			return nil, errors.Errorf("invalid path %q: expected '/' before %q", expr, rest)
+		}
+		s = rest[1:]
+	}
+}
+
+//parsePathStep parses one step and returns the unparsed rest of the expression.
+func parsePathStep(s string) (step pathStep, rest string, err error) {
+	n := strings.IndexFunc(s, func(r rune) bool { return !strings.ContainsRune(parName, r) })
+	if n == -1 {
+		n = len(s)
+	}
+	if n == 0 && strings.HasPrefix(s, "*") {
+		n = 1
+	}
+	if n == 0 {
+		return step, "", errors.Errorf("expected a name or '*' at %q", s)
+	}
+	step.name = strings.ToUpper(s[:n])
+	s = s[n:]
+	for strings.HasPrefix(s, "[") {
+		var pred pathPredicate
+		pred, s, err = parsePathPredicate(s[1:])
+		if err != nil {
+			return step, "", err
+		}
+		step.preds = append(step.preds, pred)
+	}
+	return step, s, nil
+}
+
+//parsePathPredicate parses the content of a predicate after the opening bracket, including the closing bracket.
+func parsePathPredicate(s string) (pred pathPredicate, rest string, err error) {
+	s = strings.TrimLeft(s, wsp)
+	if strings.HasPrefix(s, "!") {
+		pred.negate = true
+		s = strings.TrimLeft(s[1:], wsp)
+	}
+	n := strings.IndexFunc(s, func(r rune) bool { return !strings.ContainsRune(parName, r) })
+	if n <= 0 {
+		return pred, "", errors.Errorf("expected a name in predicate at %q", s)
+	}
+	pred.name = strings.ToUpper(s[:n])
+	s = strings.TrimLeft(s[n:], wsp)
+	if strings.HasPrefix(s, "~=") {
+		pred.contains = true
+		s = s[1:]
+	}
+	if strings.HasPrefix(s, "=") {
+		pred.hasValue = true
+		pred.value, s, err = parsePathValue(s[1:])
+		if err != nil {
+			return pred, "", err
+		}
+	}
+	if !strings.HasPrefix(s, "]") {
+		return pred, "", errors.Errorf("expected ']' at %q", s)
+	}
+	return pred, s[1:], nil
+}
+
+//parsePathValue parses a (possibly quoted) predicate value, stopping in front of the closing bracket.
+func parsePathValue(s string) (val, rest string, err error) {
+	t := strings.TrimLeft(s, wsp)
+	if strings.HasPrefix(t, "\"") {
+		end := strings.IndexByte(t[1:], '"')
+		if end == -1 {
+			return "", "", errors.Errorf("missing closing '\"' at %q", t)
+		}
+		return t[1 : end+1], strings.TrimLeft(t[end+2:], wsp), nil
+	}
+	end := strings.IndexByte(s, ']')
+	if end == -1 {
+		return "", "", errors.Errorf("missing ']' at %q", s)
+	}
+	return strings.TrimSpace(s[:end]), s[end:], nil
+}
+
+//String returns the expression the Path was compiled from.
+func (p *Path) String() string {
+	return p.expr
+}
+
+//Components returns all Components matching the whole path, in document order.
+func (p *Path) Components(roots ...*Component) []*Component {
+	return p.matchComponents(roots, p.steps)
+}
+
+//Properties returns all Properties selected by the last step of the path, which belong to a Component matching all
+// steps before, in document order.
+func (p *Path) Properties(roots ...*Component) []*Property {
+	last := p.steps[len(p.steps)-1]
+	var comps []*Component
+	if len(p.steps) > 1 {
+		comps = p.matchComponents(roots, p.steps[:len(p.steps)-1])
+	} else if p.anchored {
+		comps = roots
+	} else {
+		for _, r := range roots {
+			r.Walk(func(_ []*Component, c *Component) error {
+				comps = append(comps, c)
+				return nil
+			})
+		}
+	}
+
+	var out []*Property
+	for _, c := range comps {
+		props := c.Properties
+		if last.name != "*" {
+			props = c.FindProperties(last.name)
+		}
+		for _, prop := range props {
+			if last.matchesProperty(prop) {
+				out = append(out, prop)
+			}
+		}
+	}
+	return out
+}
+
+//matchComponents returns all Components matching steps, starting from the roots or anywhere below them, in document
+// order.
+func (p *Path) matchComponents(roots []*Component, steps []pathStep) []*Component {
+	last := len(steps) - 1
+	var out []*Component
+	for _, r := range roots {
+		r.Walk(func(path []*Component, c *Component) error {
+			//the steps before the last one have to match the direct ancestors of c
+			if len(path) >= last && (!p.anchored || len(path) == last) &&
+				matchesAll(path[len(path)-last:], steps[:last]) && steps[last].matchesComponent(c) {
+				out = append(out, c)
+			}
+			if p.anchored && len(path) == last {
+				return SkipComponent
+			}
+			return nil
+		})
+	}
+	return out
+}
+
+//matchesAll checks whether each of the Components matches the step with the same index.
+func matchesAll(comps []*Component, steps []pathStep) bool {
+	for i := range steps {
+		if !steps[i].matchesComponent(comps[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+//matchesComponent checks the name and all predicates against the Component and its Properties.
+func (s *pathStep) matchesComponent(c *Component) bool {
+	if s.name != "*" && s.name != c.Name {
+		return false
+	}
+	for _, pred := range s.preds {
+		found := false
+		for _, prop := range c.FindProperties(pred.name) {
+			if pred.matches(prop.Value) {
+				found = true
+				break
+			}
+		}
+		if found == pred.negate {
+			return false
+		}
+	}
+	return true
+}
+
+//matchesProperty checks all predicates against the Parameters of the Property, the name is not checked.
+func (s *pathStep) matchesProperty(prop *Property) bool {
+	for _, pred := range s.preds {
+		found := false
+		for _, v := range prop.Parameters[pred.name] {
+			if pred.matches(v) {
+				found = true
+				break
+			}
+		}
+		if found == pred.negate {
+			return false
+		}
+	}
+	return true
+}
+
+//matches checks if a single value satisfies the predicate.
+func (pred *pathPredicate) matches(val string) bool {
+	switch {
+	case !pred.hasValue:
+		return true
+	case pred.contains:
+		return strings.Contains(strings.ToLower(val), strings.ToLower(pred.value))
+	}
+	return pred.value == val
+}
+
+//Query compiles the path expression and returns all matching Components in the tree below (and including) c.
+// See Path for the syntax.
+func (c *Component) Query(expr string) ([]*Component, error) {
+	p, err := CompilePath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return p.Components(c), nil
+}
+
+//QueryProperties compiles the path expression and returns all matching Properties in the tree below (and including) c.
+// See Path for the syntax.
+func (c *Component) QueryProperties(expr string) ([]*Property, error) {
+	p, err := CompilePath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return p.Properties(c), nil
+}
@@ -0,0 +1,104 @@
+package go_contentline
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func ExampleComponent_Query() {
+	c, _ := InitParser(strings.NewReader(testCalendar)).ParseNextObject()
+	alarms, _ := c.Query("VCALENDAR/VEVENT[UID=second]/VALARM")
+	for _, a := range alarms {
+		fmt.Println(a.FindProperties("ACTION")[0].Value)
+	}
+	//Output:
+	//AUDIO
+	//EMAIL
+}
+
+func TestPath_Components(t *testing.T) {
+	c := parseString(t, testCalendar)
+	checks := map[string]int{
+		"VCALENDAR":                         1,
+		"/VCALENDAR":                        1,
+		"VEVENT":                            2,
+		"/VEVENT":                           0,
+		"vevent/valarm":                     3,
+		"VCALENDAR/*/VALARM":                3,
+		"*":                                 7,
+		"VEVENT[UID=first]/VALARM":          1,
+		"VEVENT[ UID = \"second\" ]":        1,
+		"VEVENT[UID=third]":                 0,
+		"*[SUMMARY]":                        2,
+		"*[SUMMARY][UID]":                   1,
+		"VCALENDAR/VTODO[SUMMARY=Clean up]": 1,
+		"VEVENT[SUMMARY~=meet]":             1,
+		"VEVENT[UID~=\"\"]":                 2,
+		"*[!UID]":                           5,
+		"VEVENT[!SUMMARY]":                  1,
+		"VEVENT[! UID=first]":               1,
+	}
+	for expr, want := range checks {
+		got, err := c.Query(expr)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if len(got) != want {
+			t.Errorf("%s: wanted %d components, got %d", expr, want, len(got))
+		}
+	}
+
+	//matches of different starting points are returned in document order
+	tree := parseString(t, "BEGIN:A\r\nBEGIN:B\r\nBEGIN:D\r\nEND:D\r\nEND:B\r\nBEGIN:C\r\nEND:C\r\nEND:A\r\n")
+	got, err := tree.Query("*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range got {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "B,D,C" {
+		t.Errorf("Wanted B,D,C, got %v", names)
+	}
+}
+
+func TestPath_Properties(t *testing.T) {
+	c := parseString(t, testCalendar)
+	checks := map[string]string{
+		"SUMMARY":                            "Meeting,Clean up",
+		"/VCALENDAR/VERSION":                 "2.0",
+		"VEVENT/UID":                         "first,second",
+		"VEVENT/VALARM/ACTION":               "DISPLAY,AUDIO,EMAIL",
+		"VEVENT/ATTENDEE[PARTSTAT=ACCEPTED]": "mailto:a@example.com",
+		"VEVENT/ATTENDEE[PARTSTAT]":          "mailto:a@example.com,mailto:b@example.com",
+		"VEVENT[UID=first]/*":                "first,Meeting,mailto:a@example.com,mailto:b@example.com",
+		"ATTENDEE[PARTSTAT~=decl]":           "mailto:b@example.com",
+		"ATTENDEE[!PARTSTAT=ACCEPTED]":       "mailto:b@example.com",
+		"ATTENDEE[!ROLE]":                    "mailto:a@example.com,mailto:b@example.com",
+	}
+	for expr, want := range checks {
+		props, err := c.QueryProperties(expr)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		var vals []string
+		for _, p := range props {
+			vals = append(vals, p.Value)
+		}
+		if got := strings.Join(vals, ","); got != want {
+			t.Errorf("%s: wanted %q, got %q", expr, want, got)
+		}
+	}
+}
+
+func TestCompilePath(t *testing.T) {
+	for _, expr := range []string{"", "/", "VEVENT//VALARM", "VEVENT[UID", "VEVENT[=x]", "VEVENT[UID=\"x]", "VEVENT]", "VEVENT/", "VEVENT[!]", "VEVENT[UID~x]"} {
+		if _, err := CompilePath(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package recurrence
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+//Instance is a single occurrence of a recurring component, after overrides were applied.
+type Instance struct {
+	//RecurrenceID is the original start of the occurrence, as generated by the master component.
+	RecurrenceID time.Time
+	Start        time.Time
+	End          time.Time
+
+	//Source is the component this instance was generated from: the master component or an override.
+	Source *go_contentline.Component
+}
+
+//Group contains all components of a calendar which share the same UID: the master component, which carries the
+// recurrence rules, and the overrides with a RECURRENCE-ID property, which replace single or (with
+// RANGE=THISANDFUTURE) all following occurrences.
+type Group struct {
+	UID       string
+	Master    *go_contentline.Component //nil if the calendar only contains overrides for this UID
+	Overrides []*go_contentline.Component
+}
+
+//GroupByUID groups the direct subcomponents of a VCALENDAR by their UID, in order of their first appearance.
+// Subcomponents without UID (e.g. VTIMEZONE) are ignored.
+func GroupByUID(cal *go_contentline.Component) []*Group {
+	var out []*Group
+	groups := make(map[string]*Group)
+	for _, c := range cal.Comps {
+		uid := c.GetProperty("UID")
+		if uid == nil {
+			continue
+		}
+		g := groups[uid.Value]
+		if g == nil {
+			g = &Group{UID: uid.Value}
+			groups[uid.Value] = g
+			out = append(out, g)
+		}
+		if c.GetProperty("RECURRENCE-ID") != nil {
+			g.Overrides = append(g.Overrides, c)
+		} else {
+			g.Master = c
+		}
+	}
+	return out
+}
+
+//Instances returns the instances of all groups in the VCALENDAR which overlap the time range [after, before),
+// sorted by their start. See Group.Instances for details.
+func Instances(cal *go_contentline.Component, after, before time.Time, resolve go_contentline.TZResolver) ([]Instance, error) {
+	var out []Instance
+	for _, g := range GroupByUID(cal) {
+		is, err := g.Instances(after, before, resolve)
+		if err != nil {
+			return nil, errors.Wrapf(err, "UID %s", g.UID)
+		}
+		out = append(out, is...)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out, nil
+}
+
+//override is a parsed override component
+type override struct {
+	c             *go_contentline.Component
+	rid           time.Time
+	thisAndFuture bool
+	start         time.Time
+	dur           time.Duration
+}
+
+//Instances expands the master component and applies the overrides. It returns all instances which overlap the time
+// range [after, before), sorted by their start. Instances whose source has STATUS:CANCELLED are left out, as well as
+// components without DTSTART, which can't be placed in time.
+func (g *Group) Instances(after, before time.Time, resolve go_contentline.TZResolver) ([]Instance, error) {
+	var overrides []override
+	for _, c := range g.Overrides {
+		o := override{c: c}
+		var err error
+		ridProp := c.GetProperty("RECURRENCE-ID")
+		o.rid, _, err = ridProp.DateTime(resolve)
+		if err != nil {
+			return nil, err
+		}
+		o.thisAndFuture = strings.EqualFold(ridProp.Parameters.Get("RANGE"), "THISANDFUTURE")
+		o.start = o.rid
+		isDate := false
+		if dtstart := c.GetProperty("DTSTART"); dtstart != nil {
+			if o.start, isDate, err = dtstart.DateTime(resolve); err != nil {
+				return nil, err
+			}
+		}
+		if o.dur, err = Duration(c, o.start, isDate, resolve); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].rid.Before(overrides[j].rid) })
+
+	var out []Instance
+	used := make([]bool, len(overrides))
+	if g.Master != nil && g.Master.GetProperty("DTSTART") != nil {
+		set, err := FromComponent(g.Master, resolve)
+		if err != nil {
+			return nil, err
+		}
+		dur, err := Duration(g.Master, set.Start, set.IsDate, resolve)
+		if err != nil {
+			return nil, err
+		}
+		//widen the range, so that instances moved into it by a THISANDFUTURE override are not missed
+		var maxShift time.Duration
+		maxDur := dur
+		for _, o := range overrides {
+			shift := o.start.Sub(o.rid)
+			if shift < 0 {
+				shift = -shift
+			}
+			if o.thisAndFuture && shift > maxShift {
+				maxShift = shift
+			}
+			if o.dur > maxDur {
+				maxDur = o.dur
+			}
+		}
+
+		rids, err := set.Between(after.Add(-maxDur-maxShift), before.Add(maxShift))
+		if err != nil {
+			return nil, err
+		}
+		for _, rid := range rids {
+			inst := Instance{rid, rid, rid.Add(dur), g.Master}
+			for i, o := range overrides {
+				if o.rid.Equal(rid) {
+					inst = Instance{rid, o.start, o.start.Add(o.dur), o.c}
+					used[i] = true
+					break
+				}
+				if o.thisAndFuture && o.rid.Before(rid) {
+					start := rid.Add(o.start.Sub(o.rid))
+					inst = Instance{rid, start, start.Add(o.dur), o.c}
+				}
+			}
+			out = append(out, inst)
+		}
+	}
+	//overrides which do not belong to a generated occurrence are instances of their own
+	for i, o := range overrides {
+		if !used[i] {
+			out = append(out, Instance{o.rid, o.start, o.start.Add(o.dur), o.c})
+		}
+	}
+
+	result := out[:0]
+	for _, inst := range out {
+		if status := inst.Source.GetProperty("STATUS"); status != nil && strings.EqualFold(status.Value, "CANCELLED") {
+			continue
+		}
+		if !inst.Start.Before(before) || inst.End.Before(after) || (inst.End.Equal(after) && inst.End.After(inst.Start)) {
+			continue
+		}
+		result = append(result, inst)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result, nil
+}
+
+//Duration returns the duration of a component starting at start, either from DTEND (DUE for VTODO) or DURATION.
+// If neither is given, it is one day for a DATE start and zero otherwise.
+func Duration(c *go_contentline.Component, start time.Time, isDate bool, resolve go_contentline.TZResolver) (time.Duration, error) {
+	end := c.GetProperty("DTEND")
+	if end == nil {
+		end = c.GetProperty("DUE")
+	}
+	if end != nil {
+		t, _, err := end.DateTime(resolve)
+		if err != nil {
+			return 0, err
+		}
+		return t.Sub(start), nil
+	}
+	if dur := c.GetProperty("DURATION"); dur != nil {
+		return go_contentline.ParseDuration(dur.Value)
+	}
+	if isDate {
+		return 24 * time.Hour, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,112 @@
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mqus/go-contentline"
+)
+
+const testOverrides = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:daily\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"DTSTART:20180101T090000Z\r\n" +
+	"DURATION:PT15M\r\n" +
+	"RRULE:FREQ=DAILY;COUNT=10\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:daily\r\n" +
+	"SUMMARY:Late standup\r\n" +
+	"RECURRENCE-ID:20180102T090000Z\r\n" +
+	"DTSTART:20180102T100000Z\r\n" +
+	"DTEND:20180102T103000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:daily\r\n" +
+	"RECURRENCE-ID:20180103T090000Z\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:daily\r\n" +
+	"SUMMARY:Standup in the afternoon\r\n" +
+	"RECURRENCE-ID;RANGE=THISANDFUTURE:20180105T090000Z\r\n" +
+	"DTSTART:20180105T140000Z\r\n" +
+	"DURATION:PT10M\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:daily\r\n" +
+	"SUMMARY:Standup in the morning\r\n" +
+	"RECURRENCE-ID:20180107T090000Z\r\n" +
+	"DTSTART:20180107T080000Z\r\n" +
+	"DURATION:PT10M\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:single\r\n" +
+	"SUMMARY:Party\r\n" +
+	"DTSTART;VALUE=DATE:20180104\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func ExampleInstances() {
+	cal, _ := go_contentline.InitParser(strings.NewReader(testOverrides)).ParseNextObject()
+	is, _ := Instances(cal, time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 9, 0, 0, 0, 0, time.UTC), nil)
+	for _, i := range is {
+		fmt.Printf("%s - %s %s\n", i.Start.Format("01-02 15:04"), i.End.Format("15:04"), i.Source.GetProperty("SUMMARY").Value)
+	}
+	//Output:
+	//01-02 10:00 - 10:30 Late standup
+	//01-04 00:00 - 00:00 Party
+	//01-04 09:00 - 09:15 Standup
+	//01-05 14:00 - 14:10 Standup in the afternoon
+	//01-06 14:00 - 14:10 Standup in the afternoon
+	//01-07 08:00 - 08:10 Standup in the morning
+	//01-08 14:00 - 14:10 Standup in the afternoon
+}
+
+func TestGroup_Instances(t *testing.T) {
+	cal := parseString(t, testOverrides)
+	groups := GroupByUID(cal)
+	if len(groups) != 2 || groups[0].UID != "daily" || len(groups[0].Overrides) != 4 || groups[1].Master == nil {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+
+	//the first instance was moved into the range, the one after is still in the range when it has begun before
+	is, err := groups[0].Instances(time.Date(2018, 1, 1, 9, 10, 0, 0, time.UTC), time.Date(2018, 1, 2, 9, 5, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(is) != 1 || !is[0].RecurrenceID.Equal(time.Date(2018, 1, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected only the first instance, got %v", is)
+	}
+
+	//an instance moved out of the range is not returned
+	is, err = groups[0].Instances(time.Date(2018, 1, 2, 9, 0, 0, 0, time.UTC), time.Date(2018, 1, 2, 9, 30, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(is) != 0 {
+		t.Errorf("expected no instance, got %v", is)
+	}
+
+	//an instance moved into the range from outside
+	is, err = groups[0].Instances(time.Date(2018, 1, 10, 14, 0, 0, 0, time.UTC), time.Date(2018, 1, 11, 0, 0, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(is) != 1 || !is[0].RecurrenceID.Equal(time.Date(2018, 1, 10, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the last instance, got %v", is)
+	}
+
+	//overrides without master
+	g := &Group{UID: "x", Overrides: groups[0].Overrides[:1]}
+	is, err = g.Instances(time.Time{}, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(is) != 1 || is[0].Source != groups[0].Overrides[0] {
+		t.Errorf("expected the override as instance, got %v", is)
+	}
+}
@@ -0,0 +1,486 @@
+package recurrence
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//maxYear is the last year for which occurrences are generated, which guarantees that rules without any
+// occurrences (e.g. every 30th of February) terminate.
+const maxYear = 9999
+
+//maxPeriods limits the number of periods (e.g. days for FREQ=DAILY) an Iterator examines and maxOccurrences the
+// number of occurrences it returns, so that hostile rules (e.g. FREQ=SECONDLY with rule parts that rarely or never
+// match) can't keep it busy. Both count from the start of the range, see Iterator.SetRange. The periods before the
+// range have a limit of their own.
+var (
+	maxPeriods     = 2000000
+	maxOccurrences = 1000000
+)
+
+//ErrTruncated is returned by Iterator.Err and Set.Between if an Iterator stopped at one of its limits before the
+// end of the recurrence (or the range).
+var ErrTruncated = errors.New("recurrence: too many periods or occurrences to compute")
+
+//Iterator returns the occurrences of a Rule in chronological order. All computations are done on the wall clock
+// time in the location of the start, which keeps the local time of the occurrences stable across DST transitions.
+type Iterator struct {
+	r     Rule
+	start time.Time
+	loc   *time.Location
+
+	//period is the civil (UTC-based) start of the current period
+	period time.Time
+	buf    []time.Time
+	count  int
+	done   bool
+	//periods is the number of periods examined so far, returned the number of occurrences
+	periods  int
+	returned int
+	//after and before are the range given to SetRange, first and last its (civil) periods
+	after, before time.Time
+	first, last   time.Time
+	inRange       bool
+	truncated     bool
+}
+
+//Iterator returns an Iterator over the occurrences of the rule, starting at start (the DTSTART of the component).
+// Occurrences before start are skipped and do not count towards COUNT. The start itself is only returned if it
+// matches the rule, but it always counts as the first occurrence (RFC5545, Section 3.8.5.3).
+func (r *Rule) Iterator(start time.Time) *Iterator {
+	it := &Iterator{r: *r, start: start, loc: start.Location()}
+	if it.r.Interval < 1 {
+		it.r.Interval = 1
+	}
+	if !it.r.WeekStartSet {
+		it.r.WeekStart = time.Monday
+	}
+	it.setDefaults()
+
+	c := wallClock(start, time.UTC)
+	switch it.r.Freq {
+	case Yearly:
+		it.period = date(c.Year(), 1, 1)
+	case Monthly:
+		it.period = date(c.Year(), c.Month(), 1)
+	case Weekly:
+		d := date(c.Year(), c.Month(), c.Day())
+		it.period = d.AddDate(0, 0, -((int(d.Weekday()) - int(it.r.WeekStart) + 7) % 7))
+	case Daily:
+		it.period = date(c.Year(), c.Month(), c.Day())
+	case Hourly:
+		it.period = c.Truncate(time.Hour)
+	case Minutely:
+		it.period = c.Truncate(time.Minute)
+	default:
+		it.period = c.Truncate(time.Second)
+	}
+	return it
+}
+
+//setDefaults fills in the rule parts which are implied by the start, see RFC5545, Section 3.3.10.
+func (it *Iterator) setDefaults() {
+	r, c := &it.r, it.start
+	noDays := len(r.ByWeekNo) == 0 && len(r.ByYearDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) == 0
+	switch {
+	case r.Freq == Yearly && noDays:
+		if len(r.ByMonth) == 0 {
+			r.ByMonth = []int{int(c.Month())}
+		}
+		r.ByMonthDay = []int{c.Day()}
+	case r.Freq == Monthly && noDays:
+		r.ByMonthDay = []int{c.Day()}
+	case r.Freq == Weekly && noDays:
+		r.ByDay = []WeekdayNum{{Day: c.Weekday()}}
+	}
+	if r.Freq > Hourly && len(r.ByHour) == 0 {
+		r.ByHour = []int{c.Hour()}
+	}
+	if r.Freq > Minutely && len(r.ByMinute) == 0 {
+		r.ByMinute = []int{c.Minute()}
+	}
+	if r.Freq > Secondly && len(r.BySecond) == 0 {
+		r.BySecond = []int{c.Second()}
+	}
+}
+
+//SetRange restricts the Iterator to the occurrences t with after <= t < before, a zero before means no end. It has
+// to be called before Next. Unless the rule has a COUNT, the periods before after are skipped without examining them.
+func (it *Iterator) SetRange(after, before time.Time) {
+	it.after, it.before = after, before
+	//the civil times of the range, with a margin for the weeks of BYWEEKNO and DST transitions
+	if !after.IsZero() {
+		it.first = wallClock(after.In(it.loc), time.UTC).AddDate(0, 0, -7)
+	}
+	if !before.IsZero() {
+		it.last = wallClock(before.In(it.loc), time.UTC).AddDate(0, 0, 7)
+	}
+	if it.r.Count == 0 && it.period.Before(it.first) {
+		it.jump(it.first)
+	}
+}
+
+//Next returns the next occurrence. If there is none, ok is false.
+func (it *Iterator) Next() (t time.Time, ok bool) {
+	for len(it.buf) == 0 {
+		if it.done {
+			return time.Time{}, false
+		}
+		it.fill()
+	}
+	if it.returned >= maxOccurrences {
+		it.done, it.buf, it.truncated = true, nil, true
+		return time.Time{}, false
+	}
+	t, it.buf = it.buf[0], it.buf[1:]
+	it.returned++
+	return t, true
+}
+
+//Err returns ErrTruncated if the Iterator stopped at one of its limits, otherwise nil.
+func (it *Iterator) Err() error {
+	if it.truncated {
+		return ErrTruncated
+	}
+	return nil
+}
+
+//fill computes the occurrences of the current period and advances to the next one.
+func (it *Iterator) fill() {
+	p := it.period
+	if !it.inRange && !p.Before(it.first) {
+		//the periods before the range are counted separately
+		it.inRange, it.periods = true, 0
+	}
+	it.periods++
+	switch {
+	case p.Year() > maxYear || (!it.last.IsZero() && !p.Before(it.last)):
+		it.done = true
+		return
+	case it.periods > maxPeriods:
+		it.done, it.truncated = true, true
+		return
+	}
+	switch {
+	//skip the remaining periods of a month, day, hour or minute without any match at once
+	case it.r.Freq < Daily && len(it.r.ByMonth) > 0 && !contains(it.r.ByMonth, int(p.Month())):
+		it.skip(date(p.Year(), p.Month()+1, 1))
+		return
+	case it.r.Freq < Daily && !it.dayMatches(p):
+		it.skip(date(p.Year(), p.Month(), p.Day()+1))
+		return
+	case it.r.Freq < Hourly && len(it.r.ByHour) > 0 && !contains(it.r.ByHour, p.Hour()):
+		it.skip(p.Truncate(time.Hour).Add(time.Hour))
+		return
+	case it.r.Freq < Minutely && len(it.r.ByMinute) > 0 && !contains(it.r.ByMinute, p.Minute()):
+		it.skip(p.Truncate(time.Minute).Add(time.Minute))
+		return
+	}
+
+	cands := it.expand()
+	if len(it.r.BySetPos) > 0 {
+		cands = setPos(cands, it.r.BySetPos)
+	}
+	for _, c := range cands {
+		t := wallClock(c, it.loc)
+		if t.Before(it.start) {
+			continue
+		}
+		if it.afterUntil(t) || (!it.before.IsZero() && !t.Before(it.before)) {
+			it.done = true
+			break
+		}
+		if it.count == 0 && !t.Equal(it.start) {
+			//the start counts as the first occurrence even if it doesn't match the rule
+			it.count = 1
+			if it.r.Count == 1 {
+				it.done = true
+				break
+			}
+		}
+		//occurrences before the range only count towards COUNT
+		if !t.Before(it.after) {
+			it.buf = append(it.buf, t)
+		}
+		it.count++
+		if it.r.Count > 0 && it.count >= it.r.Count {
+			it.done = true
+			break
+		}
+	}
+	it.advance()
+}
+
+//afterUntil checks if t is after the end of the recurrence.
+func (it *Iterator) afterUntil(t time.Time) bool {
+	r := &it.r
+	switch {
+	case r.Until.IsZero():
+		return false
+	case r.UntilIsDate:
+		return date(t.Year(), t.Month(), t.Day()).After(r.Until)
+	case r.UntilFloating:
+		return t.After(wallClock(r.Until, it.loc))
+	}
+	return t.After(r.Until)
+}
+
+//advance moves to the start of the next period.
+func (it *Iterator) advance() {
+	n := it.r.Interval
+	switch it.r.Freq {
+	case Yearly:
+		it.period = it.period.AddDate(n, 0, 0)
+	case Monthly:
+		it.period = it.period.AddDate(0, n, 0)
+	case Weekly:
+		it.period = it.period.AddDate(0, 0, 7*n)
+	case Daily:
+		it.period = it.period.AddDate(0, 0, n)
+	default:
+		it.period = it.period.Add(time.Duration(n) * it.r.Freq.unit())
+	}
+}
+
+//jump advances the period by whole intervals to the last period starting at or before the civil time c.
+func (it *Iterator) jump(c time.Time) {
+	n := it.r.Interval
+	p := it.period
+	switch it.r.Freq {
+	case Yearly:
+		it.period = p.AddDate((c.Year()-p.Year())/n*n, 0, 0)
+	case Monthly:
+		months := (c.Year()-p.Year())*12 + int(c.Month()-p.Month())
+		it.period = p.AddDate(0, months/n*n, 0)
+	case Weekly:
+		weeks := int((c.Unix() - p.Unix()) / (7 * 24 * 3600))
+		it.period = p.AddDate(0, 0, weeks/n*n*7)
+	case Daily:
+		d := int((c.Unix() - p.Unix()) / (24 * 3600))
+		it.period = p.AddDate(0, 0, d/n*n)
+	default:
+		step := int64(n) * int64(it.r.Freq.unit()/time.Second)
+		it.period = time.Unix(p.Unix()+(c.Unix()-p.Unix())/step*step, 0).UTC()
+	}
+}
+
+//skip advances a sub-daily period by whole intervals until it is at or after boundary.
+func (it *Iterator) skip(boundary time.Time) {
+	step := time.Duration(it.r.Interval) * it.r.Freq.unit()
+	steps := (boundary.Sub(it.period) + step - 1) / step
+	it.period = it.period.Add(steps * step)
+}
+
+//unit returns the length of a period with a sub-daily frequency.
+func (f Frequency) unit() time.Duration {
+	switch f {
+	case Hourly:
+		return time.Hour
+	case Minutely:
+		return time.Minute
+	}
+	return time.Second
+}
+
+//expand returns all candidates within the current period in chronological order, as civil times.
+func (it *Iterator) expand() []time.Time {
+	r, p := &it.r, it.period
+	var days []time.Time
+	switch r.Freq {
+	case Yearly:
+		first, end := date(p.Year(), 1, 1), date(p.Year()+1, 1, 1)
+		if len(r.ByWeekNo) > 0 {
+			first, end = week1Start(p.Year(), r.WeekStart), week1Start(p.Year()+1, r.WeekStart)
+		}
+		for d := first; d.Before(end); d = d.AddDate(0, 0, 1) {
+			days = append(days, d)
+		}
+	case Monthly:
+		for d := p; d.Month() == p.Month(); d = d.AddDate(0, 0, 1) {
+			days = append(days, d)
+		}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			days = append(days, p.AddDate(0, 0, i))
+		}
+	default:
+		days = []time.Time{date(p.Year(), p.Month(), p.Day())}
+	}
+
+	hours := timeValues(r.ByHour, p.Hour(), r.Freq > Hourly)
+	minutes := timeValues(r.ByMinute, p.Minute(), r.Freq > Minutely)
+	seconds := timeValues(r.BySecond, p.Second(), r.Freq > Secondly)
+
+	var out []time.Time
+	for _, d := range days {
+		if !it.dayMatches(d) {
+			continue
+		}
+		for _, h := range hours {
+			for _, m := range minutes {
+				for _, s := range seconds {
+					out = append(out, time.Date(d.Year(), d.Month(), d.Day(), h, m, s, 0, time.UTC))
+				}
+			}
+		}
+	}
+	return out
+}
+
+//timeValues returns the sorted values of a BYHOUR/BYMINUTE/BYSECOND rule part if it expands the period, otherwise
+// the current value, if the rule part allows it.
+func timeValues(list []int, cur int, expands bool) []int {
+	if expands {
+		out := append([]int(nil), list...)
+		sort.Ints(out)
+		return out
+	}
+	if len(list) > 0 && !contains(list, cur) {
+		return nil
+	}
+	return []int{cur}
+}
+
+//dayMatches checks the civil date d against all day-based rule parts.
+func (it *Iterator) dayMatches(d time.Time) bool {
+	r := &it.r
+	if len(r.ByMonth) > 0 && !contains(r.ByMonth, int(d.Month())) {
+		return false
+	}
+	if len(r.ByWeekNo) > 0 {
+		wn, weeks := weekNo(d, r.WeekStart)
+		if !containsRel(r.ByWeekNo, wn, weeks) {
+			return false
+		}
+	}
+	yearDays := daysIn(d.Year(), 0)
+	if len(r.ByYearDay) > 0 && !containsRel(r.ByYearDay, d.YearDay(), yearDays) {
+		return false
+	}
+	monthDays := daysIn(d.Year(), d.Month())
+	if len(r.ByMonthDay) > 0 && !containsRel(r.ByMonthDay, d.Day(), monthDays) {
+		return false
+	}
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day != d.Weekday() {
+			continue
+		}
+		switch {
+		case wd.N == 0:
+			return true
+		case r.Freq == Monthly || (r.Freq == Yearly && len(r.ByMonth) > 0):
+			if nthMatches(wd.N, d.Day(), monthDays) {
+				return true
+			}
+		case r.Freq == Yearly && len(r.ByWeekNo) == 0:
+			if nthMatches(wd.N, d.YearDay(), yearDays) {
+				return true
+			}
+		default:
+			//the ordinal is not allowed for other frequencies, ignore it
+			return true
+		}
+	}
+	return false
+}
+
+//nthMatches checks if the day with the (1-based) index i in a period of n days is the nth of its weekday,
+// counting from the end of the period for negative n.
+func nthMatches(nth, i, n int) bool {
+	if nth > 0 {
+		return (i-1)/7+1 == nth
+	}
+	return -((n-i)/7 + 1) == nth
+}
+
+//setPos selects the candidates given by the BYSETPOS rule part.
+func setPos(cands []time.Time, pos []int) []time.Time {
+	var idx []int
+	for _, p := range pos {
+		i := p - 1
+		if p < 0 {
+			i = len(cands) + p
+		}
+		if i >= 0 && i < len(cands) && !contains(idx, i) {
+			idx = append(idx, i)
+		}
+	}
+	sort.Ints(idx)
+	out := make([]time.Time, len(idx))
+	for j, i := range idx {
+		out[j] = cands[i]
+	}
+	return out
+}
+
+//week1Start returns the first day of the first week of the year, which is the first week with at least four days
+// in the year (RFC5545, Section 3.3.10).
+func week1Start(year int, wkst time.Weekday) time.Time {
+	jan1 := date(year, 1, 1)
+	off := (int(jan1.Weekday()) - int(wkst) + 7) % 7
+	if off <= 3 {
+		return jan1.AddDate(0, 0, -off)
+	}
+	return jan1.AddDate(0, 0, 7-off)
+}
+
+//weekNo returns the week number of the civil date d and the number of weeks in its week-numbering year.
+func weekNo(d time.Time, wkst time.Weekday) (week, weeks int) {
+	y := d.Year()
+	start := week1Start(y, wkst)
+	if d.Before(start) {
+		y--
+		start = week1Start(y, wkst)
+	} else if next := week1Start(y+1, wkst); !d.Before(next) {
+		y++
+		start = next
+	}
+	end := week1Start(y+1, wkst)
+	return days(d.Sub(start))/7 + 1, days(end.Sub(start)) / 7
+}
+
+func days(d time.Duration) int {
+	return int(d / (24 * time.Hour))
+}
+
+//daysIn returns the number of days in the month or, if month is 0, in the year.
+func daysIn(year int, month time.Month) int {
+	if month == 0 {
+		return date(year, 12, 31).YearDay()
+	}
+	return date(year, month+1, 0).Day()
+}
+
+//containsRel checks if list contains v, where negative elements count backwards from n.
+func containsRel(list []int, v, n int) bool {
+	for _, x := range list {
+		if x == v || (x < 0 && n+x+1 == v) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+//date returns midnight of the civil date.
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+//wallClock returns the time with the same wall clock reading as t in loc.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
@@ -0,0 +1,308 @@
+//Package recurrence implements recurrence rules (RRULE, RFC5545, Section 3.3.10) and the expansion of recurring
+// components into their occurrences.
+package recurrence
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+//Frequency is the value of the FREQ rule part.
+type Frequency int
+
+//The possible frequencies, ordered from the shortest to the longest interval.
+const (
+	Secondly Frequency = iota + 1
+	Minutely
+	Hourly
+	Daily
+	Weekly
+	Monthly
+	Yearly
+)
+
+var freqNames = map[Frequency]string{
+	Secondly: "SECONDLY",
+	Minutely: "MINUTELY",
+	Hourly:   "HOURLY",
+	Daily:    "DAILY",
+	Weekly:   "WEEKLY",
+	Monthly:  "MONTHLY",
+	Yearly:   "YEARLY",
+}
+
+func (f Frequency) String() string {
+	return freqNames[f]
+}
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+//WeekdayNum is an element of the BYDAY rule part, e.g. "-1SU" for the last Sunday. N is 0 if every
+// such weekday within the period is meant.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return weekdayNames[w.Day]
+	}
+	return strconv.Itoa(w.N) + weekdayNames[w.Day]
+}
+
+//Rule is a parsed recurrence rule. Zero values mean that the rule part is not set, with the exception of
+// WeekStart, for which the zero value (time.Sunday) is only written if WeekStartSet is true, as the default is Monday.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	Count    int
+
+	//Until is the inclusive end of the recurrence. If UntilIsDate is set, it is a DATE value and only the date
+	// is relevant. If UntilFloating is set, the wall clock time of Until is interpreted in the location of the
+	// start of the recurrence, otherwise it is written in UTC.
+	Until         time.Time
+	UntilIsDate   bool
+	UntilFloating bool
+
+	BySecond   []int
+	ByMinute   []int
+	ByHour     []int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByYearDay  []int
+	ByWeekNo   []int
+	ByMonth    []int
+	BySetPos   []int
+
+	WeekStart    time.Weekday
+	WeekStartSet bool
+}
+
+//ParseRule parses a value of the type RECUR, e.g. "FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE".
+func ParseRule(s string) (*Rule, error) {
+	r := &Rule{}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid rule part %q", part)
+		}
+		key, val := strings.ToUpper(kv[0]), kv[1]
+		if seen[key] {
+			return nil, errors.Errorf("rule part %s given more than once", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq = 0
+			for f, name := range freqNames {
+				if strings.EqualFold(val, name) {
+					r.Freq = f
+				}
+			}
+			if r.Freq == 0 {
+				err = errors.Errorf("unknown frequency %q", val)
+			}
+		case "INTERVAL":
+			r.Interval, err = parseInt(val, 1, 0)
+		case "COUNT":
+			r.Count, err = parseInt(val, 1, 0)
+		case "UNTIL":
+			if len(val) == 8 {
+				r.UntilIsDate = true
+				r.Until, err = go_contentline.ParseDate(val, time.UTC)
+			} else {
+				r.UntilFloating = !strings.HasSuffix(val, "Z")
+				r.Until, err = go_contentline.ParseDateTime(val, time.UTC)
+			}
+		case "BYSECOND":
+			//60 (a leap second) can't be represented by time.Time and would never match
+			r.BySecond, err = parseIntList(val, 0, 59, false)
+		case "BYMINUTE":
+			r.ByMinute, err = parseIntList(val, 0, 59, false)
+		case "BYHOUR":
+			r.ByHour, err = parseIntList(val, 0, 23, false)
+		case "BYDAY":
+			r.ByDay, err = parseWeekdayList(val)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseIntList(val, 1, 31, true)
+		case "BYYEARDAY":
+			r.ByYearDay, err = parseIntList(val, 1, 366, true)
+		case "BYWEEKNO":
+			r.ByWeekNo, err = parseIntList(val, 1, 53, true)
+		case "BYMONTH":
+			r.ByMonth, err = parseIntList(val, 1, 12, false)
+		case "BYSETPOS":
+			r.BySetPos, err = parseIntList(val, 1, 366, true)
+		case "WKST":
+			var wd WeekdayNum
+			wd, err = parseWeekday(val)
+			if err == nil && wd.N != 0 {
+				err = errors.Errorf("unexpected ordinal in %q", val)
+			}
+			r.WeekStart, r.WeekStartSet = wd.Day, true
+		default:
+			if !strings.HasPrefix(key, "X-") {
+				err = errors.Errorf("unknown rule part %q", key)
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", key)
+		}
+	}
+	if r.Freq == 0 {
+		return nil, errors.New("missing FREQ")
+	}
+	if r.Count != 0 && !r.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL must not occur both")
+	}
+	if !r.canMatch() {
+		return nil, errors.New("BYMONTHDAY never matches the months of BYMONTH")
+	}
+	return r, nil
+}
+
+//canMatch reports whether a day of BYMONTHDAY exists in one of the months of BYMONTH, which is not the case for
+// e.g. BYMONTH=2;BYMONTHDAY=30.
+func (r *Rule) canMatch() bool {
+	if len(r.ByMonth) == 0 || len(r.ByMonthDay) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		//2000 is a leap year
+		n := daysIn(2000, time.Month(m))
+		for _, d := range r.ByMonthDay {
+			if d <= n && -d <= n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//parseInt parses a number and checks that it is at least min and, if max is not 0, at most max.
+func parseInt(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
+	if err != nil || n < min || (max != 0 && n > max) {
+		return 0, errors.Errorf("%q is not a valid number", s)
+	}
+	return n, nil
+}
+
+//parseIntList parses a comma-separated list of numbers in [min, max], allowing negative numbers in
+// [-max, -min] if neg is set.
+func parseIntList(s string, min, max int, neg bool) ([]int, error) {
+	var out []int
+	for _, v := range strings.Split(s, ",") {
+		n, err := parseInt(strings.TrimPrefix(v, "-"), min, max)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(v, "-") {
+			if !neg {
+				return nil, errors.Errorf("%q must not be negative", v)
+			}
+			n = -n
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func parseWeekdayList(s string) ([]WeekdayNum, error) {
+	var out []WeekdayNum
+	for _, v := range strings.Split(s, ",") {
+		wd, err := parseWeekday(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, wd)
+	}
+	return out, nil
+}
+
+//parseWeekday parses a weekday with an optional ordinal, e.g. "MO" or "+2TU".
+func parseWeekday(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, errors.Errorf("%q is not a valid weekday", s)
+	}
+	var wd WeekdayNum
+	found := false
+	for i, name := range weekdayNames {
+		if strings.EqualFold(s[len(s)-2:], name) {
+			wd.Day, found = time.Weekday(i), true
+		}
+	}
+	if !found {
+		return wd, errors.Errorf("%q is not a valid weekday", s)
+	}
+	if num := s[:len(s)-2]; num != "" {
+		n, err := parseInt(strings.TrimPrefix(num, "-"), 1, 53)
+		if err != nil {
+			return wd, err
+		}
+		if strings.HasPrefix(num, "-") {
+			n = -n
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+//String returns the rule as a value of the type RECUR.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		switch {
+		case r.UntilIsDate:
+			parts = append(parts, "UNTIL="+go_contentline.FormatDate(r.Until))
+		case r.UntilFloating:
+			parts = append(parts, "UNTIL="+go_contentline.FormatDateTime(wallClock(r.Until, time.Local)))
+		default:
+			parts = append(parts, "UNTIL="+go_contentline.FormatDateTime(r.Until.UTC()))
+		}
+	}
+	parts = appendIntList(parts, "BYSECOND", r.BySecond)
+	parts = appendIntList(parts, "BYMINUTE", r.ByMinute)
+	parts = appendIntList(parts, "BYHOUR", r.ByHour)
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	parts = appendIntList(parts, "BYMONTHDAY", r.ByMonthDay)
+	parts = appendIntList(parts, "BYYEARDAY", r.ByYearDay)
+	parts = appendIntList(parts, "BYWEEKNO", r.ByWeekNo)
+	parts = appendIntList(parts, "BYMONTH", r.ByMonth)
+	parts = appendIntList(parts, "BYSETPOS", r.BySetPos)
+	if r.WeekStartSet {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+func appendIntList(parts []string, name string, vals []int) []string {
+	if len(vals) == 0 {
+		return parts
+	}
+	strs := make([]string, len(vals))
+	for i, v := range vals {
+		strs[i] = strconv.Itoa(v)
+	}
+	return append(parts, name+"="+strings.Join(strs, ","))
+}
@@ -0,0 +1,125 @@
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ExampleParseRule() {
+	r, _ := ParseRule("FREQ=MONTHLY;COUNT=3;BYDAY=1FR")
+	it := r.Iterator(time.Date(1997, 9, 5, 9, 0, 0, 0, time.UTC))
+	for t, ok := it.Next(); ok; t, ok = it.Next() {
+		fmt.Println(t.Format("Mon 2006-01-02 15:04"))
+	}
+	//Output:
+	//Fri 1997-09-05 09:00
+	//Fri 1997-10-03 09:00
+	//Fri 1997-11-07 09:00
+}
+
+func TestParseRule(t *testing.T) {
+	//check that everything is written back as it was read
+	for _, in := range []string{
+		"FREQ=DAILY;COUNT=10",
+		"FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;BYDAY=MO,WE,FR;WKST=SU",
+		"FREQ=MONTHLY;UNTIL=19971224;BYDAY=-1SU,+2MO",
+		"FREQ=YEARLY;UNTIL=19971224T100000;BYMONTHDAY=-3,1;BYYEARDAY=1,-366;BYWEEKNO=20;BYMONTH=1,2;BYSETPOS=-1",
+		"FREQ=MINUTELY;BYSECOND=0,59;BYMINUTE=0,30;BYHOUR=9",
+	} {
+		r, err := ParseRule(in)
+		if err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if got := strings.Replace(r.String(), "2MO", "+2MO", 1); got != in {
+			t.Errorf("Wanted: %s\nGot:    %s", in, got)
+		}
+	}
+
+	for _, in := range []string{
+		"",
+		"COUNT=10",
+		"FREQ=FORTNIGHTLY",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=1;UNTIL=19971224",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYMONTH=13",
+		"FREQ=DAILY;BYMONTH=-1",
+		"FREQ=DAILY;BYDAY=MU",
+		"FREQ=DAILY;WKST=1MO",
+		"FREQ=DAILY;UNTIL=1997",
+		"FREQ=DAILY;FOO=BAR",
+		"FREQ=SECONDLY;BYSECOND=60",
+		"FREQ=YEARLY;BYMONTH=2,4;BYMONTHDAY=-31,31",
+	} {
+		if _, err := ParseRule(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestRule_Iterator(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	//most examples are taken from RFC5545, Section 3.8.5.3. A start which doesn't match the rule counts towards
+	// COUNT, but isn't returned.
+	checks := []struct {
+		rule  string
+		start time.Time
+		want  string
+	}{
+		{"FREQ=DAILY;COUNT=3", time.Date(1997, 9, 2, 9, 0, 0, 0, ny),
+			"1997-09-02 09:00,1997-09-03 09:00,1997-09-04 09:00"},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=TU,TH;COUNT=8", time.Date(1997, 9, 2, 9, 0, 0, 0, ny),
+			"1997-09-02 09:00,1997-09-04 09:00,1997-09-16 09:00,1997-09-18 09:00,1997-09-30 09:00,1997-10-02 09:00,1997-10-14 09:00,1997-10-16 09:00"},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=5", time.Date(1997, 9, 29, 9, 0, 0, 0, ny),
+			"1997-09-30 09:00,1997-10-31 09:00,1997-11-28 09:00,1997-12-31 09:00"},
+		{"FREQ=YEARLY;BYWEEKNO=20;BYDAY=MO;COUNT=3", time.Date(1997, 5, 12, 9, 0, 0, 0, ny),
+			"1997-05-12 09:00,1998-05-11 09:00,1999-05-17 09:00"},
+		{"FREQ=YEARLY;BYDAY=20MO;COUNT=3", time.Date(1997, 5, 19, 9, 0, 0, 0, ny),
+			"1997-05-19 09:00,1998-05-18 09:00,1999-05-17 09:00"},
+		{"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=5", time.Date(1997, 9, 2, 9, 0, 0, 0, ny),
+			"1998-02-13 09:00,1998-03-13 09:00,1998-11-13 09:00,1999-08-13 09:00"},
+		{"FREQ=HOURLY;INTERVAL=3;UNTIL=19970902T210000Z", time.Date(1997, 9, 2, 9, 0, 0, 0, ny),
+			"1997-09-02 09:00,1997-09-02 12:00,1997-09-02 15:00"},
+		{"FREQ=DAILY;BYHOUR=9,10,11,12,13,14,15,16;BYMINUTE=0,20,40;COUNT=4", time.Date(1997, 9, 2, 9, 0, 0, 0, ny),
+			"1997-09-02 09:00,1997-09-02 09:20,1997-09-02 09:40,1997-09-02 10:00"},
+		{"FREQ=YEARLY;COUNT=4;BYMONTH=6,7", time.Date(1997, 6, 10, 9, 0, 0, 0, ny),
+			"1997-06-10 09:00,1997-07-10 09:00,1998-06-10 09:00,1998-07-10 09:00"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", time.Date(1997, 8, 5, 9, 0, 0, 0, ny),
+			"1997-08-05 09:00,1997-08-10 09:00,1997-08-19 09:00,1997-08-24 09:00"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", time.Date(1997, 8, 5, 9, 0, 0, 0, ny),
+			"1997-08-05 09:00,1997-08-17 09:00,1997-08-19 09:00,1997-08-31 09:00"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-2;UNTIL=19971231", time.Date(1997, 9, 28, 9, 0, 0, 0, ny),
+			"1997-09-29 09:00,1997-10-30 09:00,1997-11-29 09:00,1997-12-30 09:00"},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;COUNT=3", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+			"2004-02-29 00:00,2008-02-29 00:00"},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;BYDAY=MO;COUNT=4", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+			"2016-02-29 00:00,2044-02-29 00:00,2072-02-29 00:00"},
+		//the wall clock time stays the same across the DST transition
+		{"FREQ=DAILY;UNTIL=19971027T000000", time.Date(1997, 10, 25, 9, 0, 0, 0, ny),
+			"1997-10-25 09:00,1997-10-26 09:00"},
+	}
+	for _, c := range checks {
+		r, err := ParseRule(c.rule)
+		if err != nil {
+			t.Errorf("%s: %v", c.rule, err)
+			continue
+		}
+		var got []string
+		it := r.Iterator(c.start)
+		for o, ok := it.Next(); ok; o, ok = it.Next() {
+			if o.Location() != c.start.Location() {
+				t.Errorf("%s: occurrence %v not in the location of the start", c.rule, o)
+			}
+			got = append(got, o.Format("2006-01-02 15:04"))
+		}
+		if g := strings.Join(got, ","); g != c.want {
+			t.Errorf("%s:\nWanted: %s\nGot:    %s", c.rule, c.want, g)
+		}
+	}
+}
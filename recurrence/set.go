@@ -0,0 +1,117 @@
+package recurrence
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+//Set is a recurrence set as defined in RFC5545, Section 3.8.5.3: the start, all occurrences generated by the rules
+// and all additional dates, without the excluded dates.
+type Set struct {
+	//Start is the value of DTSTART, IsDate is set if it is of the type DATE.
+	Start  time.Time
+	IsDate bool
+
+	Rules   []*Rule
+	RDates  []time.Time
+	ExDates []time.Time
+}
+
+//FromComponent reads the recurrence set of a component (e.g. VEVENT) from its DTSTART, RRULE, RDATE and EXDATE
+// properties. TZID parameters are resolved with resolve, see go_contentline.Property.DateTime.
+func FromComponent(c *go_contentline.Component, resolve go_contentline.TZResolver) (*Set, error) {
+	dtstart := c.GetProperty("DTSTART")
+	if dtstart == nil {
+		return nil, errors.Errorf("%s has no DTSTART", c.Name)
+	}
+	s := &Set{}
+	var err error
+	s.Start, s.IsDate, err = dtstart.DateTime(resolve)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range c.FindProperties("RRULE") {
+		r, err := ParseRule(p.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid RRULE")
+		}
+		s.Rules = append(s.Rules, r)
+	}
+	for _, p := range c.FindProperties("RDATE") {
+		ts, _, err := p.DateTimes(resolve)
+		if err != nil {
+			return nil, err
+		}
+		s.RDates = append(s.RDates, ts...)
+	}
+	for _, p := range c.FindProperties("EXDATE") {
+		ts, _, err := p.DateTimes(resolve)
+		if err != nil {
+			return nil, err
+		}
+		s.ExDates = append(s.ExDates, ts...)
+	}
+	return s, nil
+}
+
+//Between returns all occurrences t with after <= t < before in chronological order. It returns ErrTruncated if a
+// rule has too many periods or occurrences to compute, see Iterator.Err.
+func (s *Set) Between(after, before time.Time) ([]time.Time, error) {
+	var out []time.Time
+	add := func(t time.Time) {
+		if !t.Before(after) && t.Before(before) {
+			out = append(out, t)
+		}
+	}
+
+	add(s.Start)
+	for _, t := range s.RDates {
+		add(t)
+	}
+	for _, r := range s.Rules {
+		it := r.Iterator(s.Start)
+		it.SetRange(after, before)
+		for t, ok := it.Next(); ok; t, ok = it.Next() {
+			add(t)
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	result := out[:0]
+	for i, t := range out {
+		if i > 0 && t.Equal(out[i-1]) {
+			continue
+		}
+		if s.excluded(t) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+//excluded checks if t is listed as EXDATE.
+func (s *Set) excluded(t time.Time) bool {
+	for _, ex := range s.ExDates {
+		if t.Equal(ex) {
+			return true
+		}
+	}
+	return false
+}
+
+//Expand returns the start times of all occurrences of a recurring component in the time range [after, before),
+// see FromComponent and Set.Between.
+func Expand(c *go_contentline.Component, after, before time.Time, resolve go_contentline.TZResolver) ([]time.Time, error) {
+	s, err := FromComponent(c, resolve)
+	if err != nil {
+		return nil, err
+	}
+	return s.Between(after, before)
+}
@@ -0,0 +1,185 @@
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mqus/go-contentline"
+)
+
+func parseString(t *testing.T, in string) *go_contentline.Component {
+	t.Helper()
+	c, err := go_contentline.InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func ExampleExpand() {
+	c, _ := go_contentline.InitParser(strings.NewReader("BEGIN:VEVENT\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20180319T100000\r\n" +
+		"RRULE:FREQ=WEEKLY\r\n" +
+		"EXDATE;TZID=Europe/Berlin:20180402T100000\r\n" +
+		"END:VEVENT\r\n")).ParseNextObject()
+	ts, _ := Expand(c, time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 4, 15, 0, 0, 0, 0, time.UTC), nil)
+	for _, t := range ts {
+		fmt.Println(t.UTC())
+	}
+	//Output:
+	//2018-03-19 09:00:00 +0000 UTC
+	//2018-03-26 08:00:00 +0000 UTC
+	//2018-04-09 08:00:00 +0000 UTC
+}
+
+func TestSet_Between(t *testing.T) {
+	c := parseString(t, "BEGIN:VEVENT\r\n"+
+		"DTSTART;VALUE=DATE:20180101\r\n"+
+		"RRULE:FREQ=MONTHLY;BYMONTHDAY=1,15\r\n"+
+		"RDATE;VALUE=DATE:20180103,20180104\r\n"+
+		"RDATE;VALUE=DATE:20180115\r\n"+
+		"EXDATE;VALUE=DATE:20180201\r\n"+
+		"END:VEVENT\r\n")
+	s, err := FromComponent(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsDate {
+		t.Error("expected DATE start")
+	}
+	ts, err := s.Between(time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range ts {
+		got = append(got, go_contentline.FormatDate(o))
+	}
+	want := "20180103,20180104,20180115,20180215"
+	if g := strings.Join(got, ","); g != want {
+		t.Errorf("Wanted: %s\nGot:    %s", want, g)
+	}
+
+	//a start not matching the rule is still part of the set and counts as the first occurrence
+	c = parseString(t, "BEGIN:VEVENT\r\n"+
+		"DTSTART:20180102T100000Z\r\n"+
+		"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=2\r\n"+
+		"END:VEVENT\r\n")
+	ts, err = Expand(c, time.Time{}, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	for _, o := range ts {
+		got = append(got, go_contentline.FormatDateTime(o))
+	}
+	want = "20180102T100000Z,20180108T100000Z"
+	if g := strings.Join(got, ","); g != want {
+		t.Errorf("Wanted: %s\nGot:    %s", want, g)
+	}
+
+	//errors
+	for _, in := range []string{
+		"BEGIN:VEVENT\r\nRRULE:FREQ=DAILY\r\nEND:VEVENT\r\n",
+		"BEGIN:VEVENT\r\nDTSTART:2018\r\nEND:VEVENT\r\n",
+		"BEGIN:VEVENT\r\nDTSTART;TZID=Nowhere/Atlantis:20180102T100000\r\nEND:VEVENT\r\n",
+		"BEGIN:VEVENT\r\nDTSTART:20180102T100000Z\r\nRRULE:FREQ=NEVER\r\nEND:VEVENT\r\n",
+		"BEGIN:VEVENT\r\nDTSTART:20180102T100000Z\r\nEXDATE:2018\r\nEND:VEVENT\r\n",
+	} {
+		if _, err := FromComponent(parseString(t, in), nil); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestSet_BetweenCount(t *testing.T) {
+	c := parseString(t, "BEGIN:VEVENT\r\n"+
+		"DTSTART:20240131T100000Z\r\n"+
+		"RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3\r\n"+
+		"END:VEVENT\r\n")
+	ts, err := Expand(c, time.Time{}, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range ts {
+		got = append(got, go_contentline.FormatDateTime(o))
+	}
+	want := "20240131T100000Z,20240223T100000Z,20240329T100000Z"
+	if g := strings.Join(got, ","); g != want {
+		t.Errorf("Wanted: %s\nGot:    %s", want, g)
+	}
+}
+
+func TestSet_BetweenNoMatch(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		rule string
+		err  error
+	}{
+		//never matches, which can't be told without trying
+		{"FREQ=SECONDLY;INTERVAL=2;BYSECOND=1", ErrTruncated},
+		{"FREQ=SECONDLY;BYHOUR=3;BYMINUTE=5;BYMONTH=2;BYMONTHDAY=29;BYDAY=MO", nil},
+		{"FREQ=MINUTELY;INTERVAL=2;BYMINUTE=1", ErrTruncated},
+	} {
+		r, err := ParseRule(c.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &Set{Start: start, Rules: []*Rule{r}}
+		done := make(chan error)
+		var ts []time.Time
+		go func() {
+			var err error
+			ts, err = s.Between(start, time.Date(9000, 1, 1, 0, 0, 0, 0, time.UTC))
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != c.err {
+				t.Errorf("%s: Wanted error %v, got %v", c.rule, c.err, err)
+			}
+			if err == nil && (len(ts) == 0 || !ts[0].Equal(start)) {
+				t.Errorf("%s: expected the start as first occurrence, got %v", c.rule, ts)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s: Between didn't return", c.rule)
+		}
+	}
+}
+
+func TestSet_BetweenLimits(t *testing.T) {
+	defer func(periods, occurrences int) { maxPeriods, maxOccurrences = periods, occurrences }(maxPeriods, maxOccurrences)
+	maxPeriods, maxOccurrences = 1000, 100
+
+	//the limits count from the start of the range, not from DTSTART
+	start := time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC)
+	after, before := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 6, 4, 0, 0, 0, 0, time.UTC)
+	for _, rule := range []string{"FREQ=DAILY", "FREQ=HOURLY;INTERVAL=24", "FREQ=WEEKLY;BYDAY=SA,SU,MO"} {
+		r, err := ParseRule(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &Set{Start: start, Rules: []*Rule{r}}
+		ts, err := s.Between(after, before)
+		if err != nil {
+			t.Errorf("%s: %v", rule, err)
+			continue
+		}
+		if len(ts) != 3 || !ts[0].Equal(time.Date(2030, 6, 1, 9, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: unexpected occurrences %v", rule, ts)
+		}
+	}
+
+	//too many occurrences in the range and, with COUNT, too many periods before it are reported instead of
+	// being cut off
+	for _, rule := range []string{"FREQ=HOURLY", "FREQ=DAILY;COUNT=30000"} {
+		r, _ := ParseRule(rule)
+		s := &Set{Start: start, Rules: []*Rule{r}}
+		if _, err := s.Between(after, before.AddDate(0, 0, 10)); err != ErrTruncated {
+			t.Errorf("%s: Wanted ErrTruncated, got %v", rule, err)
+		}
+	}
+}
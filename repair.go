@@ -0,0 +1,105 @@
+package go_contentline
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//Repair describes a change made to a value or parameter by EncoderOptions.Repair.
+type Repair struct {
+	//Property is the repaired property, which itself is not changed.
+	Property *Property
+	//Parameter is the name of the repaired parameter, or empty if the value was repaired.
+	Parameter string
+	//Before and After are the value (or the parameter value) before and after the change.
+	Before, After string
+	//Reason describes the change, e.g. "removed invalid UTF-8".
+	Reason string
+}
+
+//repairProperty returns a repaired copy of p, or p itself if nothing had to be changed. All changes are passed to
+// opts.Repaired.
+func repairProperty(p *Property, opts EncoderOptions) *Property {
+	var out *Property
+	report := func(param, before, after, reason string) {
+		if out == nil {
+			out = p.Clone()
+		}
+		if opts.Repaired != nil {
+			opts.Repaired(Repair{p, param, before, after, reason})
+		}
+	}
+
+	value := repairString(p.Value, "", isText(p), opts, report)
+	//the parameters are sorted, so that the repairs are reported in the order of the output
+	for _, name := range p.Parameters.names() {
+		for i, v := range p.Parameters[name] {
+			if repaired := repairString(v, name, false, opts, report); repaired != v {
+				out.Parameters[name][i] = repaired
+			}
+		}
+	}
+	if out == nil {
+		return p
+	}
+	out.Value = value
+	return out
+}
+
+//repairString applies all repairs to s and calls report for each of them. Line breaks are escaped if text is set
+// (for TEXT values), kept in parameter values (which are escaped by EscapeParamVal) and removed otherwise.
+func repairString(s, param string, text bool, opts EncoderOptions, report func(param, before, after, reason string)) string {
+	apply := func(reason string, fn func(string) string) {
+		if repaired := fn(s); repaired != s {
+			report(param, s, repaired, reason)
+			s = repaired
+		}
+	}
+	apply("removed invalid UTF-8", stripInvalidUTF8)
+	if text {
+		apply(`escaped line breaks as \n`, strings.NewReplacer("\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace)
+	}
+	apply("removed control characters", func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if isForbiddenControl(r, param != "") {
+				return -1
+			}
+			return r
+		}, s)
+	})
+	apply("normalized to NFC", norm.NFC.String)
+	if opts.Normalize != nil {
+		apply("normalized", opts.Normalize)
+	}
+	return s
+}
+
+//isForbiddenControl reports whether r is a control character which must not occur in a content line. HTAB is
+// allowed, line breaks are allowed in parameter values, as EscapeParamVal escapes them.
+func isForbiddenControl(r rune, param bool) bool {
+	switch {
+	case r == '\t':
+		return false
+	case param && (r == '\r' || r == '\n'):
+		return false
+	}
+	return r < 0x20 || r == 0x7F
+}
+
+//stripInvalidUTF8 removes all bytes from s which are not part of a valid UTF-8 sequence.
+func stripInvalidUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r != utf8.RuneError || size > 1 {
+			out = append(out, s[i:i+size]...)
+		}
+		i += size
+	}
+	return string(out)
+}
@@ -0,0 +1,82 @@
+package go_contentline
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncoderOptions_Repair(t *testing.T) {
+	p := NewPropertyUnchecked("NOTE", "bad\xff\r\nline\x00 tab\t", Parameters{"X-P": {"a\x01b\nc"}})
+	var repairs []string
+	opts := EncoderOptions{
+		Repair:    true,
+		Normalize: strings.ToLower,
+		Repaired: func(r Repair) {
+			if r.Property != p {
+				t.Errorf("unexpected property %v", r.Property)
+			}
+			repairs = append(repairs, r.Parameter+": "+r.Reason+": "+r.After)
+		},
+	}
+	var buf bytes.Buffer
+	p.EncodeWithOptions(&buf, opts)
+	if want := "NOTE;X-P=ab^nc:bad\\nline tab\t\r\n"; buf.String() != want {
+		t.Errorf("Wanted %q, got %q", want, buf.String())
+	}
+	want := []string{
+		": removed invalid UTF-8: bad\r\nline\x00 tab\t",
+		": escaped line breaks as \\n: bad\\nline\x00 tab\t",
+		": removed control characters: bad\\nline tab\t",
+		"X-P: removed control characters: ab\nc",
+	}
+	if !reflect.DeepEqual(repairs, want) {
+		t.Errorf("Wanted %q, got %q", want, repairs)
+	}
+	if p.Value != "bad\xff\r\nline\x00 tab\t" || p.Parameters["X-P"][0] != "a\x01b\nc" {
+		t.Errorf("the property was changed: %#v", p)
+	}
+
+	repairs = nil
+	opts.Normalize = strings.ToUpper
+	buf.Reset()
+	p = NewPropertyUnchecked("SUMMARY", "ok", Parameters{})
+	p.EncodeWithOptions(&buf, opts)
+	if buf.String() != "SUMMARY:OK\r\n" || len(repairs) != 1 || repairs[0] != ": normalized: OK" {
+		t.Errorf("unexpected result %q, %q", buf.String(), repairs)
+	}
+}
+
+func TestEncoderOptions_RepairNFC(t *testing.T) {
+	//"e" followed by a combining acute accent, which is "é" in NFC
+	p := NewPropertyUnchecked("ATTACH", "http://example.com/\r\ncafe\u0301", Parameters{
+		"X-B": {"b\x01"}, "X-A": {"a\x01"}, "X-C": {"c\x01"},
+	})
+	var repairs []string
+	opts := EncoderOptions{Repair: true, Repaired: func(r Repair) {
+		repairs = append(repairs, r.Parameter+": "+r.Reason)
+	}}
+	var buf bytes.Buffer
+	p.EncodeWithOptions(&buf, opts)
+	if want := "ATTACH;X-A=a;X-B=b;X-C=c:http://example.com/caf\u00e9\r\n"; buf.String() != want {
+		t.Errorf("Wanted %q, got %q", want, buf.String())
+	}
+	want := []string{
+		": removed control characters",
+		": normalized to NFC",
+		"X-A: removed control characters",
+		"X-B: removed control characters",
+		"X-C: removed control characters",
+	}
+	if !reflect.DeepEqual(repairs, want) {
+		t.Errorf("Wanted %q, got %q", want, repairs)
+	}
+
+	//line breaks are only escaped in TEXT values
+	buf.Reset()
+	NewPropertyUnchecked("X-NOTE", "a\nb", Parameters{"VALUE": {"text"}}).EncodeWithOptions(&buf, opts)
+	if want := "X-NOTE;VALUE=text:a\\nb\r\n"; buf.String() != want {
+		t.Errorf("Wanted %q, got %q", want, buf.String())
+	}
+}
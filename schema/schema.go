@@ -0,0 +1,249 @@
+//Package schema checks components against the rules of RFC5545 (iCalendar) and RFC6350 (vCard): which properties
+// are required or may occur only once, which subcomponents are required and whether values have the format of their
+// value type.
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/internal/valuetype"
+	"github.com/mqus/go-contentline/recurrence"
+)
+
+//Problem is a violation of the rules found by Validate.
+type Problem struct {
+	//Path is the path of the component, e.g. VCALENDAR/VEVENT[2] for the second VEVENT of a calendar.
+	Path string
+	//Component is the component containing the problem.
+	Component *go_contentline.Component
+	//Property is the property containing the problem, or nil if the problem is not caused by a single property.
+	Property *go_contentline.Property
+	Message  string
+}
+
+func (p Problem) String() string {
+	if p.Property != nil {
+		return p.Path + "/" + p.Property.Name + ": " + p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+//cardinality describes how often a property may occur, max is -1 for no limit.
+type cardinality struct {
+	min, max int
+}
+
+var (
+	once      = cardinality{1, 1}
+	optional  = cardinality{0, 1}
+	oneOrMore = cardinality{1, -1}
+)
+
+//rules contains the properties of each component which are required or may occur at most once, as given by
+// RFC5545, Section 3.6 and RFC6350, Section 6.
+var rules = map[string]map[string]cardinality{
+	"VCALENDAR": {"PRODID": once, "VERSION": once, "CALSCALE": optional, "METHOD": optional},
+	"VEVENT": {"DTSTAMP": once, "UID": once, "DTSTART": optional, "CLASS": optional, "CREATED": optional,
+		"DESCRIPTION": optional, "GEO": optional, "LAST-MODIFIED": optional, "LOCATION": optional,
+		"ORGANIZER": optional, "PRIORITY": optional, "SEQUENCE": optional, "STATUS": optional, "SUMMARY": optional,
+		"TRANSP": optional, "URL": optional, "RECURRENCE-ID": optional, "DTEND": optional, "DURATION": optional},
+	"VTODO": {"DTSTAMP": once, "UID": once, "CLASS": optional, "COMPLETED": optional, "CREATED": optional,
+		"DESCRIPTION": optional, "DTSTART": optional, "GEO": optional, "LAST-MODIFIED": optional,
+		"LOCATION": optional, "ORGANIZER": optional, "PERCENT-COMPLETE": optional, "PRIORITY": optional,
+		"RECURRENCE-ID": optional, "SEQUENCE": optional, "STATUS": optional, "SUMMARY": optional, "URL": optional,
+		"DUE": optional, "DURATION": optional},
+	"VJOURNAL": {"DTSTAMP": once, "UID": once, "CLASS": optional, "CREATED": optional, "DTSTART": optional,
+		"LAST-MODIFIED": optional, "ORGANIZER": optional, "RECURRENCE-ID": optional, "SEQUENCE": optional,
+		"STATUS": optional, "SUMMARY": optional, "URL": optional},
+	"VFREEBUSY": {"DTSTAMP": once, "UID": once, "CONTACT": optional, "DTSTART": optional, "DTEND": optional,
+		"ORGANIZER": optional, "URL": optional},
+	"VTIMEZONE": {"TZID": once, "LAST-MODIFIED": optional, "TZURL": optional},
+	"STANDARD":  {"DTSTART": once, "TZOFFSETTO": once, "TZOFFSETFROM": once},
+	"DAYLIGHT":  {"DTSTART": once, "TZOFFSETTO": once, "TZOFFSETFROM": once},
+	"VALARM": {"ACTION": once, "TRIGGER": once, "DURATION": optional, "REPEAT": optional,
+		"DESCRIPTION": optional, "SUMMARY": optional},
+	"VCARD": {"VERSION": once, "FN": oneOrMore, "N": optional, "BDAY": optional, "ANNIVERSARY": optional,
+		"GENDER": optional, "PRODID": optional, "REV": optional, "UID": optional, "KIND": optional},
+}
+
+//exclusive lists pairs of properties of which a component may only contain one.
+var exclusive = map[string][2]string{
+	"VEVENT": {"DTEND", "DURATION"},
+	"VTODO":  {"DUE", "DURATION"},
+}
+
+//Validate checks c and all of its subcomponents and returns the problems found, in the order of the components.
+// Components and properties which are not described by RFC5545 or RFC6350 are not checked, except for the format of
+// their values if they have a VALUE parameter. If c is a VCALENDAR, all TZID parameters must reference one of its
+// VTIMEZONEs.
+func Validate(c *go_contentline.Component) []Problem {
+	v := &validator{}
+	if strings.EqualFold(c.Name, "VCALENDAR") {
+		v.zones = make(map[string]bool)
+		for _, tz := range c.FindSubComponents("VTIMEZONE") {
+			if id := tz.GetProperty("TZID"); id != nil {
+				v.zones[id.Value] = true
+			}
+		}
+	}
+	v.component(strings.ToUpper(c.Name), c, strings.EqualFold(c.Name, "VCARD"))
+	return v.problems
+}
+
+type validator struct {
+	//zones contains the TZIDs of the VTIMEZONEs of the calendar, it is nil if no calendar is validated
+	zones    map[string]bool
+	problems []Problem
+}
+
+func (v *validator) add(path string, c *go_contentline.Component, p *go_contentline.Property, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{path, c, p, fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) component(path string, c *go_contentline.Component, vcard bool) {
+	name := strings.ToUpper(c.Name)
+	table := rules[name]
+	names := make([]string, 0, len(table))
+	for n := range table {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		card := table[n]
+		found := len(c.FindProperties(n))
+		switch {
+		case found < card.min && card.max == -1:
+			v.add(path, c, nil, "%s must contain %s at least once", name, n)
+		case found < card.min:
+			v.add(path, c, nil, "%s must contain %s", name, n)
+		case card.max >= 0 && found > card.max:
+			v.add(path, c, nil, "%s must not contain %s more than once, found %d", name, n, found)
+		}
+	}
+	if pair, ok := exclusive[name]; ok && c.GetProperty(pair[0]) != nil && c.GetProperty(pair[1]) != nil {
+		v.add(path, c, nil, "%s must not contain both %s and %s", name, pair[0], pair[1])
+	}
+
+	switch name {
+	case "VCALENDAR":
+		if len(c.Comps) == 0 {
+			v.add(path, c, nil, "VCALENDAR must contain at least one component")
+		}
+	case "VTIMEZONE":
+		if len(c.FindSubComponents("STANDARD"))+len(c.FindSubComponents("DAYLIGHT")) == 0 {
+			v.add(path, c, nil, "VTIMEZONE must contain at least one STANDARD or DAYLIGHT component")
+		}
+	case "VALARM":
+		v.alarm(path, c)
+	case "VCARD":
+		if ver := c.GetProperty("VERSION"); ver != nil && ver.Value != "4.0" && ver.Value != "3.0" && ver.Value != "2.1" {
+			v.add(path, c, ver, "unknown version %q", ver.Value)
+		}
+	}
+
+	for _, p := range c.Properties {
+		v.property(path, c, p, vcard)
+	}
+
+	count := make(map[string]int)
+	for _, sub := range c.Comps {
+		subName := strings.ToUpper(sub.Name)
+		count[subName]++
+		subPath := path + "/" + subName
+		if n := len(c.FindSubComponents(subName)); n > 1 {
+			subPath += "[" + strconv.Itoa(count[subName]) + "]"
+		}
+		v.component(subPath, sub, vcard || subName == "VCARD")
+	}
+}
+
+//alarm checks the properties required by the ACTION of a VALARM, see RFC5545, Section 3.6.6.
+func (v *validator) alarm(path string, c *go_contentline.Component) {
+	if (c.GetProperty("DURATION") == nil) != (c.GetProperty("REPEAT") == nil) {
+		v.add(path, c, nil, "VALARM must contain both DURATION and REPEAT or neither of them")
+	}
+	action := c.GetProperty("ACTION")
+	if action == nil {
+		return
+	}
+	var required []string
+	switch strings.ToUpper(action.Value) {
+	case "DISPLAY":
+		required = []string{"DESCRIPTION"}
+	case "EMAIL":
+		required = []string{"DESCRIPTION", "SUMMARY", "ATTENDEE"}
+	}
+	for _, n := range required {
+		if c.GetProperty(n) == nil {
+			v.add(path, c, nil, "VALARM with ACTION %s must contain %s", strings.ToUpper(action.Value), n)
+		}
+	}
+}
+
+//property checks the format of the value of p and whether its TZID references a VTIMEZONE.
+func (v *validator) property(path string, c *go_contentline.Component, p *go_contentline.Property, vcard bool) {
+	if v.zones != nil {
+		if id := p.Parameters.Get("TZID"); id != "" && !v.zones[id] && !strings.EqualFold(c.Name, "VTIMEZONE") {
+			v.add(path, c, p, "no VTIMEZONE with TZID %q", id)
+		}
+	}
+	typ := valuetype.Of(p, vcard)
+	if typ == valuetype.Recur {
+		if _, err := recurrence.ParseRule(p.Value); err != nil {
+			v.add(path, c, p, "invalid recurrence rule: %v", err)
+		}
+		return
+	}
+	if valuetype.IsStructured(p.Name, vcard) {
+		return
+	}
+	for _, value := range valuetype.Split(p, vcard) {
+		if msg := checkValue(typ, value[0][0]); msg != "" {
+			v.add(path, c, p, "%s", msg)
+		}
+	}
+}
+
+//checkValue returns a description of the problem if s is not a valid value of the type, otherwise an empty string.
+func checkValue(typ, s string) string {
+	var err error
+	switch typ {
+	case valuetype.DateTime:
+		_, err = go_contentline.ParseDateTime(s, time.UTC)
+	case valuetype.Date:
+		_, err = go_contentline.ParseDate(s, time.UTC)
+	case valuetype.Integer:
+		_, err = strconv.Atoi(s)
+	case valuetype.Float:
+		_, err = strconv.ParseFloat(s, 64)
+	case valuetype.Duration:
+		_, err = go_contentline.ParseDuration(s)
+	case valuetype.UTCOffset:
+		_, err = go_contentline.ParseUTCOffset(s)
+	case valuetype.Period:
+		parts := strings.SplitN(s, "/", 2)
+		if len(parts) != 2 {
+			return fmt.Sprintf("invalid %s value %q", typ, s)
+		}
+		if _, err = go_contentline.ParseDateTime(parts[0], time.UTC); err == nil {
+			if strings.ContainsAny(parts[1], "P") {
+				_, err = go_contentline.ParseDuration(parts[1])
+			} else {
+				_, err = go_contentline.ParseDateTime(parts[1], time.UTC)
+			}
+		}
+	case valuetype.Boolean:
+		if u := strings.ToUpper(s); u != "TRUE" && u != "FALSE" {
+			return fmt.Sprintf("invalid %s value %q", typ, s)
+		}
+	}
+	if err != nil {
+		return fmt.Sprintf("invalid %s value %q", typ, s)
+	}
+	return ""
+}
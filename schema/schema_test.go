@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mqus/go-contentline"
+)
+
+func parse(t *testing.T, s string) *go_contentline.Component {
+	c, err := go_contentline.InitParser(strings.NewReader(s)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		describe string
+		in       string
+		want     []string
+	}{
+		{"valid calendar", "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:x\r\n" +
+			"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\nBEGIN:STANDARD\r\nDTSTART:19700101T000000\r\n" +
+			"TZOFFSETFROM:+0100\r\nTZOFFSETTO:+0100\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n" +
+			"BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20200101T000000Z\r\nDTSTART;TZID=Europe/Berlin:20200101T100000\r\n" +
+			"RRULE:FREQ=DAILY;COUNT=2\r\nBEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:x\r\nTRIGGER:-PT5M\r\n" +
+			"END:VALARM\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n", nil},
+		{"missing and repeated properties", "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nVERSION:2.0\r\n" +
+			"BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20200101T000000Z\r\nEND:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\nDTSTAMP:20200101T000000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n", []string{
+			"VCALENDAR: VCALENDAR must contain PRODID",
+			"VCALENDAR: VCALENDAR must not contain VERSION more than once, found 2",
+			"VCALENDAR/VEVENT[2]: VEVENT must contain UID",
+		}},
+		{"invalid values", "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:x\r\n" +
+			"BEGIN:VTODO\r\nUID:1\r\nDTSTAMP:2020-01-01\r\nDUE;TZID=Nowhere:20200101T100000\r\nDURATION:PT1H\r\n" +
+			"PRIORITY:high\r\nRRULE:FREQ=SOMETIMES\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", []string{
+			"VCALENDAR/VTODO: VTODO must not contain both DUE and DURATION",
+			`VCALENDAR/VTODO/DTSTAMP: invalid date-time value "2020-01-01"`,
+			`VCALENDAR/VTODO/DUE: no VTIMEZONE with TZID "Nowhere"`,
+			`VCALENDAR/VTODO/PRIORITY: invalid integer value "high"`,
+			`VCALENDAR/VTODO/RRULE: invalid recurrence rule: invalid FREQ: unknown frequency "SOMETIMES"`,
+		}},
+		{"alarm and time zone", "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:x\r\n" +
+			"BEGIN:VTIMEZONE\r\nTZID:A\r\nEND:VTIMEZONE\r\n" +
+			"BEGIN:VEVENT\r\nUID:1\r\nDTSTAMP:20200101T000000Z\r\n" +
+			"BEGIN:VALARM\r\nACTION:EMAIL\r\nTRIGGER:-PT5M\r\nREPEAT:2\r\nEND:VALARM\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n", []string{
+			"VCALENDAR/VTIMEZONE: VTIMEZONE must contain at least one STANDARD or DAYLIGHT component",
+			"VCALENDAR/VEVENT/VALARM: VALARM must contain both DURATION and REPEAT or neither of them",
+			"VCALENDAR/VEVENT/VALARM: VALARM with ACTION EMAIL must contain DESCRIPTION",
+			"VCALENDAR/VEVENT/VALARM: VALARM with ACTION EMAIL must contain SUMMARY",
+			"VCALENDAR/VEVENT/VALARM: VALARM with ACTION EMAIL must contain ATTENDEE",
+		}},
+		{"vcard", "BEGIN:VCARD\r\nVERSION:5.0\r\nN:Doe;Jane;;;\r\nN:Doe;J;;;\r\nEND:VCARD\r\n", []string{
+			"VCARD: VCARD must contain FN at least once",
+			"VCARD: VCARD must not contain N more than once, found 2",
+			`VCARD/VERSION: unknown version "5.0"`,
+		}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range Validate(parse(t, tt.in)) {
+			got = append(got, p.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: Wanted\n%s\ngot\n%s", tt.describe, strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
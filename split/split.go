@@ -0,0 +1,157 @@
+//Package split splits files with multiple objects into single objects and joins them again.
+//
+// Calendars are split into one VCALENDAR per UID, as CalDAV servers store them, with only the VTIMEZONEs the
+// components of that UID reference. Joining calendars removes duplicate VTIMEZONEs. Other objects, like the VCARDs
+// of a .vcf file, are already single objects and are kept as they are.
+package split
+
+import (
+	"io"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+)
+
+//Read parses all objects from r and splits the calendars among them, see Calendar.
+func Read(r io.Reader, opts go_contentline.ParserOptions) ([]*go_contentline.Component, error) {
+	p := go_contentline.InitParserWithOptions(r, opts)
+	var out []*go_contentline.Component
+	for {
+		c, err := p.ParseNextObject()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(c.Name, "VCALENDAR") {
+			out = append(out, Calendar(c)...)
+		} else {
+			out = append(out, c)
+		}
+	}
+}
+
+//Write encodes all objects to w, one after another.
+func Write(w io.Writer, objects []*go_contentline.Component) {
+	for _, c := range objects {
+		c.Encode(w)
+	}
+}
+
+//Calendar splits a VCALENDAR into one VCALENDAR per UID, in the order in which the UIDs first occur. Each of them
+// contains the properties of cal, the components with that UID (e.g. a recurring event and its overrides) and
+// copies of the VTIMEZONEs they reference. Components without UID get a VCALENDAR of their own.
+func Calendar(cal *go_contentline.Component) []*go_contentline.Component {
+	zones := make(map[string]*go_contentline.Component)
+	for _, sub := range cal.Comps {
+		if isTimeZone(sub) {
+			if tzid := tzid(sub); tzid != "" && zones[tzid] == nil {
+				zones[tzid] = sub
+			}
+		}
+	}
+
+	var pieces []*go_contentline.Component
+	byUID := make(map[string]*go_contentline.Component)
+	for _, sub := range cal.Comps {
+		if isTimeZone(sub) {
+			continue
+		}
+		uid := ""
+		if p := sub.GetProperty("UID"); p != nil {
+			uid = p.Value
+		}
+		piece := byUID[uid]
+		if piece == nil || uid == "" {
+			piece = &go_contentline.Component{Name: cal.Name}
+			for _, p := range cal.Properties {
+				piece.AddProperty(p.Clone())
+			}
+			pieces = append(pieces, piece)
+			byUID[uid] = piece
+		}
+		piece.AddComponent(sub.Clone())
+	}
+
+	for _, piece := range pieces {
+		var tzs []*go_contentline.Component
+		for _, id := range referencedTZIDs(piece) {
+			if tz := zones[id]; tz != nil {
+				tzs = append(tzs, tz.Clone())
+			}
+		}
+		piece.Comps = append(tzs, piece.Comps...)
+	}
+	return pieces
+}
+
+//Join joins the calendars among objects into a single VCALENDAR at the position of the first one. It has the
+// properties of the first calendar, the VTIMEZONEs of all calendars without duplicates (the first one of each TZID is
+// kept) and all other components. All other objects are kept as they are. The objects are not modified.
+func Join(objects []*go_contentline.Component) []*go_contentline.Component {
+	var out []*go_contentline.Component
+	var joined *go_contentline.Component
+	var tzs, comps []*go_contentline.Component
+	seen := make(map[string]bool)
+	for _, c := range objects {
+		if !strings.EqualFold(c.Name, "VCALENDAR") {
+			out = append(out, c.Clone())
+			continue
+		}
+		if joined == nil {
+			joined = &go_contentline.Component{Name: c.Name}
+			for _, p := range c.Properties {
+				joined.AddProperty(p.Clone())
+			}
+			out = append(out, joined)
+		}
+		for _, sub := range c.Comps {
+			if !isTimeZone(sub) {
+				comps = append(comps, sub.Clone())
+				continue
+			}
+			id := tzid(sub)
+			if id != "" && seen[id] {
+				continue
+			}
+			seen[id] = true
+			tzs = append(tzs, sub.Clone())
+		}
+	}
+	if joined != nil {
+		joined.Comps = append(tzs, comps...)
+	}
+	return out
+}
+
+func isTimeZone(c *go_contentline.Component) bool {
+	return strings.EqualFold(c.Name, "VTIMEZONE")
+}
+
+func tzid(tz *go_contentline.Component) string {
+	if p := tz.GetProperty("TZID"); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+//referencedTZIDs returns the values of all TZID parameters in c and its subcomponents, in the order of their first
+// occurrence.
+func referencedTZIDs(c *go_contentline.Component) []string {
+	var out []string
+	seen := make(map[string]bool)
+	c.Walk(func(path []*go_contentline.Component, sub *go_contentline.Component) error {
+		if isTimeZone(sub) {
+			return go_contentline.SkipComponent
+		}
+		for _, p := range sub.Properties {
+			if id := p.Parameters.Get("TZID"); id != "" && !seen[id] {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+		return nil
+	})
+	return out
+}
@@ -0,0 +1,111 @@
+package split
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mqus/go-contentline"
+)
+
+func timeZone(tzid string) string {
+	return "BEGIN:VTIMEZONE\r\n" +
+		"TZID:" + tzid + "\r\n" +
+		"BEGIN:STANDARD\r\n" +
+		"DTSTART:19700101T000000\r\n" +
+		"TZOFFSETFROM:+0100\r\n" +
+		"TZOFFSETTO:+0100\r\n" +
+		"END:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n"
+}
+
+var testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Test//EN\r\n" +
+	timeZone("Europe/Berlin") +
+	timeZone("Europe/Paris") +
+	timeZone("Unused") +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20200101T100000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:2\r\n" +
+	"DUE;TZID=Europe/Berlin:20200101T100000\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20200108T100000\r\n" +
+	"DTSTART;TZID=Europe/Paris:20200108T110000\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+const testCards = "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:A\r\nEND:VCARD\r\n" +
+	"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:B\r\nEND:VCARD\r\n"
+
+//names lists the names of the subcomponents, with the TZID or UID.
+func names(c *go_contentline.Component) string {
+	var out []string
+	for _, sub := range c.Comps {
+		id := sub.GetProperty("UID")
+		if id == nil {
+			id = sub.GetProperty("TZID")
+		}
+		out = append(out, sub.Name+"/"+id.Value)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestRead(t *testing.T) {
+	objs, err := Read(strings.NewReader(testCalendar+testCards), go_contentline.ParserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 4 {
+		t.Fatalf("expected 4 objects, got %d", len(objs))
+	}
+	want := []string{
+		"VTIMEZONE/Europe/Berlin VTIMEZONE/Europe/Paris VEVENT/1 VEVENT/1",
+		"VTIMEZONE/Europe/Berlin VTODO/2",
+	}
+	for i, w := range want {
+		if got := names(objs[i]); got != w {
+			t.Errorf("object %d: Wanted %q, got %q", i, w, got)
+		}
+		if objs[i].GetProperty("PRODID") == nil {
+			t.Errorf("object %d: missing PRODID", i)
+		}
+	}
+	if objs[2].Name != "VCARD" || objs[3].GetProperty("FN").Value != "B" {
+		t.Errorf("unexpected cards: %v, %v", objs[2], objs[3])
+	}
+
+	if _, err := Read(strings.NewReader("BEGIN:VCARD\r\nEND:VCALENDAR\r\n"), go_contentline.ParserOptions{}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	objs, err := Read(strings.NewReader(testCards+testCalendar), go_contentline.ParserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := Join(objs)
+	if len(joined) != 3 || joined[2].Name != "VCALENDAR" {
+		t.Fatalf("unexpected result: %v", joined)
+	}
+	want := "VTIMEZONE/Europe/Berlin VTIMEZONE/Europe/Paris VEVENT/1 VEVENT/1 VTODO/2"
+	if got := names(joined[2]); got != want {
+		t.Errorf("Wanted %q, got %q", want, got)
+	}
+
+	var buf bytes.Buffer
+	Write(&buf, joined)
+	again, err := Read(&buf, go_contentline.ParserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != len(objs) {
+		t.Errorf("expected %d objects after writing, got %d", len(objs), len(again))
+	}
+}
@@ -0,0 +1,47 @@
+package go_contentline
+
+import "strings"
+
+//textUnescaper and textEscaper implement the escaping of TEXT values (RFC5545, Section 3.3.11 and RFC6350,
+// Section 3.4). A backslash before any other character is kept.
+var (
+	textUnescaper = strings.NewReplacer("\\\\", "\\", "\\n", "\n", "\\N", "\n", "\\,", ",", "\\;", ";")
+	textEscaper   = strings.NewReplacer("\\", "\\\\", "\r\n", "\\n", "\n", "\\n", "\r", "\\n", ",", "\\,", ";", "\\;")
+)
+
+//UnescapeText returns the text contained in a TEXT value, with escaped backslashes, commas, semicolons and newlines
+// replaced by the characters they represent.
+func UnescapeText(value string) string {
+	return textUnescaper.Replace(value)
+}
+
+//EscapeText returns the TEXT value for a text, which escapes backslashes, commas, semicolons and newlines.
+func EscapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+//SplitText splits a TEXT value with multiple values (e.g. CATEGORIES) at the commas which are not escaped and
+// unescapes the parts.
+func SplitText(value string) []string {
+	parts := splitEscaped(value, ',')
+	for i := range parts {
+		parts[i] = UnescapeText(parts[i])
+	}
+	return parts
+}
+
+//splitEscaped splits a value at the separators which are not escaped by a backslash. The parts are not unescaped.
+func splitEscaped(value string, sep byte) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			out = append(out, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, value[start:])
+}
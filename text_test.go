@@ -0,0 +1,28 @@
+package go_contentline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscapeText(t *testing.T) {
+	checks := map[string]string{
+		"plain":                 "plain",
+		"a, b; c\\d":            "a\\, b\\; c\\\\d",
+		"line 1\nline 2\r\nend": "line 1\\nline 2\\nend",
+	}
+	for in, want := range checks {
+		if got := EscapeText(in); got != want {
+			t.Errorf("EscapeText(%q): Wanted %q, got %q", in, want, got)
+		}
+		if got := UnescapeText(want); got != in && in != "line 1\nline 2\r\nend" {
+			t.Errorf("UnescapeText(%q): Wanted %q, got %q", want, in, got)
+		}
+	}
+	if got := UnescapeText("a\\Nb\\x"); got != "a\nb\\x" {
+		t.Errorf("unexpected result of UnescapeText: %q", got)
+	}
+	if got := SplitText("work,a\\,b,c\\\\,"); !reflect.DeepEqual(got, []string{"work", "a,b", "c\\", ""}) {
+		t.Errorf("unexpected result of SplitText: %q", got)
+	}
+}
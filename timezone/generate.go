@@ -0,0 +1,187 @@
+package timezone
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mqus/go-contentline"
+)
+
+//probeStep is the distance in which the offset of a location is sampled to find its transitions.
+const probeStep = 12 * time.Hour
+
+//onset is a transition found in a time.Location
+type onset struct {
+	at       time.Time
+	from, to int
+	name     string
+}
+
+//local returns the onset as wall clock time before the transition, as used by DTSTART and RDATE.
+func (o onset) local() time.Time {
+	return o.at.In(time.FixedZone("", o.from))
+}
+
+//ToComponent generates a VTIMEZONE component for loc, which describes the time zone at least from the year before
+// from until to. Yearly repeating transitions on the same weekday are combined into RRULEs, all others are listed as
+// RDATEs. The TZID is the name of the location, e.g. Europe/Berlin.
+func ToComponent(loc *time.Location, from, to time.Time) *go_contentline.Component {
+	onsets := findOnsets(loc, from.AddDate(-1, 0, 0), to)
+	out := &go_contentline.Component{Name: "VTIMEZONE"}
+	out.AddProperty(newProperty("TZID", loc.String()))
+
+	if len(onsets) == 0 {
+		name, off := from.In(loc).Zone()
+		out.AddComponent(observance("STANDARD", time.Date(1970, 1, 1, 0, 0, 0, 0, time.FixedZone("", off)), off, off, name))
+		return out
+	}
+
+	//onsets with the same offsets and name are described by the same observance, as far as possible with RRULEs
+	var groups [][]onset
+	for _, o := range onsets {
+		found := false
+		for i, g := range groups {
+			if g[0].from == o.from && g[0].to == o.to && g[0].name == o.name {
+				groups[i] = append(g, o)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, []onset{o})
+		}
+	}
+
+	for _, g := range groups {
+		var single []onset
+		for len(g) > 0 {
+			n, byDay := yearlyRun(g)
+			if n < 2 {
+				single = append(single, g[0])
+				g = g[1:]
+				continue
+			}
+			first, last := g[0], g[n-1]
+			rule := "FREQ=YEARLY;BYMONTH=" + strconv.Itoa(int(first.local().Month())) + ";BYDAY=" + byDay
+			if !last.at.AddDate(1, 0, 0).After(to) {
+				rule += ";UNTIL=" + go_contentline.FormatDateTime(last.at.UTC())
+			}
+			obs := observance(kind(first), first.local(), first.from, first.to, first.name)
+			obs.AddProperty(newProperty("RRULE", rule))
+			out.AddComponent(obs)
+			g = g[n:]
+		}
+		if len(single) > 0 {
+			obs := observance(kind(single[0]), single[0].local(), single[0].from, single[0].to, single[0].name)
+			if len(single) > 1 {
+				dates := make([]string, len(single)-1)
+				for i, o := range single[1:] {
+					dates[i] = go_contentline.FormatDateTime(o.local())
+				}
+				obs.AddProperty(newProperty("RDATE", strings.Join(dates, ",")))
+			}
+			out.AddComponent(obs)
+		}
+	}
+	return out
+}
+
+//findOnsets samples the offset of loc to find all transitions in [from, to].
+func findOnsets(loc *time.Location, from, to time.Time) []onset {
+	var out []onset
+	prevName, prevOff := from.In(loc).Zone()
+	for t := from; t.Before(to); {
+		next := t.Add(probeStep)
+		name, off := next.In(loc).Zone()
+		if name != prevName || off != prevOff {
+			//binary search for the first second of the new zone
+			lo, hi := t, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+				if n, o := mid.In(loc).Zone(); n == prevName && o == prevOff {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			out = append(out, onset{hi.UTC(), prevOff, off, name})
+			prevName, prevOff = name, off
+		}
+		t = next
+	}
+	return out
+}
+
+//yearlyRun returns the number of onsets at the start of g which take place in consecutive years in the same month,
+// on the same weekday and at the same time, and the BYDAY value describing them.
+func yearlyRun(g []onset) (n int, byDay string) {
+	first := g[0].local()
+	cands := nthCandidates(first)
+	for n = 1; n < len(g); n++ {
+		t, prev := g[n].local(), g[n-1].local()
+		if t.Year() != prev.Year()+1 || t.Month() != first.Month() || t.Weekday() != first.Weekday() ||
+			t.Hour() != first.Hour() || t.Minute() != first.Minute() || t.Second() != first.Second() {
+			break
+		}
+		var common []int
+		for _, c := range nthCandidates(t) {
+			for _, c2 := range cands {
+				if c == c2 {
+					common = append(common, c)
+				}
+			}
+		}
+		if len(common) == 0 {
+			break
+		}
+		cands = common
+	}
+	//prefer the last weekday of the month, as it is the more common rule
+	nth := cands[0]
+	for _, c := range cands {
+		if c == -1 {
+			nth = -1
+		}
+	}
+	return n, strconv.Itoa(nth) + weekdayAbbr(first.Weekday())
+}
+
+//nthCandidates returns the ordinals describing the weekday of t in its month: counted from the start and,
+// if it is the last one, -1.
+func nthCandidates(t time.Time) []int {
+	out := []int{(t.Day()-1)/7 + 1}
+	if t.AddDate(0, 0, 7).Month() != t.Month() {
+		out = append(out, -1)
+	}
+	return out
+}
+
+//weekdayAbbr returns the two-letter abbreviation of the weekday.
+func weekdayAbbr(wd time.Weekday) string {
+	return strings.ToUpper(wd.String()[:2])
+}
+
+//kind returns DAYLIGHT if the onset moves the clock forward, STANDARD otherwise.
+func kind(o onset) string {
+	if o.to > o.from {
+		return "DAYLIGHT"
+	}
+	return "STANDARD"
+}
+
+//observance creates a STANDARD or DAYLIGHT component.
+func observance(name string, start time.Time, from, to int, tzname string) *go_contentline.Component {
+	obs := &go_contentline.Component{Name: name}
+	obs.AddProperty(
+		newProperty("DTSTART", go_contentline.FormatDateTime(start)),
+		newProperty("TZOFFSETFROM", go_contentline.FormatUTCOffset(from)),
+		newProperty("TZOFFSETTO", go_contentline.FormatUTCOffset(to)),
+		newProperty("TZNAME", tzname),
+	)
+	return obs
+}
+
+func newProperty(name, value string) *go_contentline.Property {
+	return go_contentline.NewPropertyUnchecked(name, value, make(go_contentline.Parameters))
+}
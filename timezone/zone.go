@@ -0,0 +1,254 @@
+//Package timezone converts between VTIMEZONE components (RFC5545, Section 3.6.5) and time.Location.
+//
+// Generating VTIMEZONE components needs the IANA time zone database, which time.LoadLocation reads from the system.
+// Programs running on systems without it can embed it by importing the package time/tzdata (Go 1.15 and later).
+package timezone
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/recurrence"
+	"github.com/pkg/errors"
+)
+
+//horizonYear is the year up to which the onsets of open-ended observances are computed.
+const horizonYear = 2100
+
+//maxOnsets is the maximum number of onsets of an observance up to horizonYear.
+const maxOnsets = 10000
+
+//Zone is a time zone as described by a VTIMEZONE component.
+type Zone struct {
+	//ID is the value of the TZID property.
+	ID string
+
+	initial     zone
+	transitions []transition
+}
+
+//zone describes the local time between two transitions.
+type zone struct {
+	name   string
+	offset int
+	isDST  bool
+}
+
+//transition is the onset of an observance.
+type transition struct {
+	at time.Time
+	zone
+}
+
+//FromComponent reads a VTIMEZONE component with its STANDARD and DAYLIGHT observances. The onsets of observances
+// without end are computed up to the year 2100. Observances recurring more often than monthly or with more than 10000
+// onsets are rejected.
+func FromComponent(c *go_contentline.Component) (*Zone, error) {
+	if c.Name != "VTIMEZONE" {
+		return nil, errors.Errorf("expected VTIMEZONE, got %s", c.Name)
+	}
+	tzid := c.GetProperty("TZID")
+	if tzid == nil {
+		return nil, errors.New("VTIMEZONE has no TZID")
+	}
+	z := &Zone{ID: tzid.Value}
+	horizon := time.Date(horizonYear+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	var first time.Time
+	for _, obs := range c.Comps {
+		if obs.Name != "STANDARD" && obs.Name != "DAYLIGHT" {
+			continue
+		}
+		from, to, err := offsets(obs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: %s", z.ID, obs.Name)
+		}
+		//onsets are given in the local time before the transition
+		fixed := time.FixedZone(go_contentline.FormatUTCOffset(from), from)
+		set, err := recurrence.FromComponent(obs, func(string) (*time.Location, error) { return fixed, nil })
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: %s", z.ID, obs.Name)
+		}
+		for _, r := range set.Rules {
+			if r.Freq < recurrence.Monthly {
+				return nil, errors.Errorf("%s: %s recurs %s", z.ID, obs.Name, r.Freq)
+			}
+		}
+		onsets, err := set.Between(time.Time{}, horizon)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: %s", z.ID, obs.Name)
+		}
+		if len(onsets) > maxOnsets {
+			return nil, errors.Errorf("%s: %s has more than %d onsets", z.ID, obs.Name, maxOnsets)
+		}
+		zn := zone{go_contentline.FormatUTCOffset(to), to, obs.Name == "DAYLIGHT"}
+		if name := obs.GetProperty("TZNAME"); name != nil {
+			zn.name = name.Value
+		}
+		for _, t := range onsets {
+			z.transitions = append(z.transitions, transition{t.UTC(), zn})
+		}
+		//the time before the first onset is described by TZOFFSETFROM of the earliest observance
+		if first.IsZero() || set.Start.Before(first) {
+			first = set.Start
+			z.initial = zone{go_contentline.FormatUTCOffset(from), from, false}
+		}
+	}
+	if len(z.transitions) == 0 {
+		return nil, errors.Errorf("%s: no observances", z.ID)
+	}
+	sort.Slice(z.transitions, func(i, j int) bool { return z.transitions[i].at.Before(z.transitions[j].at) })
+
+	for _, t := range z.transitions {
+		if t.offset == z.initial.offset && !t.isDST {
+			z.initial.name = t.name
+			break
+		}
+	}
+	return z, nil
+}
+
+//offsets reads TZOFFSETFROM and TZOFFSETTO of an observance.
+func offsets(obs *go_contentline.Component) (from, to int, err error) {
+	fromProp, toProp := obs.GetProperty("TZOFFSETFROM"), obs.GetProperty("TZOFFSETTO")
+	if fromProp == nil || toProp == nil || obs.GetProperty("DTSTART") == nil {
+		return 0, 0, errors.New("DTSTART, TZOFFSETFROM and TZOFFSETTO are required")
+	}
+	if from, err = go_contentline.ParseUTCOffset(fromProp.Value); err != nil {
+		return 0, 0, err
+	}
+	to, err = go_contentline.ParseUTCOffset(toProp.Value)
+	return from, to, err
+}
+
+//Lookup returns the abbreviated name of the zone in effect at time t and its offset in seconds east of UTC.
+func (z *Zone) Lookup(t time.Time) (name string, offset int) {
+	i := sort.Search(len(z.transitions), func(i int) bool { return z.transitions[i].at.After(t) })
+	if i == 0 {
+		return z.initial.name, z.initial.offset
+	}
+	return z.transitions[i-1].name, z.transitions[i-1].offset
+}
+
+//Location returns a time.Location which behaves like the zone. Its name is the TZID of the zone. It returns an
+// error for zones which don't fit into the TZif format, i.e. with more than 256 distinct offsets and names or more
+// than 255 bytes of names before the last one.
+func (z *Zone) Location() (*time.Location, error) {
+	data, err := z.tzdata()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: could not create location", z.ID)
+	}
+	loc, err := time.LoadLocationFromTZData(z.ID, data)
+	return loc, errors.Wrapf(err, "%s: could not create location", z.ID)
+}
+
+//tzdata encodes the zone in the TZif format (RFC8536, version 2) as understood by time.LoadLocationFromTZData.
+func (z *Zone) tzdata() ([]byte, error) {
+	var types []zone
+	var chars bytes.Buffer
+	nameIdx := make(map[string]int)
+	typeIdx := func(zn zone) int {
+		for i, t := range types {
+			if t == zn {
+				return i
+			}
+		}
+		if _, ok := nameIdx[zn.name]; !ok {
+			nameIdx[zn.name] = chars.Len()
+			chars.WriteString(zn.name)
+			chars.WriteByte(0)
+		}
+		types = append(types, zn)
+		return len(types) - 1
+	}
+	typeIdx(z.initial)
+	idx := make([]byte, len(z.transitions))
+	for i, t := range z.transitions {
+		n := typeIdx(t.zone)
+		//both indexes are stored as a single byte
+		if n > 255 {
+			return nil, errors.New("more than 256 distinct local time types")
+		}
+		if nameIdx[t.name] > 255 {
+			return nil, errors.New("more than 255 bytes of time zone abbreviations")
+		}
+		idx[i] = byte(n)
+	}
+
+	var buf bytes.Buffer
+	header := func(timecnt, typecnt, charcnt int) {
+		buf.WriteString("TZif2")
+		buf.Write(make([]byte, 15))
+		for _, n := range []int{0, 0, 0, timecnt, typecnt, charcnt} {
+			binary.Write(&buf, binary.BigEndian, uint32(n))
+		}
+	}
+	ttinfo := func(zn zone) {
+		binary.Write(&buf, binary.BigEndian, int32(zn.offset))
+		if zn.isDST {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		buf.WriteByte(byte(nameIdx[zn.name]))
+	}
+
+	//the version 1 part is skipped by all readers of version 2, so it only contains a single type
+	header(0, 1, chars.Len())
+	ttinfo(z.initial)
+	buf.Write(chars.Bytes())
+
+	header(len(z.transitions), len(types), chars.Len())
+	for _, t := range z.transitions {
+		binary.Write(&buf, binary.BigEndian, t.at.Unix())
+	}
+	buf.Write(idx)
+	for _, t := range types {
+		ttinfo(t)
+	}
+	buf.Write(chars.Bytes())
+	//empty footer, the last transition stays in effect
+	buf.WriteString("\n\n")
+	return buf.Bytes(), nil
+}
+
+//Resolver returns a TZResolver which resolves TZIDs to the VTIMEZONE components contained in the VCALENDAR cal.
+// All other TZIDs (and floating times) are resolved with fallback, which defaults to
+// go_contentline.DefaultTZResolver if nil. The returned function is safe for concurrent use.
+func Resolver(cal *go_contentline.Component, fallback go_contentline.TZResolver) go_contentline.TZResolver {
+	if fallback == nil {
+		fallback = go_contentline.DefaultTZResolver
+	}
+	comps := make(map[string]*go_contentline.Component)
+	for _, c := range cal.FindSubComponents("VTIMEZONE") {
+		if tzid := c.GetProperty("TZID"); tzid != nil {
+			comps[tzid.Value] = c
+		}
+	}
+	var mu sync.Mutex
+	cache := make(map[string]*time.Location)
+	return func(tzid string) (*time.Location, error) {
+		c, ok := comps[tzid]
+		if !ok {
+			return fallback(tzid)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if loc, ok := cache[tzid]; ok {
+			return loc, nil
+		}
+		z, err := FromComponent(c)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := z.Location()
+		if err != nil {
+			return nil, err
+		}
+		cache[tzid] = loc
+		return loc, nil
+	}
+}
@@ -0,0 +1,181 @@
+package timezone
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mqus/go-contentline"
+)
+
+//testTimezone is the example from RFC5545, Section 3.6.5 with a changed TZID
+const testTimezone = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:US-Eastern\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19671029T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10;UNTIL=20061029T060000Z\r\n" +
+	"TZOFFSETFROM:-0400\r\n" +
+	"TZOFFSETTO:-0500\r\n" +
+	"TZNAME:EST\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:20071104T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=1SU;BYMONTH=11\r\n" +
+	"TZOFFSETFROM:-0400\r\n" +
+	"TZOFFSETTO:-0500\r\n" +
+	"TZNAME:EST\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:19870405T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=1SU;BYMONTH=4;UNTIL=20060402T070000Z\r\n" +
+	"TZOFFSETFROM:-0500\r\n" +
+	"TZOFFSETTO:-0400\r\n" +
+	"TZNAME:EDT\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:20070311T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=2SU;BYMONTH=3\r\n" +
+	"TZOFFSETFROM:-0500\r\n" +
+	"TZOFFSETTO:-0400\r\n" +
+	"TZNAME:EDT\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=US-Eastern:20180310T120000\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func parseString(t *testing.T, in string) *go_contentline.Component {
+	t.Helper()
+	c, err := go_contentline.InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+//compareLocations checks that both locations have the same offsets in the time range
+func compareLocations(t *testing.T, want, got *time.Location, from, to time.Time) {
+	t.Helper()
+	for ts := from; ts.Before(to); ts = ts.Add(time.Hour) {
+		wantName, wantOff := ts.In(want).Zone()
+		gotName, gotOff := ts.In(got).Zone()
+		if wantName != gotName || wantOff != gotOff {
+			t.Fatalf("%v: Wanted %s (%d), Got %s (%d)", ts, wantName, wantOff, gotName, gotOff)
+		}
+	}
+}
+
+func TestFromComponent(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	cal := parseString(t, testTimezone)
+	z, err := FromComponent(cal.Comps[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := z.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.String() != "US-Eastern" {
+		t.Errorf("unexpected name %s", loc)
+	}
+	compareLocations(t, ny, loc, time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	if name, off := z.Lookup(time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)); name != "EDT" || off != -4*3600 {
+		t.Errorf("unexpected zone %s (%d)", name, off)
+	}
+
+	//resolve the TZID of the event
+	resolve := Resolver(cal, nil)
+	start, _, err := cal.Comps[1].GetProperty("DTSTART").DateTime(resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2018, 3, 10, 17, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("Wanted %v, Got %v", want, start)
+	}
+	if loc, err := resolve("Europe/Berlin"); err != nil || loc.String() != "Europe/Berlin" {
+		t.Errorf("expected the fallback, got %v (%v)", loc, err)
+	}
+
+	for _, in := range []string{
+		"BEGIN:VTIMEZONE\r\nBEGIN:STANDARD\r\nDTSTART:19671029T020000\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:x\r\nEND:VTIMEZONE\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:x\r\nBEGIN:STANDARD\r\nDTSTART:19671029T020000\r\nTZOFFSETTO:-0500\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:x\r\nBEGIN:STANDARD\r\nDTSTART:19671029T020000\r\nTZOFFSETFROM:-04\r\nTZOFFSETTO:-0500\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n",
+		"BEGIN:VEVENT\r\nEND:VEVENT\r\n",
+		//too many onsets
+		"BEGIN:VTIMEZONE\r\nTZID:x\r\nBEGIN:STANDARD\r\nDTSTART:19700101T000000\r\nRRULE:FREQ=SECONDLY\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:x\r\nBEGIN:STANDARD\r\nDTSTART:19700101T000000\r\nRRULE:FREQ=YEARLY;BYMONTH=1;BYMONTHDAY=1,2,3,4,5,6,7,8,9,10;BYHOUR=0,1,2,3,4,5,6,7,8,9,10,11\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n",
+	} {
+		if _, err := FromComponent(parseString(t, in)); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestLocation_Limits(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	//300 offsets with the same name and 100 names with the same offset
+	types := &Zone{ID: "types", initial: zone{"A", 0, false}}
+	names := &Zone{ID: "names", initial: zone{"A", 0, false}}
+	for i := 0; i < 300; i++ {
+		at := start.AddDate(0, 0, i)
+		types.transitions = append(types.transitions, transition{at, zone{"A", i * 60, false}})
+		if i < 100 {
+			names.transitions = append(names.transitions, transition{at, zone{fmt.Sprintf("N%03d", i), 0, false}})
+		}
+	}
+	for _, z := range []*Zone{types, names} {
+		if _, err := z.Location(); err == nil {
+			t.Errorf("%s: expected an error", z.ID)
+		}
+		z.transitions = z.transitions[:50]
+		if _, err := z.Location(); err != nil {
+			t.Errorf("%s: %v", z.ID, err)
+		}
+	}
+}
+
+func TestToComponent(t *testing.T) {
+	from, to := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"Europe/Berlin", "America/New_York", "Australia/Lord_Howe", "Asia/Kolkata", "America/Sao_Paulo", "UTC"} {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Skip(err)
+		}
+		c := ToComponent(loc, from, to)
+
+		//encode and parse again
+		var buf bytes.Buffer
+		c.Encode(&buf)
+		z, err := FromComponent(parseString(t, buf.String()))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := z.Location()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		compareLocations(t, loc, got, from, to)
+	}
+
+	//yearly transitions are combined
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	c := ToComponent(loc, from, to)
+	std := c.FindSubComponents("STANDARD")
+	if len(c.Comps) != 2 || len(std) != 1 || std[0].GetProperty("RRULE").Value != "FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU" {
+		var buf bytes.Buffer
+		c.Encode(&buf)
+		t.Errorf("expected one rule for each observance, got:\n%s", buf.String())
+	}
+}
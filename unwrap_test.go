@@ -0,0 +1,19 @@
+//go:build go1.13
+// +build go1.13
+
+package go_contentline
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseError_Unwrap(t *testing.T) {
+	in := "BEGIN:A\r\nX;P=a;Q=b:v\r\nEND:A\r\n"
+	_, err := InitParserWithOptions(strings.NewReader(in), ParserOptions{Limits: Limits{MaxParameters: 1}}).ParseNextObject()
+	var limit *ParameterCountError
+	if !errors.As(err, &limit) || limit.Max != 1 {
+		t.Errorf("Wanted a *ParameterCountError, got %v", err)
+	}
+}
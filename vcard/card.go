@@ -0,0 +1,338 @@
+package vcard
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+//Card is a typed model of a VCARD component. Properties which are unknown, repeated where only one is expected or
+// have values or parameters which can't be represented by the typed fields (e.g. because they are escaped
+// differently) are kept in Extra, so that converting a card to a Card and back keeps all of its content. The order of
+// the properties is not kept.
+type Card struct {
+	Version       string //VERSION
+	Name          *Name  //N
+	FormattedName string //FN
+
+	Emails    []*Email   //EMAIL
+	Phones    []*Phone   //TEL
+	Addresses []*Address //ADR
+	Orgs      []*Org     //ORG
+	Photos    []*Photo   //PHOTO
+	Related   []*Related //RELATED
+
+	Birthday    *Date //BDAY
+	Anniversary *Date //ANNIVERSARY
+
+	//Extra contains all properties which can't be represented by the typed fields.
+	Extra []*go_contentline.Property
+}
+
+//Meta contains the group and the parameters of a property.
+type Meta struct {
+	//Group is the group of the property, e.g. item1 for item1.EMAIL.
+	Group string
+	//Types are the values of the TYPE parameter, e.g. work or home.
+	Types []string
+	//Pref is the value of the PREF parameter (1 is the most preferred), or 0 if there is none.
+	Pref int
+	//Params contains all other parameters.
+	Params go_contentline.Parameters
+}
+
+//Name is the structured name of the card (N). Each component can contain multiple values.
+type Name struct {
+	Meta
+	FamilyNames       []string
+	GivenNames        []string
+	AdditionalNames   []string
+	HonorificPrefixes []string
+	HonorificSuffixes []string
+}
+
+//Email is an email address of the card (EMAIL).
+type Email struct {
+	Meta
+	Address string
+}
+
+//Phone is a telephone number of the card (TEL), either as text or as tel: URI.
+type Phone struct {
+	Meta
+	Number string
+}
+
+//Address is a delivery address of the card (ADR). Each component can contain multiple values, e.g. the lines of
+// the street address.
+type Address struct {
+	Meta
+	POBox      []string
+	Extended   []string
+	Street     []string
+	Locality   []string
+	Region     []string
+	PostalCode []string
+	Country    []string
+}
+
+//Org is an organization of the card (ORG) and its units.
+type Org struct {
+	Meta
+	Name  string
+	Units []string
+}
+
+//Photo is a photo of the card (PHOTO). In vCard 4.0 the value is a URI, which can be a data URI. vCard 2.1 and 3.0
+// also allow inline base64 data, which is marked by the ENCODING parameter in Params.
+type Photo struct {
+	Meta
+	Value string
+}
+
+//Data returns the inline data of the photo and its media type, see go_contentline.Property.Binary.
+func (ph *Photo) Data() ([]byte, string, error) {
+	return ph.Meta.property("PHOTO", ph.Value).Binary()
+}
+
+//Related is a relationship to another entity (RELATED), a URI or, with VALUE=text in Params, a text.
+type Related struct {
+	Meta
+	Value string
+}
+
+//FromComponent converts a VCARD component.
+func FromComponent(c *go_contentline.Component) (*Card, error) {
+	if c.Name != "VCARD" {
+		return nil, errors.Errorf("expected VCARD, got %s", c.Name)
+	}
+	card := &Card{}
+	seen := make(map[string]bool)
+	for _, p := range c.Properties {
+		name := strings.ToUpper(p.Name)
+		if !card.decode(name, p, seen[name]) {
+			card.Extra = append(card.Extra, p.Clone())
+			continue
+		}
+		seen[name] = true
+	}
+	return card, nil
+}
+
+//decode stores the property in the typed fields and reports whether this was possible without loss.
+func (card *Card) decode(name string, p *go_contentline.Property, seen bool) bool {
+	plain := p.Group == "" && len(p.Parameters) == 0 && !seen
+	switch name {
+	case "VERSION":
+		if !plain {
+			return false
+		}
+		card.Version = p.Value
+	case "FN":
+		fn, ok := unescape(p.Value)
+		if !plain || !ok {
+			return false
+		}
+		card.FormattedName = fn
+	case "BDAY", "ANNIVERSARY":
+		if !plain {
+			return false
+		}
+		d, err := ParseDate(p.Value)
+		if err != nil || d.format(card.Version) != p.Value {
+			return false
+		}
+		if name == "BDAY" {
+			card.Birthday = &d
+		} else {
+			card.Anniversary = &d
+		}
+	case "N":
+		parts := splitStructured(p.Value)
+		if seen || len(parts) > 5 {
+			return false
+		}
+		for len(parts) < 5 {
+			parts = append(parts, "")
+		}
+		n := &Name{Meta: meta(p)}
+		if !splitLists(parts, &n.FamilyNames, &n.GivenNames, &n.AdditionalNames, &n.HonorificPrefixes,
+			&n.HonorificSuffixes) {
+			return false
+		}
+		card.Name = n
+	case "EMAIL":
+		address, ok := unescape(p.Value)
+		if !ok {
+			return false
+		}
+		card.Emails = append(card.Emails, &Email{meta(p), address})
+	case "TEL":
+		card.Phones = append(card.Phones, &Phone{meta(p), p.Value})
+	case "ADR":
+		parts := splitStructured(p.Value)
+		if len(parts) > 7 {
+			return false
+		}
+		for len(parts) < 7 {
+			parts = append(parts, "")
+		}
+		a := &Address{Meta: meta(p)}
+		if !splitLists(parts, &a.POBox, &a.Extended, &a.Street, &a.Locality, &a.Region, &a.PostalCode, &a.Country) {
+			return false
+		}
+		card.Addresses = append(card.Addresses, a)
+	case "ORG":
+		parts := splitStructured(p.Value)
+		for i := range parts {
+			var ok bool
+			if parts[i], ok = unescape(parts[i]); !ok {
+				return false
+			}
+		}
+		card.Orgs = append(card.Orgs, &Org{meta(p), parts[0], parts[1:]})
+	case "PHOTO":
+		card.Photos = append(card.Photos, &Photo{meta(p), p.Value})
+	case "RELATED":
+		card.Related = append(card.Related, &Related{meta(p), p.Value})
+	default:
+		return false
+	}
+	return true
+}
+
+//ToComponent converts the card into a VCARD component.
+func (card *Card) ToComponent() *go_contentline.Component {
+	c := &go_contentline.Component{Name: "VCARD"}
+	add := func(name, value string) {
+		if value != "" {
+			c.AddProperty(newProperty(name, value))
+		}
+	}
+	add("VERSION", card.Version)
+	add("FN", go_contentline.EscapeText(card.FormattedName))
+	if n := card.Name; n != nil {
+		var parts []string
+		for _, v := range [][]string{n.FamilyNames, n.GivenNames, n.AdditionalNames, n.HonorificPrefixes,
+			n.HonorificSuffixes} {
+			parts = append(parts, joinText(v, ","))
+		}
+		c.AddProperty(n.property("N", strings.Join(parts, ";")))
+	}
+	for _, e := range card.Emails {
+		c.AddProperty(e.property("EMAIL", go_contentline.EscapeText(e.Address)))
+	}
+	for _, ph := range card.Phones {
+		c.AddProperty(ph.property("TEL", ph.Number))
+	}
+	for _, a := range card.Addresses {
+		var parts []string
+		for _, v := range [][]string{a.POBox, a.Extended, a.Street, a.Locality, a.Region, a.PostalCode, a.Country} {
+			parts = append(parts, joinText(v, ","))
+		}
+		c.AddProperty(a.property("ADR", strings.Join(parts, ";")))
+	}
+	for _, o := range card.Orgs {
+		c.AddProperty(o.property("ORG", joinText(append([]string{o.Name}, o.Units...), ";")))
+	}
+	for _, ph := range card.Photos {
+		c.AddProperty(ph.property("PHOTO", ph.Value))
+	}
+	for _, r := range card.Related {
+		c.AddProperty(r.property("RELATED", r.Value))
+	}
+	if card.Birthday != nil {
+		add("BDAY", card.Birthday.format(card.Version))
+	}
+	if card.Anniversary != nil {
+		add("ANNIVERSARY", card.Anniversary.format(card.Version))
+	}
+	for _, p := range card.Extra {
+		c.AddProperty(p.Clone())
+	}
+	return c
+}
+
+//meta returns the group and the parameters of p.
+func meta(p *go_contentline.Property) Meta {
+	m := Meta{Group: p.Group, Params: make(go_contentline.Parameters)}
+	for k, vals := range p.Parameters {
+		switch {
+		case strings.EqualFold(k, "TYPE"):
+			m.Types = append(m.Types, vals...)
+			continue
+		case strings.EqualFold(k, "PREF") && len(vals) == 1:
+			if pref, err := strconv.Atoi(vals[0]); err == nil && pref > 0 && strconv.Itoa(pref) == vals[0] {
+				m.Pref = pref
+				continue
+			}
+		}
+		m.Params[k] = append([]string(nil), vals...)
+	}
+	return m
+}
+
+//property returns a property with the group and the parameters of m.
+func (m *Meta) property(name, value string) *go_contentline.Property {
+	p := newProperty(name, value)
+	p.Group = m.Group
+	for k, vals := range m.Params {
+		p.SetParameter(k, append([]string(nil), vals...)...)
+	}
+	if len(m.Types) > 0 {
+		p.SetParameter("TYPE", m.Types...)
+	}
+	if m.Pref > 0 {
+		p.SetParameter("PREF", strconv.Itoa(m.Pref))
+	}
+	return p
+}
+
+//splitStructured splits a structured value (like N or ADR) at the semicolons which are not escaped. The parts are
+// not unescaped.
+func splitStructured(value string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ';':
+			out = append(out, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, value[start:])
+}
+
+//unescape unescapes a TEXT value and reports whether escaping it again results in the same value.
+func unescape(value string) (string, bool) {
+	s := go_contentline.UnescapeText(value)
+	return s, go_contentline.EscapeText(s) == value
+}
+
+//splitLists splits the parts of a structured value (like N or ADR) into the lists, see go_contentline.SplitText. It
+// reports whether joining the lists again results in the same parts.
+func splitLists(parts []string, lists ...*[]string) bool {
+	for i, v := range lists {
+		if parts[i] == "" {
+			continue
+		}
+		*v = go_contentline.SplitText(parts[i])
+		if joinText(*v, ",") != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//joinText escapes the texts and joins them with sep.
+func joinText(texts []string, sep string) string {
+	escaped := make([]string, len(texts))
+	for i, s := range texts {
+		escaped[i] = go_contentline.EscapeText(s)
+	}
+	return strings.Join(escaped, sep)
+}
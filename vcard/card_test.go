@@ -0,0 +1,151 @@
+package vcard
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const testCard40 = "BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Jane Doe\r\n" +
+	"N;SORT-AS=\"Doe,Jane\":Doe;Jane;Ann,Marie;Dr.;\r\n" +
+	"EMAIL;TYPE=work;PREF=1:jane@example.com\r\n" +
+	"item1.EMAIL;X-CUSTOM=yes:jane@home.example\r\n" +
+	"item1.X-ABLabel:Private\r\n" +
+	"TEL;VALUE=uri;TYPE=cell,voice:tel:+1-555-0100\r\n" +
+	"ADR;TYPE=home;LABEL=\"1 Main St\\nSpringfield\":;;1 Main St;Springfield;IL;62701;USA\r\n" +
+	"ORG:Example Inc.;Research;Lab 3\r\n" +
+	"PHOTO:data:image/png;base64,iVBORw0K\r\n" +
+	"RELATED;TYPE=spouse:urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6\r\n" +
+	"BDAY:--0415\r\n" +
+	"ANNIVERSARY:20090808T1430-0500\r\n" +
+	"NOTE:Unknown to the model\r\n" +
+	"X-SPOUSE:John\r\n" +
+	"END:VCARD\r\n"
+
+func TestFromComponent(t *testing.T) {
+	card, err := FromComponent(parseString(t, testCard40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Version != Version40 || card.FormattedName != "Jane Doe" {
+		t.Errorf("unexpected card: %+v", card)
+	}
+	n := card.Name
+	if n == nil || !reflect.DeepEqual(n.FamilyNames, []string{"Doe"}) ||
+		!reflect.DeepEqual(n.AdditionalNames, []string{"Ann", "Marie"}) || n.HonorificSuffixes != nil ||
+		n.Params.Get("SORT-AS") != "Doe,Jane" {
+		t.Errorf("unexpected name: %+v", n)
+	}
+	if len(card.Emails) != 2 {
+		t.Fatalf("unexpected emails: %v", card.Emails)
+	}
+	if e := card.Emails[0]; e.Address != "jane@example.com" || e.Pref != 1 || !reflect.DeepEqual(e.Types, []string{"work"}) {
+		t.Errorf("unexpected email: %+v", e)
+	}
+	if e := card.Emails[1]; e.Group != "ITEM1" || e.Params.Get("X-CUSTOM") != "yes" {
+		t.Errorf("unexpected email: %+v", e)
+	}
+	if len(card.Phones) != 1 || card.Phones[0].Number != "tel:+1-555-0100" || len(card.Phones[0].Types) != 2 {
+		t.Errorf("unexpected phones: %+v", card.Phones)
+	}
+	if len(card.Addresses) != 1 || card.Addresses[0].Street[0] != "1 Main St" || card.Addresses[0].Country[0] != "USA" {
+		t.Errorf("unexpected addresses: %+v", card.Addresses)
+	}
+	if len(card.Orgs) != 1 || card.Orgs[0].Name != "Example Inc." || !reflect.DeepEqual(card.Orgs[0].Units, []string{"Research", "Lab 3"}) {
+		t.Errorf("unexpected orgs: %+v", card.Orgs)
+	}
+	if len(card.Photos) != 1 {
+		t.Fatalf("unexpected photos: %+v", card.Photos)
+	}
+	if data, mediaType, err := card.Photos[0].Data(); err != nil || mediaType != "image/png" || len(data) != 6 {
+		t.Errorf("unexpected photo data: %v, %s, %v", data, mediaType, err)
+	}
+	if len(card.Related) != 1 || card.Related[0].Types[0] != "spouse" {
+		t.Errorf("unexpected related: %+v", card.Related)
+	}
+	if card.Birthday == nil || *card.Birthday != (Date{Month: 4, Day: 15}) {
+		t.Errorf("unexpected birthday: %v", card.Birthday)
+	}
+	if card.Anniversary == nil || *card.Anniversary != (Date{2009, 8, 8, "1430-0500"}) {
+		t.Errorf("unexpected anniversary: %v", card.Anniversary)
+	}
+	if len(card.Extra) != 3 {
+		t.Errorf("unexpected extra properties: %v", card.Extra)
+	}
+}
+
+func TestCard_ToComponent(t *testing.T) {
+	in := parseString(t, testCard40)
+	card, err := FromComponent(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := describe(card.ToComponent()), describe(in)
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the card:\nwant %q\ngot  %q", want, got)
+	}
+
+	card = &Card{Version: Version30, FormattedName: "A; B", Birthday: &Date{Year: 1985, Month: 4, Day: 15}}
+	card.Emails = []*Email{{Meta{Types: []string{"INTERNET"}}, "a@example.com"}}
+	want = []string{"VERSION:3.0", "FN:A\\; B", "EMAIL;TYPE=INTERNET:a@example.com", "BDAY:1985-04-15"}
+	if got := describe(card.ToComponent()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted %q, got %q", want, got)
+	}
+}
+
+func TestCard_RoundTripValues(t *testing.T) {
+	in := parseString(t, "BEGIN:VCARD\r\n"+
+		"VERSION:4.0\r\n"+
+		"FN:needlessly \\:escaped\r\n"+
+		"ADR:;;123 Main St,Apt 4;Town;;;\r\n"+
+		"ORG:Example\\, Inc.\r\n"+
+		"END:VCARD\r\n")
+	card, err := FromComponent(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(card.Addresses) != 1 || !reflect.DeepEqual(card.Addresses[0].Street, []string{"123 Main St", "Apt 4"}) {
+		t.Errorf("unexpected addresses: %+v", card.Addresses)
+	}
+	if card.FormattedName != "" || len(card.Extra) != 1 || card.Extra[0].Name != "FN" {
+		t.Errorf("Expected FN in Extra, got %q, %v", card.FormattedName, card.Extra)
+	}
+	got, want := describe(card.ToComponent()), describe(in)
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the card:\nwant %q\ngot  %q", want, got)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	checks := map[string]Date{
+		"19850415":    {1985, 4, 15, ""},
+		"1985-04-15":  {1985, 4, 15, ""},
+		"1985-04":     {1985, 4, 0, ""},
+		"1985":        {1985, 0, 0, ""},
+		"--0415":      {0, 4, 15, ""},
+		"--04-15":     {0, 4, 15, ""},
+		"--04":        {0, 4, 0, ""},
+		"---15":       {0, 0, 15, ""},
+		"19961022T14": {1996, 10, 22, "14"},
+	}
+	for in, want := range checks {
+		got, err := ParseDate(in)
+		if err != nil || got != want {
+			t.Errorf("ParseDate(%q): Wanted %v, got %v (%v)", in, want, got, err)
+		}
+	}
+	for _, in := range []string{"", "1985-13-01", "198504", "--4-15", "abcd", "1985T", "+1985"} {
+		if _, err := ParseDate(in); err == nil {
+			t.Errorf("ParseDate(%q): expected an error", in)
+		}
+	}
+	if s := (Date{Month: 4, Day: 15}).String(); s != "--0415" {
+		t.Errorf("unexpected String(): %s", s)
+	}
+}
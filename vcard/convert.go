@@ -0,0 +1,392 @@
+//Package vcard works with VCARD components as defined by vCard 2.1, 3.0 (RFC2426) and 4.0 (RFC6350).
+//
+// Card is a typed model of a VCARD component, Convert converts components between the versions.
+//
+// Cards of version 2.1 should be parsed with the Legacy option of go_contentline.ParserOptions, which decodes their
+// quoted-printable values. To write them, use go_contentline.EncoderOptions with the version of the card.
+package vcard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+	"github.com/pkg/errors"
+)
+
+//The supported vCard versions.
+const (
+	Version21 = go_contentline.VCard21
+	Version30 = go_contentline.VCard30
+	Version40 = go_contentline.VCard40
+)
+
+//Issue describes a property or parameter which could not be converted to the target version and was left out.
+type Issue struct {
+	//Property is the property concerned, as found in the copy of the card.
+	Property *go_contentline.Property
+	//Message describes what was left out and why.
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Property.Name + ": " + i.Message
+}
+
+//binaryProperties are the properties which can contain inline binary data.
+var binaryProperties = map[string]bool{"PHOTO": true, "LOGO": true, "SOUND": true, "KEY": true}
+
+//removedIn40 lists the properties of vCard 2.1 and 3.0 without counterpart in vCard 4.0 (RFC6350, Appendix A).
+var removedIn40 = map[string]bool{"NAME": true, "MAILER": true, "CLASS": true, "PROFILE": true, "AGENT": true}
+
+//addedIn40 lists the properties of vCard 4.0 without counterpart in vCard 2.1 and 3.0.
+var addedIn40 = map[string]bool{"KIND": true, "GENDER": true, "LANG": true, "ANNIVERSARY": true, "XML": true,
+	"CLIENTPIDMAP": true, "MEMBER": true, "RELATED": true}
+
+//paramsAddedIn40 lists the parameters of vCard 4.0 without counterpart in vCard 2.1 and 3.0.
+var paramsAddedIn40 = []string{"PID", "ALTID", "CALSCALE", "GEO", "TZ", "MEDIATYPE", "INDEX", "LEVEL", "CC"}
+
+//removedTypes lists the TYPE values of vCard 2.1 and 3.0 which were removed in vCard 4.0, per property.
+var removedTypes = map[string]map[string]bool{
+	"ADR":   {"dom": true, "intl": true, "postal": true, "parcel": true},
+	"EMAIL": {"internet": true, "x400": true},
+	"TEL":   {"msg": true, "bbs": true, "modem": true, "car": true, "isdn": true, "pcs": true},
+}
+
+//abLabels maps the predefined labels of X-ABLabel properties (written by Apple) to TYPE values.
+var abLabels = map[string][]string{
+	"_$!<Home>!$_":    {"home"},
+	"_$!<Work>!$_":    {"work"},
+	"_$!<Mobile>!$_":  {"cell"},
+	"_$!<Main>!$_":    {"voice"},
+	"_$!<HomeFAX>!$_": {"home", "fax"},
+	"_$!<WorkFAX>!$_": {"work", "fax"},
+	"_$!<Pager>!$_":   {"pager"},
+	"_$!<Other>!$_":   {},
+}
+
+//Version returns the value of the VERSION property of the card. Cards without VERSION are version 2.1, as it is
+// the only version where VERSION is optional.
+func Version(card *go_contentline.Component) string {
+	if v := card.GetProperty("VERSION"); v != nil {
+		return strings.TrimSpace(v.Value)
+	}
+	return Version21
+}
+
+//Convert converts a copy of the VCARD card to the given version. The card itself is not changed.
+//
+// Converting to vCard 4.0 turns TYPE=PREF into PREF=1, inline binary data (ENCODING=b/BASE64) into data: URIs and
+// LABEL properties into the LABEL parameter of the matching ADR. Predefined X-ABLabel values of a group (as written
+// by Apple) are turned into TYPE values of the other properties of the group.
+// Converting from vCard 4.0 reverses these changes. Properties, parameters and TYPE values which don't exist in the
+// target version are left out and reported as Issues.
+func Convert(card *go_contentline.Component, version string) (*go_contentline.Component, []Issue, error) {
+	if card.Name != "VCARD" {
+		return nil, nil, errors.Errorf("expected VCARD, got %s", card.Name)
+	}
+	from := Version(card)
+	for _, v := range []string{from, version} {
+		if v != Version21 && v != Version30 && v != Version40 {
+			return nil, nil, errors.Errorf("unsupported vCard version %q", v)
+		}
+	}
+	c := &converter{card: card.Clone(), to: version}
+	switch {
+	case from == version:
+	case version == Version40:
+		c.up()
+	case from == Version40:
+		c.down()
+	default:
+		c.between()
+	}
+	if v := c.card.GetProperty("VERSION"); v != nil {
+		v.Value = version
+	} else {
+		c.card.Properties = append([]*go_contentline.Property{newProperty("VERSION", version)}, c.card.Properties...)
+	}
+	return c.card, c.issues, nil
+}
+
+//converter holds the state of a conversion.
+type converter struct {
+	card   *go_contentline.Component
+	to     string
+	issues []Issue
+	props  []*go_contentline.Property
+}
+
+func (c *converter) report(p *go_contentline.Property, format string, args ...interface{}) {
+	c.issues = append(c.issues, Issue{p, fmt.Sprintf(format, args...)})
+}
+
+//keep adds p to the properties of the converted card.
+func (c *converter) keep(p *go_contentline.Property) {
+	c.props = append(c.props, p)
+}
+
+//up converts a vCard 2.1 or 3.0 to vCard 4.0.
+func (c *converter) up() {
+	c.applyABLabels()
+	var labels []*go_contentline.Property
+	for _, p := range c.card.Properties {
+		switch {
+		case removedIn40[p.Name]:
+			c.report(p, "removed in vCard 4.0")
+			continue
+		case p.Name == "LABEL":
+			labels = append(labels, p)
+			continue
+		case p.Name == "SORT-STRING":
+			if n := c.card.GetProperty("N"); n != nil {
+				n.SetParameter("SORT-AS", go_contentline.UnescapeText(p.Value))
+			} else {
+				c.report(p, "no N property for the SORT-AS parameter")
+			}
+			continue
+		case binaryProperties[p.Name]:
+			c.toDataURI(p)
+			c.keep(p)
+			continue
+		}
+
+		types := c.types(p, strings.ToLower)
+		var kept []string
+		for _, t := range types {
+			switch {
+			case t == "pref":
+				if p.Parameters.Get("PREF") == "" {
+					p.SetParameter("PREF", "1")
+				}
+			case removedTypes[p.Name][t]:
+				if t != "internet" {
+					c.report(p, "TYPE=%s removed in vCard 4.0", t)
+				}
+			default:
+				kept = append(kept, t)
+			}
+		}
+		setTypes(p, kept)
+		c.keep(p)
+	}
+	c.card.Properties = c.props
+
+	for _, label := range labels {
+		adr := c.matchingAdr(label)
+		if adr == nil {
+			c.report(label, "no matching ADR property for the LABEL parameter")
+			continue
+		}
+		adr.SetParameter("LABEL", go_contentline.UnescapeText(label.Value))
+	}
+}
+
+//down converts a vCard 4.0 to vCard 2.1 or 3.0.
+func (c *converter) down() {
+	upper := c.to == Version21
+	var sortString []*go_contentline.Property
+	for _, p := range c.card.Properties {
+		if addedIn40[p.Name] {
+			c.report(p, "not supported before vCard 4.0")
+			continue
+		}
+		for _, name := range paramsAddedIn40 {
+			if _, ok := p.Parameters[name]; ok {
+				c.report(p, "parameter %s not supported before vCard 4.0", name)
+				delete(p.Parameters, name)
+			}
+		}
+		if sortAs := p.Parameters.Get("SORT-AS"); sortAs != "" {
+			if p.Name == "N" && c.to == Version30 {
+				sortString = append(sortString, newProperty("SORT-STRING", go_contentline.EscapeText(sortAs)))
+			} else {
+				c.report(p, "parameter SORT-AS not supported before vCard 4.0")
+			}
+		}
+		delete(p.Parameters, "SORT-AS")
+		if binaryProperties[p.Name] {
+			delete(p.Parameters, "PREF")
+			c.fromDataURI(p)
+			c.keep(p)
+			continue
+		}
+
+		types := c.types(p, strings.ToLower)
+		if pref := p.Parameters.Get("PREF"); pref != "" {
+			if pref == "1" {
+				types = append(types, "pref")
+			} else {
+				c.report(p, "PREF=%s can only be kept as preferred (PREF=1)", pref)
+			}
+			delete(p.Parameters, "PREF")
+		}
+		if upper {
+			for i := range types {
+				types[i] = strings.ToUpper(types[i])
+			}
+		}
+		setTypes(p, types)
+		c.keep(p)
+
+		if label := p.Parameters.Get("LABEL"); p.Name == "ADR" && label != "" {
+			lp := newProperty("LABEL", go_contentline.EscapeText(label))
+			if len(types) > 0 {
+				lp.SetParameter("TYPE", types...)
+			}
+			c.keep(lp)
+		}
+		delete(p.Parameters, "LABEL")
+	}
+	c.card.Properties = append(c.props, sortString...)
+}
+
+//between converts between vCard 2.1 and 3.0, which only differ in the syntax of a few parameters.
+func (c *converter) between() {
+	for _, p := range c.card.Properties {
+		if !binaryProperties[p.Name] {
+			types := c.types(p, strings.ToLower)
+			if c.to == Version21 {
+				for i := range types {
+					types[i] = strings.ToUpper(types[i])
+				}
+			}
+			setTypes(p, types)
+		}
+		if enc := strings.ToUpper(p.Parameters.Get("ENCODING")); enc == "B" || enc == "BASE64" {
+			p.SetParameter("ENCODING", legacyBase64(c.to))
+		}
+		if val := strings.ToUpper(p.Parameters.Get("VALUE")); val == "URL" || val == "URI" {
+			p.SetParameter("VALUE", legacyURI(c.to))
+		}
+		c.keep(p)
+	}
+	c.card.Properties = c.props
+}
+
+//types returns the TYPE values of p, split at commas and converted with conv.
+func (c *converter) types(p *go_contentline.Property, conv func(string) string) []string {
+	var out []string
+	for k, vals := range p.Parameters {
+		if !strings.EqualFold(k, "TYPE") {
+			continue
+		}
+		for _, v := range vals {
+			for _, t := range strings.Split(v, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					out = append(out, conv(t))
+				}
+			}
+		}
+	}
+	return out
+}
+
+//setTypes replaces the TYPE values of p.
+func setTypes(p *go_contentline.Property, types []string) {
+	for k := range p.Parameters {
+		if strings.EqualFold(k, "TYPE") {
+			delete(p.Parameters, k)
+		}
+	}
+	if len(types) > 0 {
+		p.SetParameter("TYPE", types...)
+	}
+}
+
+//applyABLabels turns the predefined values of X-ABLabel properties into TYPE values of the other properties of
+// their group. The X-ABLabel properties with these values are removed, as well as groups with only one property left.
+func (c *converter) applyABLabels() {
+	members := make(map[string]int)
+	for _, p := range c.card.Properties {
+		if p.Group != "" {
+			members[strings.ToUpper(p.Group)]++
+		}
+	}
+	var props []*go_contentline.Property
+	for _, p := range c.card.Properties {
+		types, ok := abLabels[p.Value]
+		if !strings.EqualFold(p.Name, "X-ABLABEL") || p.Group == "" || !ok {
+			props = append(props, p)
+			continue
+		}
+		group := strings.ToUpper(p.Group)
+		for _, q := range c.card.Properties {
+			if q != p && strings.EqualFold(q.Group, group) {
+				setTypes(q, append(c.types(q, strings.ToLower), types...))
+			}
+		}
+		members[group]--
+	}
+	for _, p := range props {
+		if members[strings.ToUpper(p.Group)] == 1 {
+			p.Group = ""
+		}
+	}
+	c.card.Properties = props
+}
+
+//matchingAdr returns the ADR which has the same TYPE values as the LABEL property, or the only ADR of the card.
+func (c *converter) matchingAdr(label *go_contentline.Property) *go_contentline.Property {
+	adrs := c.card.FindProperties("ADR")
+	want := strings.Join(c.types(label, strings.ToLower), ",")
+	for _, adr := range adrs {
+		if strings.Join(c.types(adr, strings.ToLower), ",") == want && adr.Parameters.Get("LABEL") == "" {
+			return adr
+		}
+	}
+	if len(adrs) == 1 && adrs[0].Parameters.Get("LABEL") == "" {
+		return adrs[0]
+	}
+	return nil
+}
+
+//toDataURI converts inline binary data of vCard 2.1/3.0 into a data: URI.
+func (c *converter) toDataURI(p *go_contentline.Property) {
+	data, typ, err := p.Binary()
+	if err == go_contentline.ErrNotInline {
+		delete(p.Parameters, "VALUE")
+		return
+	}
+	if err != nil {
+		c.report(p, "invalid inline data, kept as is: %v", err)
+		return
+	}
+	//the TYPE of inline data is its media type, which is now part of the data: URI
+	delete(p.Parameters, "TYPE")
+	p.SetBinary(data, typ, Version40)
+}
+
+//fromDataURI converts a data: URI into inline binary data of vCard 2.1/3.0. Other URIs are marked with VALUE=URL
+// or VALUE=uri.
+func (c *converter) fromDataURI(p *go_contentline.Property) {
+	data, typ, err := p.Binary()
+	if err != nil {
+		if err != go_contentline.ErrNotInline {
+			c.report(p, "invalid data: URI, kept as URI: %v", err)
+		}
+		p.SetParameter("VALUE", legacyURI(c.to))
+		return
+	}
+	p.SetBinary(data, typ, c.to)
+}
+
+//legacyBase64 returns the ENCODING value for inline binary data in the given version.
+func legacyBase64(version string) string {
+	if version == Version21 {
+		return "BASE64"
+	}
+	return "b"
+}
+
+//legacyURI returns the VALUE value for URIs in the given version.
+func legacyURI(version string) string {
+	if version == Version21 {
+		return "URL"
+	}
+	return "uri"
+}
+
+func newProperty(name, value string) *go_contentline.Property {
+	return go_contentline.NewPropertyUnchecked(name, value, make(go_contentline.Parameters))
+}
@@ -0,0 +1,174 @@
+package vcard
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mqus/go-contentline"
+)
+
+func parseString(t *testing.T, in string) *go_contentline.Component {
+	t.Helper()
+	c, err := go_contentline.InitParserWithOptions(strings.NewReader(in), go_contentline.ParserOptions{Legacy: true}).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+//describe lists the properties of a card in a stable form for comparisons.
+func describe(c *go_contentline.Component) []string {
+	var out []string
+	for _, p := range c.Properties {
+		s := p.Name
+		if p.Group != "" {
+			s = p.Group + "." + s
+		}
+		for _, k := range []string{"TYPE", "PREF", "ENCODING", "VALUE", "LABEL", "SORT-AS"} {
+			if vals, ok := p.Parameters[k]; ok {
+				s += ";" + k + "=" + strings.Join(vals, ",")
+			}
+		}
+		out = append(out, s+":"+p.Value)
+	}
+	return out
+}
+
+const testCard30 = "BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"N:Doe;John;;;\r\n" +
+	"FN:John Doe\r\n" +
+	"SORT-STRING:Doe\r\n" +
+	"EMAIL;TYPE=INTERNET,WORK,PREF:john@example.com\r\n" +
+	"TEL;TYPE=CELL;TYPE=MSG:+1 555 1234\r\n" +
+	"item1.TEL:+1 555 9876\r\n" +
+	"item1.X-ABLabel:_$!<HomeFAX>!$_\r\n" +
+	"item2.EMAIL:john@home.example.com\r\n" +
+	"item2.X-ABLabel:Private\r\n" +
+	"ADR;TYPE=home:;;1 Main St;Springfield;;12345;USA\r\n" +
+	"LABEL;TYPE=home:1 Main St\\nSpringfield 12345\r\n" +
+	"PHOTO;ENCODING=b;TYPE=JPEG:/9j/4AAQ\r\n" +
+	"MAILER:PigeonMail\r\n" +
+	"END:VCARD\r\n"
+
+func TestConvert(t *testing.T) {
+	card := parseString(t, testCard30)
+	before := card.Clone()
+
+	v4, issues, err := Convert(card, Version40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"VERSION:4.0",
+		"N;SORT-AS=Doe:Doe;John;;;",
+		"FN:John Doe",
+		"EMAIL;TYPE=work;PREF=1:john@example.com",
+		"TEL;TYPE=cell:+1 555 1234",
+		"TEL;TYPE=home,fax:+1 555 9876",
+		"ITEM2.EMAIL:john@home.example.com",
+		"ITEM2.X-ABLABEL:Private",
+		"ADR;TYPE=home;LABEL=1 Main St\nSpringfield 12345:;;1 Main St;Springfield;;12345;USA",
+		"PHOTO:data:image/jpeg;base64,/9j/4AAQ",
+	}
+	if got := describe(v4); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted:\n%s\nGot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	var msgs []string
+	for _, i := range issues {
+		msgs = append(msgs, i.String())
+	}
+	if got := strings.Join(msgs, "; "); got != "TEL: TYPE=msg removed in vCard 4.0; MAILER: removed in vCard 4.0" {
+		t.Errorf("unexpected issues: %s", got)
+	}
+	if !reflect.DeepEqual(card, before) {
+		t.Error("the original card was changed")
+	}
+
+	//and back again
+	v3, issues, err := Convert(v4, Version30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"VERSION:3.0",
+		"N:Doe;John;;;",
+		"FN:John Doe",
+		"EMAIL;TYPE=work,pref:john@example.com",
+		"TEL;TYPE=cell:+1 555 1234",
+		"TEL;TYPE=home,fax:+1 555 9876",
+		"ITEM2.EMAIL:john@home.example.com",
+		"ITEM2.X-ABLABEL:Private",
+		"ADR;TYPE=home:;;1 Main St;Springfield;;12345;USA",
+		"LABEL;TYPE=home:1 Main St\\nSpringfield 12345",
+		"PHOTO;TYPE=JPEG;ENCODING=b:/9j/4AAQ",
+		"SORT-STRING:Doe",
+	}
+	if got := describe(v3); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted:\n%s\nGot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
+
+func TestConvert_Down(t *testing.T) {
+	card := parseString(t, "BEGIN:VCARD\r\n"+
+		"VERSION:4.0\r\n"+
+		"FN:Jane\r\n"+
+		"KIND:individual\r\n"+
+		"TEL;VALUE=uri;PREF=2;PID=1.1;TYPE=work:tel:+1-555-1234\r\n"+
+		"PHOTO:http://example.com/jane.png\r\n"+
+		"LOGO:data:image/png,%89PNG\r\n"+
+		"END:VCARD\r\n")
+	v21, issues, err := Convert(card, Version21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"VERSION:2.1",
+		"FN:Jane",
+		"TEL;TYPE=WORK;VALUE=uri:tel:+1-555-1234",
+		"PHOTO;VALUE=URL:http://example.com/jane.png",
+		"LOGO;TYPE=PNG;ENCODING=BASE64:iVBORw==",
+	}
+	if got := describe(v21); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted:\n%s\nGot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if len(issues) != 3 {
+		t.Errorf("expected 3 issues, got %v", issues)
+	}
+
+	//2.1 and 3.0 only differ in syntax
+	v30, _, err := Convert(v21, Version30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(v30)[4]; got != "LOGO;TYPE=PNG;ENCODING=b:iVBORw==" {
+		t.Errorf("unexpected LOGO: %s", got)
+	}
+
+	if _, _, err = Convert(card, "5.0"); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
+
+func TestConvert_InvalidInline(t *testing.T) {
+	card := parseString(t, "BEGIN:VCARD\r\n"+
+		"VERSION:3.0\r\n"+
+		"FN:Jane\r\n"+
+		"PHOTO;ENCODING=b;TYPE=JPEG:not-base64!\r\n"+
+		"END:VCARD\r\n")
+	v4, issues, err := Convert(card, Version40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the media type must survive, the data is kept as is
+	if got := describe(v4)[2]; got != "PHOTO;TYPE=JPEG;ENCODING=b:not-base64!" {
+		t.Errorf("unexpected PHOTO: %s", got)
+	}
+	if len(issues) != 1 {
+		t.Errorf("expected 1 issue, got %v", issues)
+	}
+}
@@ -0,0 +1,97 @@
+package vcard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//Date is a possibly partial date as used by BDAY and ANNIVERSARY (RFC6350, Section 4.3.4), e.g. --0415 for a
+// birthday without a year. Unknown components are 0.
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+	//Time is the time of day as written after the T, e.g. 102200Z, or empty if there is none.
+	Time string
+}
+
+//ParseDate parses a date in the basic (19850415) or extended (1985-04-15) format of ISO 8601, a reduced date like
+// 1985-04 or 1985, or a truncated date like --0415, --04-15 or ---15. It may be followed by T and a time of day.
+func ParseDate(s string) (Date, error) {
+	var d Date
+	date := s
+	if i := strings.IndexByte(s, 'T'); i >= 0 {
+		date, d.Time = s[:i], s[i+1:]
+		if d.Time == "" {
+			return Date{}, errors.Errorf("invalid date %q: empty time", s)
+		}
+	}
+	digits := strings.Replace(date, "-", "", -1)
+	var err error
+	number := func(s string) int {
+		n, e := strconv.Atoi(s)
+		if e != nil || len(s) != 2 && len(s) != 4 || strings.HasPrefix(s, "+") {
+			err = errors.Errorf("invalid date %q", date)
+		}
+		return n
+	}
+	switch {
+	case strings.HasPrefix(date, "---") && len(digits) == 2:
+		d.Day = number(digits)
+	case strings.HasPrefix(date, "--") && len(digits) == 4:
+		d.Month, d.Day = number(digits[:2]), number(digits[2:])
+	case strings.HasPrefix(date, "--") && len(digits) == 2:
+		d.Month = number(digits)
+	case !strings.HasPrefix(date, "-") && len(digits) == 8:
+		d.Year, d.Month, d.Day = number(digits[:4]), number(digits[4:6]), number(digits[6:])
+	case !strings.HasPrefix(date, "-") && len(digits) == 6 && strings.Contains(date, "-"):
+		d.Year, d.Month = number(digits[:4]), number(digits[4:])
+	case len(date) == 4:
+		d.Year = number(date)
+	default:
+		return Date{}, errors.Errorf("invalid date %q", date)
+	}
+	if err != nil {
+		return Date{}, err
+	}
+	if d.Month > 12 || d.Day > 31 || (d.Year > 0 || d.Month > 0) && d.Month == 0 && d.Day > 0 {
+		return Date{}, errors.Errorf("invalid date %q", date)
+	}
+	return d, nil
+}
+
+//String returns the date in the format of vCard 4.0, e.g. 19850415 or --0415.
+func (d Date) String() string {
+	return d.format(Version40)
+}
+
+//format returns the date in the format used by the given version: complete dates are written in the extended
+// format (1985-04-15) for vCard 2.1 and 3.0.
+func (d Date) format(version string) string {
+	var s string
+	switch {
+	case d.Year > 0 && d.Month > 0 && d.Day > 0:
+		if version == Version21 || version == Version30 {
+			s = fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+		} else {
+			s = fmt.Sprintf("%04d%02d%02d", d.Year, d.Month, d.Day)
+		}
+	case d.Year > 0 && d.Month > 0:
+		s = fmt.Sprintf("%04d-%02d", d.Year, d.Month)
+	case d.Year > 0:
+		s = fmt.Sprintf("%04d", d.Year)
+	case d.Month > 0 && d.Day > 0:
+		s = fmt.Sprintf("--%02d%02d", d.Month, d.Day)
+	case d.Month > 0:
+		s = fmt.Sprintf("--%02d", d.Month)
+	case d.Day > 0:
+		s = fmt.Sprintf("---%02d", d.Day)
+	}
+	if d.Time != "" {
+		s += "T" + d.Time
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+package go_contentline
+
+import "github.com/pkg/errors"
+
+//SkipComponent can be returned by a WalkFunc to skip all subcomponents of the current Component.
+// It is never returned by Walk itself.
+var SkipComponent = errors.New("skip this component")
+
+//StopWalk can be returned by a WalkFunc to stop the traversal without reporting an error.
+// It is never returned by Walk itself.
+var StopWalk = errors.New("stop walking")
+
+//WalkFunc is the type of the function called by Component.Walk for every visited Component. path contains all
+// ancestors of c, starting with the Component Walk was called on, and is empty for that Component itself. The slice
+// is reused between calls and must be copied if it should be retained.
+type WalkFunc func(path []*Component, c *Component) error
+
+//Walk traverses the component tree depth-first in document order, calling fn for c and every Component nested within.
+// If fn returns SkipComponent, the subcomponents of the current Component are not visited. If fn returns StopWalk,
+// the traversal ends and Walk returns nil. Any other non-nil error ends the traversal and is returned by Walk.
+func (c *Component) Walk(fn WalkFunc) error {
+	err := c.walk(nil, fn)
+	if err == StopWalk {
+		return nil
+	}
+	return err
+}
+
+//walk is the recursive part of Walk.
+func (c *Component) walk(path []*Component, fn WalkFunc) error {
+	switch err := fn(path, c); err {
+	case nil:
+	case SkipComponent:
+		return nil
+	default:
+		return err
+	}
+	path = append(path, c)
+	for _, sub := range c.Comps {
+		if err := sub.walk(path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package go_contentline
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//testCalendar is a small calendar with nested components, shared by the tests for traversal and queries.
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:first\r\n" +
+	"SUMMARY:Meeting\r\n" +
+	"ATTENDEE;PARTSTAT=ACCEPTED:mailto:a@example.com\r\n" +
+	"ATTENDEE;PARTSTAT=DECLINED:mailto:b@example.com\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:second\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:AUDIO\r\n" +
+	"END:VALARM\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:EMAIL\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"SUMMARY:Clean up\r\n" +
+	"END:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+func parseString(t *testing.T, in string) *Component {
+	t.Helper()
+	c, err := InitParser(strings.NewReader(in)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func ExampleComponent_Walk() {
+	c, _ := InitParser(strings.NewReader(testCalendar)).ParseNextObject()
+	c.Walk(func(path []*Component, c *Component) error {
+		fmt.Printf("%s%s\n", strings.Repeat("- ", len(path)), c.Name)
+		return nil
+	})
+	//Output:
+	//VCALENDAR
+	//- VEVENT
+	//- - VALARM
+	//- VEVENT
+	//- - VALARM
+	//- - VALARM
+	//- VTODO
+}
+
+func TestComponent_Walk(t *testing.T) {
+	c := parseString(t, testCalendar)
+
+	//skip subcomponents of events
+	var names []string
+	err := c.Walk(func(path []*Component, c *Component) error {
+		names = append(names, c.Name)
+		if c.Name == "VEVENT" {
+			return SkipComponent
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if got := strings.Join(names, ","); got != "VCALENDAR,VEVENT,VEVENT,VTODO" {
+		t.Errorf("unexpected traversal with SkipComponent: %s", got)
+	}
+
+	//stop at the first alarm, check the path
+	var path []string
+	err = c.Walk(func(p []*Component, c *Component) error {
+		if c.Name == "VALARM" {
+			for _, a := range p {
+				path = append(path, a.Name)
+			}
+			return StopWalk
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if got := strings.Join(path, "/"); got != "VCALENDAR/VEVENT" {
+		t.Errorf("unexpected path with StopWalk: %s", got)
+	}
+
+	//other errors are passed through
+	myErr := fmt.Errorf("my error")
+	if err = c.Walk(func([]*Component, *Component) error { return myErr }); err != myErr {
+		t.Errorf("expected the error of the WalkFunc, got %v", err)
+	}
+}
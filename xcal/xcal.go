@@ -0,0 +1,391 @@
+//Package xcal converts components to and from their XML representations xCal (RFC6321) and xCard (RFC6351).
+//
+// VCARD components are written as xCard, all others as xCal. Values are written with their value types like in
+// jCal, see package jcal. Structured values like N or ADR are written with the element names of their components.
+package xcal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sort"
+	"strings"
+
+	"github.com/mqus/go-contentline"
+	"github.com/mqus/go-contentline/internal/valuetype"
+	"github.com/pkg/errors"
+)
+
+//The XML namespaces of xCal and xCard.
+const (
+	CalendarNamespace = "urn:ietf:params:xml:ns:icalendar-2.0"
+	CardNamespace     = "urn:ietf:params:xml:ns:vcard-4.0"
+)
+
+//components contains the element names of the components of structured values.
+var components = map[bool]map[string][]string{
+	false: {
+		"REQUEST-STATUS": {"code", "description", "data"},
+		"GEO":            {"latitude", "longitude"},
+	},
+	true: {
+		"N":            {"surname", "given", "additional", "prefix", "suffix"},
+		"ADR":          {"pobox", "ext", "street", "locality", "region", "code", "country"},
+		"GENDER":       {"sex", "identity"},
+		"CLIENTPIDMAP": {"sourceid", "uri"},
+	},
+}
+
+//valueTypes contains the element names of values.
+var valueTypes = map[string]bool{
+	valuetype.Text: true, valuetype.Unknown: true, valuetype.Date: true, valuetype.DateTime: true,
+	valuetype.Time: true, valuetype.DateAndOrTime: true, valuetype.Timestamp: true, valuetype.Duration: true,
+	valuetype.Period: true, valuetype.Recur: true, valuetype.Integer: true, valuetype.Float: true,
+	valuetype.Boolean: true, valuetype.UTCOffset: true, valuetype.URI: true, valuetype.CalAddress: true,
+	valuetype.Binary: true, valuetype.LanguageTag: true,
+}
+
+//paramTypes contains the value types of parameters which are not text.
+var paramTypes = map[string]string{
+	"ALTREP": valuetype.URI, "DIR": valuetype.URI, "SENT-BY": valuetype.CalAddress,
+	"MEMBER": valuetype.CalAddress, "DELEGATED-TO": valuetype.CalAddress, "DELEGATED-FROM": valuetype.CalAddress,
+	"RSVP": valuetype.Boolean,
+}
+
+//Marshal returns an XML document with the xCal or xCard of the components. If the first component is a VCARD, all
+// of them are written as xCard, otherwise as xCal.
+func Marshal(cs ...*go_contentline.Component) ([]byte, error) {
+	vcard := len(cs) > 0 && strings.EqualFold(cs[0].Name, "VCARD")
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	e := &encoder{xml.NewEncoder(&buf)}
+	e.Indent("", "  ")
+	root, ns := "icalendar", CalendarNamespace
+	if vcard {
+		root, ns = "vcards", CardNamespace
+	}
+	e.start(root, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: ns})
+	for _, c := range cs {
+		e.component(c, vcard)
+	}
+	e.end(root)
+	if err := e.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+//encoder writes XML tokens. Errors are reported by Flush.
+type encoder struct {
+	*xml.Encoder
+}
+
+func (e *encoder) start(name string, attrs ...xml.Attr) {
+	e.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs})
+}
+
+func (e *encoder) end(name string) {
+	e.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+}
+
+func (e *encoder) element(name, text string) {
+	e.start(name)
+	e.EncodeToken(xml.CharData(text))
+	e.end(name)
+}
+
+func (e *encoder) component(c *go_contentline.Component, vcard bool) {
+	name := strings.ToLower(c.Name)
+	e.start(name)
+	if vcard {
+		//the properties of a group are written together, at the position of its first property
+		written := make(map[string]bool)
+		for _, p := range c.Properties {
+			g := strings.ToLower(p.Group)
+			if g == "" {
+				e.property(p, vcard)
+				continue
+			}
+			if written[g] {
+				continue
+			}
+			written[g] = true
+			e.start("group", xml.Attr{Name: xml.Name{Local: "name"}, Value: g})
+			for _, q := range c.Properties {
+				if strings.EqualFold(q.Group, g) {
+					e.property(q, vcard)
+				}
+			}
+			e.end("group")
+		}
+	} else if len(c.Properties) > 0 {
+		e.start("properties")
+		for _, p := range c.Properties {
+			e.property(p, vcard)
+		}
+		e.end("properties")
+	}
+	if len(c.Comps) > 0 {
+		if !vcard {
+			e.start("components")
+		}
+		for _, sub := range c.Comps {
+			e.component(sub, vcard)
+		}
+		if !vcard {
+			e.end("components")
+		}
+	}
+	e.end(name)
+}
+
+func (e *encoder) property(p *go_contentline.Property, vcard bool) {
+	name := strings.ToLower(p.Name)
+	e.start(name)
+	var keys []string
+	for k, vals := range p.Parameters {
+		if !strings.EqualFold(k, "VALUE") && len(vals) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		e.start("parameters")
+		for _, k := range keys {
+			typ := paramTypes[strings.ToUpper(k)]
+			if typ == "" {
+				typ = valuetype.Text
+			}
+			e.start(strings.ToLower(k))
+			for _, v := range p.Parameters[k] {
+				e.element(typ, v)
+			}
+			e.end(strings.ToLower(k))
+		}
+		e.end("parameters")
+	}
+
+	typ := valuetype.Of(p, vcard)
+	switch {
+	case typ == valuetype.Recur:
+		e.start(typ)
+		for _, part := range valuetype.SplitRecur(p.Value) {
+			for _, v := range part.Values {
+				e.element(part.Name, v)
+			}
+		}
+		e.end(typ)
+	case valuetype.IsStructured(p.Name, vcard):
+		names := components[vcard][strings.ToUpper(p.Name)]
+		for i, elems := range valuetype.Split(p, vcard)[0] {
+			elemName := typ
+			if i < len(names) {
+				elemName = names[i]
+			}
+			for _, v := range elems {
+				e.element(elemName, valuetype.Extend(typ, v))
+			}
+		}
+	default:
+		for _, value := range valuetype.Split(p, vcard) {
+			v := value[0][0]
+			if typ != valuetype.Period {
+				e.element(typ, valuetype.Extend(typ, v))
+				continue
+			}
+			parts := strings.SplitN(v, "/", 2)
+			e.start(typ)
+			e.element("start", valuetype.Extend(valuetype.DateTime, parts[0]))
+			if len(parts) == 2 {
+				if strings.Contains(parts[1], "P") {
+					e.element("duration", parts[1])
+				} else {
+					e.element("end", valuetype.Extend(valuetype.DateTime, parts[1]))
+				}
+			}
+			e.end(typ)
+		}
+	}
+	e.end(name)
+}
+
+//node is a generic XML element.
+type node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []node     `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+func (n *node) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+//Unmarshal parses an xCal or xCard document and returns its components.
+func Unmarshal(data []byte) ([]*go_contentline.Component, error) {
+	var root node
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, errors.Wrap(err, "invalid xCal")
+	}
+	var vcard bool
+	switch root.XMLName.Local {
+	case "icalendar":
+	case "vcards":
+		vcard = true
+	default:
+		return nil, errors.Errorf("invalid xCal: unexpected root element %s", root.XMLName.Local)
+	}
+	var out []*go_contentline.Component
+	for i := range root.Nodes {
+		c, err := component(&root.Nodes[i], vcard)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func component(n *node, vcard bool) (*go_contentline.Component, error) {
+	c := &go_contentline.Component{Name: strings.ToUpper(n.XMLName.Local)}
+	addProperty := func(pn *node, group string) error {
+		p, err := property(pn, vcard)
+		if err != nil {
+			return errors.Wrapf(err, "invalid xCal in %s", c.Name)
+		}
+		p.Group = group
+		c.AddProperty(p)
+		return nil
+	}
+	for i := range n.Nodes {
+		child := &n.Nodes[i]
+		switch {
+		case !vcard && child.XMLName.Local == "properties":
+			for j := range child.Nodes {
+				if err := addProperty(&child.Nodes[j], ""); err != nil {
+					return nil, err
+				}
+			}
+		case !vcard && child.XMLName.Local == "components":
+			for j := range child.Nodes {
+				sub, err := component(&child.Nodes[j], vcard)
+				if err != nil {
+					return nil, err
+				}
+				c.AddComponent(sub)
+			}
+		case vcard && child.XMLName.Local == "group":
+			for j := range child.Nodes {
+				if err := addProperty(&child.Nodes[j], child.attr("name")); err != nil {
+					return nil, err
+				}
+			}
+		case vcard:
+			if err := addProperty(child, ""); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.Errorf("invalid xCal: unexpected element %s in %s", child.XMLName.Local, c.Name)
+		}
+	}
+	return c, nil
+}
+
+func property(n *node, vcard bool) (*go_contentline.Property, error) {
+	p := go_contentline.NewPropertyUnchecked(strings.ToUpper(n.XMLName.Local), "", make(go_contentline.Parameters))
+	var values []*node
+	for i := range n.Nodes {
+		child := &n.Nodes[i]
+		if child.XMLName.Local != "parameters" {
+			values = append(values, child)
+			continue
+		}
+		for _, param := range child.Nodes {
+			var vals []string
+			for _, v := range param.Nodes {
+				vals = append(vals, v.Text)
+			}
+			p.SetParameter(param.XMLName.Local, vals...)
+		}
+	}
+	if len(values) == 0 {
+		return nil, errors.Errorf("%s: missing value", p.Name)
+	}
+
+	names := components[vcard][p.Name]
+	typ := values[0].XMLName.Local
+	if !valueTypes[typ] {
+		//the components of a structured value
+		typ = valuetype.Default(p.Name, vcard)
+		if names == nil {
+			return nil, errors.Errorf("%s: unknown value type %s", p.Name, values[0].XMLName.Local)
+		}
+	}
+	if typ != valuetype.Unknown && typ != valuetype.Default(p.Name, vcard) {
+		if vcard {
+			p.SetParameter("VALUE", typ)
+		} else {
+			p.SetParameter("VALUE", strings.ToUpper(typ))
+		}
+	}
+
+	switch {
+	case typ == valuetype.Recur:
+		var parts []valuetype.RecurPart
+		for _, part := range values[0].Nodes {
+			name := strings.ToLower(part.XMLName.Local)
+			if len(parts) > 0 && parts[len(parts)-1].Name == name {
+				parts[len(parts)-1].Values = append(parts[len(parts)-1].Values, part.Text)
+			} else {
+				parts = append(parts, valuetype.RecurPart{Name: name, Values: []string{part.Text}})
+			}
+		}
+		p.Value = valuetype.JoinRecur(parts)
+	case valuetype.IsStructured(p.Name, vcard):
+		comps := make([][]string, len(names))
+		for _, v := range values {
+			i := indexOf(names, v.XMLName.Local)
+			if i < 0 {
+				//components without name, like those of ORG
+				comps = append(comps, nil)
+				i = len(comps) - 1
+			}
+			comps[i] = append(comps[i], valuetype.Basic(typ, v.Text))
+		}
+		for i := range comps {
+			if comps[i] == nil {
+				comps[i] = []string{""}
+			}
+		}
+		p.Value = valuetype.Join([][][]string{comps}, typ)
+	default:
+		var vals [][][]string
+		for _, v := range values {
+			text := valuetype.Basic(typ, v.Text)
+			if typ == valuetype.Period {
+				var parts []string
+				for _, part := range v.Nodes {
+					parts = append(parts, valuetype.Basic(valuetype.DateTime, part.Text))
+				}
+				text = strings.Join(parts, "/")
+			}
+			vals = append(vals, [][]string{{text}})
+		}
+		p.Value = valuetype.Join(vals, typ)
+	}
+	return p, nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, e := range list {
+		if e == s {
+			return i
+		}
+	}
+	return -1
+}
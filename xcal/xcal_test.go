@@ -0,0 +1,102 @@
+package xcal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mqus/go-contentline"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Test//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20200115T100000\r\n" +
+	"SUMMARY:Meeting <with> & comma\\,\r\n" +
+	"GEO:37.386013;-122.082932\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=5;BYDAY=MO,WE\r\n" +
+	"FREEBUSY:19970308T160000Z/PT8H30M\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+const testCard = "BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"FN:Jane Doe\r\n" +
+	"N:Doe;Jane;;;\r\n" +
+	"ORG:Example;Sales\r\n" +
+	"ITEM1.EMAIL;TYPE=work:jane@example.com\r\n" +
+	"ITEM1.X-ABLABEL:Work\r\n" +
+	"END:VCARD\r\n"
+
+func parse(t *testing.T, s string) *go_contentline.Component {
+	c, err := go_contentline.InitParser(strings.NewReader(s)).ParseNextObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func encode(c *go_contentline.Component) string {
+	var buf bytes.Buffer
+	c.Encode(&buf)
+	return buf.String()
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := Marshal(parse(t, testCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0">`,
+		`<date-time>2020-01-15T10:00:00</date-time>`,
+		`<text>Meeting &lt;with&gt; &amp; comma,</text>`,
+		`<latitude>37.386013</latitude>`,
+		`<byday>WE</byday>`,
+		`<duration>PT8H30M</duration>`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("missing %s in %s", want, data)
+		}
+	}
+
+	data, err = Marshal(parse(t, testCard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<vcards xmlns="urn:ietf:params:xml:ns:vcard-4.0">`,
+		`<surname>Doe</surname>`,
+		`<group name="item1">`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("missing %s in %s", want, data)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, in := range []string{testCalendar, testCard} {
+		c := parse(t, in)
+		data, err := Marshal(c, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Unmarshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 2 {
+			t.Fatalf("expected 2 components, got %d", len(out))
+		}
+		if got, want := encode(out[1]), encode(c); got != want {
+			t.Errorf("Wanted\n%s\ngot\n%s", want, got)
+		}
+	}
+
+	if _, err := Unmarshal([]byte(`<foo/>`)); err == nil {
+		t.Error("expected an error")
+	}
+}